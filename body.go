@@ -0,0 +1,837 @@
+package ttninjs
+
+import (
+	"errors"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrContentOutsideBody is returned by ParseBody when a body_html5 has content
+// outside of its body element.
+var ErrContentOutsideBody = errors.New("content outside of the body element")
+
+// Body is a structured representation of body_html5.
+//
+// The HTML structures used by TT are parsed into typed blocks and inlines.
+// Everything else is kept as RawHTML and InlineHTML, so that rendering a
+// parsed body gives the same HTML, except for whitespace between blocks.
+type Body struct {
+	Blocks []Block
+	// Wrapper is the HTML around the blocks. A nil Wrapper wraps the
+	// blocks in a body element.
+	Wrapper *BodyWrapper
+}
+
+// BodyWrapper is the HTML before and after the blocks of a body, like the
+// start and end tags of the html and body elements, and a head element. Both
+// are empty if the body wasn't wrapped.
+type BodyWrapper struct {
+	Start string
+	End   string
+}
+
+// Block is a block level element of a body: *Paragraph, *Heading, *List,
+// *Blockquote, *Figure, *Table or *RawHTML.
+type Block interface {
+	block()
+}
+
+// Inline is inline content of a block: *Text, *Strong, *Emphasis, *Link,
+// *LineBreak or *InlineHTML.
+type Inline interface {
+	inline()
+}
+
+// Paragraph is a p element.
+type Paragraph struct {
+	Class   string
+	Content []Inline
+}
+
+// Heading is a h1-h6 element.
+type Heading struct {
+	Level   int
+	Class   string
+	Content []Inline
+}
+
+// List is an ordered (ol) or unordered (ul) list.
+type List struct {
+	Ordered bool
+	Items   [][]Inline
+}
+
+// Blockquote is a blockquote element.
+type Blockquote struct {
+	Class  string
+	Blocks []Block
+}
+
+// Figure is an image referencing an association of the document, with an
+// optional caption.
+type Figure struct {
+	// ID is the id attribute of the figure, which TT sets to the
+	// association key.
+	ID string
+	// Association is the data-association attribute of the figure.
+	Association string
+	Class       string
+	Src         string
+	Alt         string
+	Caption     []Inline
+
+	// attrs and imgAttrs are the attributes of a parsed figure and its
+	// img, in order. They're rendered in that order, even if empty.
+	attrs    []string
+	imgAttrs []string
+}
+
+// AssociationKey returns the key of the association that the figure
+// references, from Association or ID.
+func (f *Figure) AssociationKey() string {
+	if f.Association != "" {
+		return f.Association
+	}
+
+	return f.ID
+}
+
+// Table is a table with optional caption and header rows.
+type Table struct {
+	Class   string
+	Caption []Inline
+	Head    []TableRow
+	Rows    []TableRow
+
+	// implicitBody is set for parsed tables without a tbody element.
+	implicitBody bool
+}
+
+// TableRow is a row of a table.
+type TableRow struct {
+	Cells []TableCell
+}
+
+// TableCell is a td or, if Header is set, a th element.
+type TableCell struct {
+	Header  bool
+	Colspan int
+	Rowspan int
+	Content []Inline
+}
+
+// RawHTML is a block that is kept as HTML.
+type RawHTML struct {
+	HTML string
+}
+
+// Text is plain text.
+type Text struct {
+	Text string
+}
+
+// Strong is a strong element.
+type Strong struct {
+	Content []Inline
+}
+
+// Emphasis is an em element.
+type Emphasis struct {
+	Content []Inline
+}
+
+// Link is an a element.
+type Link struct {
+	Href    string
+	Content []Inline
+}
+
+// LineBreak is a br element.
+type LineBreak struct{}
+
+// InlineHTML is inline content that is kept as HTML.
+type InlineHTML struct {
+	HTML string
+}
+
+func (*Paragraph) block()  {}
+func (*Heading) block()    {}
+func (*List) block()       {}
+func (*Blockquote) block() {}
+func (*Figure) block()     {}
+func (*Table) block()      {}
+func (*RawHTML) block()    {}
+
+func (*Text) inline()       {}
+func (*Strong) inline()     {}
+func (*Emphasis) inline()   {}
+func (*Link) inline()       {}
+func (*LineBreak) inline()  {}
+func (*InlineHTML) inline() {}
+
+// ParseBody parses the BodyHtml5 of a document. The body can be wrapped in
+// html and body elements, but there can't be any content outside of them.
+func ParseBody(doc *Document) (*Body, error) {
+	root := parseHTML(doc.BodyHtml5)
+
+	content, wrapper, err := bodyElement(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Body{Blocks: parseBlocks(content), Wrapper: wrapper}, nil
+}
+
+// bodyElement finds the element with the body content, the body element if
+// there is one, otherwise the root, and the HTML around it.
+func bodyElement(root *htmlNode) (*htmlNode, *BodyWrapper, error) {
+	var wrapper BodyWrapper
+
+	n := root
+
+	for _, tag := range []string{"html", "body"} {
+		var found *htmlNode
+
+		for _, c := range n.Children {
+			if c.Type == htmlElementNode && c.Tag == tag {
+				found = c
+
+				break
+			}
+		}
+
+		if found == nil {
+			continue
+		}
+
+		var before, after strings.Builder
+
+		seen := false
+
+		for _, c := range n.Children {
+			switch {
+			case c == found:
+				seen = true
+			case !isBlank(c) && c.Tag != "head":
+				return nil, nil, ErrContentOutsideBody
+			case seen:
+				writeHTML(&after, c)
+			default:
+				writeHTML(&before, c)
+			}
+		}
+
+		writeHTMLStartTag(&before, found)
+
+		wrapper.Start += before.String()
+		wrapper.End = "</" + tag + ">" + after.String() + wrapper.End
+
+		n = found
+	}
+
+	return n, &wrapper, nil
+}
+
+func isBlank(n *htmlNode) bool {
+	return n.Type == htmlCommentNode ||
+		n.Type == htmlTextNode && strings.TrimSpace(n.Text) == ""
+}
+
+// hasOnlyAttrs checks that a node has no other attributes than the given.
+func hasOnlyAttrs(n *htmlNode, allowed ...string) bool {
+	for _, a := range n.Attrs {
+		if !slices.Contains(allowed, a.Key) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// elementChildren returns the element children of a node, or false if it has
+// content other than elements and whitespace.
+func elementChildren(n *htmlNode) ([]*htmlNode, bool) {
+	var res []*htmlNode
+
+	for _, c := range n.Children {
+		switch {
+		case c.Type == htmlElementNode:
+			res = append(res, c)
+		case c.Type == htmlTextNode && strings.TrimSpace(c.Text) == "":
+		default:
+			return nil, false
+		}
+	}
+
+	return res, true
+}
+
+func parseBlocks(n *htmlNode) []Block {
+	var blocks []Block
+
+	for _, c := range n.Children {
+		if c.Type == htmlTextNode && strings.TrimSpace(c.Text) == "" {
+			continue
+		}
+
+		blocks = append(blocks, parseBlock(c))
+	}
+
+	return blocks
+}
+
+func parseBlock(n *htmlNode) Block {
+	if n.Type != htmlElementNode {
+		return rawBlock(n)
+	}
+
+	switch n.Tag {
+	case "p":
+		if class, ok := classAttr(n); ok {
+			return &Paragraph{Class: class, Content: parseInlines(n)}
+		}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		if class, ok := classAttr(n); ok {
+			return &Heading{
+				Level:   int(n.Tag[1] - '0'),
+				Class:   class,
+				Content: parseInlines(n),
+			}
+		}
+	case "ul", "ol":
+		if l := parseList(n); l != nil {
+			return l
+		}
+	case "blockquote":
+		if class, ok := classAttr(n); ok {
+			return &Blockquote{Class: class, Blocks: parseBlocks(n)}
+		}
+	case "figure":
+		if f := parseFigure(n); f != nil {
+			return f
+		}
+	case "table":
+		if t := parseTable(n); t != nil {
+			return t
+		}
+	}
+
+	return rawBlock(n)
+}
+
+// classAttr returns the class of an element that has no other attributes. An
+// empty class attribute isn't rendered, so elements with one are kept as
+// RawHTML.
+func classAttr(n *htmlNode) (string, bool) {
+	if !hasOnlyAttrs(n, "class") {
+		return "", false
+	}
+
+	class, ok := n.attr("class")
+	if ok && class == "" {
+		return "", false
+	}
+
+	return class, true
+}
+
+// attrKeys returns the attribute names of an element, or false if an
+// attribute is repeated.
+func attrKeys(n *htmlNode) ([]string, bool) {
+	keys := make([]string, 0, len(n.Attrs))
+
+	for _, a := range n.Attrs {
+		if slices.Contains(keys, a.Key) {
+			return nil, false
+		}
+
+		keys = append(keys, a.Key)
+	}
+
+	return keys, true
+}
+
+func rawBlock(n *htmlNode) *RawHTML {
+	var b strings.Builder
+
+	writeHTML(&b, n)
+
+	return &RawHTML{HTML: b.String()}
+}
+
+func parseList(n *htmlNode) *List {
+	if len(n.Attrs) > 0 {
+		return nil
+	}
+
+	items, ok := elementChildren(n)
+	if !ok {
+		return nil
+	}
+
+	l := List{Ordered: n.Tag == "ol"}
+
+	for _, li := range items {
+		if li.Tag != "li" || len(li.Attrs) > 0 || containsBlocks(li) {
+			return nil
+		}
+
+		l.Items = append(l.Items, parseInlines(li))
+	}
+
+	return &l
+}
+
+func containsBlocks(n *htmlNode) bool {
+	for _, c := range n.Children {
+		if blockElements[c.Tag] || containsBlocks(c) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseFigure(n *htmlNode) *Figure {
+	if !hasOnlyAttrs(n, "id", "data-association", "class") {
+		return nil
+	}
+
+	children, ok := elementChildren(n)
+	if !ok || len(children) == 0 || len(children) > 2 {
+		return nil
+	}
+
+	img := children[0]
+	if img.Tag != "img" || !hasOnlyAttrs(img, "src", "alt") {
+		return nil
+	}
+
+	var f Figure
+
+	f.attrs, ok = attrKeys(n)
+	if !ok {
+		return nil
+	}
+
+	f.imgAttrs, ok = attrKeys(img)
+	if !ok {
+		return nil
+	}
+
+	f.ID, _ = n.attr("id")
+	f.Association, _ = n.attr("data-association")
+	f.Class, _ = n.attr("class")
+	f.Src, _ = img.attr("src")
+	f.Alt, _ = img.attr("alt")
+
+	if len(children) == 2 {
+		caption := children[1]
+		if caption.Tag != "figcaption" || len(caption.Attrs) > 0 {
+			return nil
+		}
+
+		// Keep empty captions, so that they're rendered.
+		f.Caption = parseInlines(caption)
+		if f.Caption == nil {
+			f.Caption = []Inline{}
+		}
+	}
+
+	return &f
+}
+
+func parseTable(n *htmlNode) *Table {
+	class, ok := classAttr(n)
+	if !ok {
+		return nil
+	}
+
+	children, ok := elementChildren(n)
+	if !ok {
+		return nil
+	}
+
+	t := Table{Class: class}
+
+	var seenHead, seenBody bool
+
+	for _, c := range children {
+		if len(c.Attrs) > 0 {
+			return nil
+		}
+
+		switch {
+		case c.Tag == "caption" && t.Caption == nil && !seenHead && !seenBody:
+			t.Caption = parseInlines(c)
+			if t.Caption == nil {
+				t.Caption = []Inline{}
+			}
+		case c.Tag == "thead" && !seenHead && !seenBody && t.Rows == nil:
+			rows, ok := parseTableRows(c)
+			if !ok {
+				return nil
+			}
+
+			seenHead = true
+			t.Head = rows
+
+			if t.Head == nil {
+				t.Head = []TableRow{}
+			}
+		case c.Tag == "tbody" && !seenBody:
+			rows, ok := parseTableRows(c)
+			if !ok {
+				return nil
+			}
+
+			seenBody = true
+			t.Rows = rows
+		case c.Tag == "tr" && !seenBody:
+			row, ok := parseTableRow(c)
+			if !ok {
+				return nil
+			}
+
+			t.Rows = append(t.Rows, row)
+		default:
+			return nil
+		}
+	}
+
+	t.implicitBody = !seenBody
+
+	return &t
+}
+
+func parseTableRows(n *htmlNode) ([]TableRow, bool) {
+	children, ok := elementChildren(n)
+	if !ok {
+		return nil, false
+	}
+
+	var rows []TableRow
+
+	for _, c := range children {
+		row, ok := parseTableRow(c)
+		if !ok {
+			return nil, false
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, true
+}
+
+func parseTableRow(n *htmlNode) (TableRow, bool) {
+	var row TableRow
+
+	children, ok := elementChildren(n)
+	if !ok || n.Tag != "tr" || len(n.Attrs) > 0 {
+		return row, false
+	}
+
+	for _, c := range children {
+		if c.Tag != "td" && c.Tag != "th" || containsBlocks(c) {
+			return row, false
+		}
+
+		cell := TableCell{
+			Header:  c.Tag == "th",
+			Content: parseInlines(c),
+		}
+
+		for i, a := range c.Attrs {
+			// Rendering writes colspan before rowspan.
+			if i > 0 && a.Key == "colspan" {
+				return row, false
+			}
+
+			v, err := strconv.Atoi(a.Val)
+			if err != nil || v < 1 || strconv.Itoa(v) != a.Val {
+				return row, false
+			}
+
+			switch a.Key {
+			case "colspan":
+				cell.Colspan = v
+			case "rowspan":
+				cell.Rowspan = v
+			default:
+				return row, false
+			}
+		}
+
+		row.Cells = append(row.Cells, cell)
+	}
+
+	return row, true
+}
+
+func parseInlines(n *htmlNode) []Inline {
+	var res []Inline
+
+	for _, c := range n.Children {
+		res = append(res, parseInline(c))
+	}
+
+	return res
+}
+
+func parseInline(n *htmlNode) Inline {
+	switch {
+	case n.Type == htmlTextNode:
+		return &Text{Text: n.Text}
+	case n.Type != htmlElementNode:
+	case n.Tag == "strong" && len(n.Attrs) == 0:
+		return &Strong{Content: parseInlines(n)}
+	case n.Tag == "em" && len(n.Attrs) == 0:
+		return &Emphasis{Content: parseInlines(n)}
+	case n.Tag == "br" && len(n.Attrs) == 0:
+		return &LineBreak{}
+	case n.Tag == "a" && len(n.Attrs) == 1 && n.Attrs[0].Key == "href":
+		return &Link{Href: n.Attrs[0].Val, Content: parseInlines(n)}
+	}
+
+	var b strings.Builder
+
+	writeHTML(&b, n)
+
+	return &InlineHTML{HTML: b.String()}
+}
+
+// RenderHTML5 renders the body as a body_html5 document fragment.
+func (b *Body) RenderHTML5() string {
+	start, end := "<body>", "</body>"
+	if b.Wrapper != nil {
+		start, end = b.Wrapper.Start, b.Wrapper.End
+	}
+
+	var w strings.Builder
+
+	w.WriteString(start)
+	writeBlocks(&w, b.Blocks)
+	w.WriteString(end)
+
+	return w.String()
+}
+
+// RenderText renders the body as plain text with one paragraph per line, like
+// ExtractText.
+func (b *Body) RenderText() string {
+	var w strings.Builder
+
+	writeBlocks(&w, b.Blocks)
+
+	return htmlText(parseHTML(w.String()))
+}
+
+// InsertAfterParagraph inserts blocks after the n:th paragraph, counting from
+// one, among the top level blocks of the body. Returns false if the body has
+// fewer than n paragraphs.
+func (b *Body) InsertAfterParagraph(n int, blocks ...Block) bool {
+	var count int
+
+	for i, block := range b.Blocks {
+		if _, ok := block.(*Paragraph); !ok {
+			continue
+		}
+
+		count++
+
+		if count == n {
+			b.Blocks = slices.Insert(b.Blocks, i+1, blocks...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeBlocks(w *strings.Builder, blocks []Block) {
+	for _, b := range blocks {
+		writeBlock(w, b)
+	}
+}
+
+func writeBlock(w *strings.Builder, b Block) {
+	switch b := b.(type) {
+	case *Paragraph:
+		writeStartTag(w, "p", "class", b.Class)
+		writeInlines(w, b.Content)
+		w.WriteString("</p>")
+	case *Heading:
+		tag := "h" + strconv.Itoa(min(max(b.Level, 1), 6))
+
+		writeStartTag(w, tag, "class", b.Class)
+		writeInlines(w, b.Content)
+		writeEndTag(w, tag)
+	case *List:
+		tag := "ul"
+		if b.Ordered {
+			tag = "ol"
+		}
+
+		writeStartTag(w, tag)
+
+		for _, item := range b.Items {
+			w.WriteString("<li>")
+			writeInlines(w, item)
+			w.WriteString("</li>")
+		}
+
+		writeEndTag(w, tag)
+	case *Blockquote:
+		writeStartTag(w, "blockquote", "class", b.Class)
+		writeBlocks(w, b.Blocks)
+		w.WriteString("</blockquote>")
+	case *Figure:
+		writeOrderedStartTag(w, "figure", b.attrs,
+			"id", b.ID,
+			"data-association", b.Association,
+			"class", b.Class)
+
+		imgAttrs := b.imgAttrs
+		if imgAttrs == nil {
+			imgAttrs = []string{"src"}
+		}
+
+		writeOrderedStartTag(w, "img", imgAttrs, "src", b.Src, "alt", b.Alt)
+
+		if b.Caption != nil {
+			w.WriteString("<figcaption>")
+			writeInlines(w, b.Caption)
+			w.WriteString("</figcaption>")
+		}
+
+		w.WriteString("</figure>")
+	case *Table:
+		writeTable(w, b)
+	case *RawHTML:
+		w.WriteString(b.HTML)
+	}
+}
+
+func writeTable(w *strings.Builder, t *Table) {
+	writeStartTag(w, "table", "class", t.Class)
+
+	if t.Caption != nil {
+		w.WriteString("<caption>")
+		writeInlines(w, t.Caption)
+		w.WriteString("</caption>")
+	}
+
+	if t.Head != nil {
+		w.WriteString("<thead>")
+		writeTableRows(w, t.Head)
+		w.WriteString("</thead>")
+	}
+
+	if t.implicitBody {
+		writeTableRows(w, t.Rows)
+	} else {
+		w.WriteString("<tbody>")
+		writeTableRows(w, t.Rows)
+		w.WriteString("</tbody>")
+	}
+
+	w.WriteString("</table>")
+}
+
+func writeTableRows(w *strings.Builder, rows []TableRow) {
+	for _, row := range rows {
+		w.WriteString("<tr>")
+
+		for _, cell := range row.Cells {
+			tag := "td"
+			if cell.Header {
+				tag = "th"
+			}
+
+			var colspan, rowspan string
+
+			if cell.Colspan > 0 {
+				colspan = strconv.Itoa(cell.Colspan)
+			}
+
+			if cell.Rowspan > 0 {
+				rowspan = strconv.Itoa(cell.Rowspan)
+			}
+
+			writeStartTag(w, tag, "colspan", colspan, "rowspan", rowspan)
+			writeInlines(w, cell.Content)
+			writeEndTag(w, tag)
+		}
+
+		w.WriteString("</tr>")
+	}
+}
+
+func writeInlines(w *strings.Builder, content []Inline) {
+	for _, in := range content {
+		switch in := in.(type) {
+		case *Text:
+			w.WriteString(escapeHTMLText(in.Text))
+		case *Strong:
+			w.WriteString("<strong>")
+			writeInlines(w, in.Content)
+			w.WriteString("</strong>")
+		case *Emphasis:
+			w.WriteString("<em>")
+			writeInlines(w, in.Content)
+			w.WriteString("</em>")
+		case *Link:
+			w.WriteString(`<a href="`)
+			w.WriteString(html.EscapeString(in.Href))
+			w.WriteString(`">`)
+			writeInlines(w, in.Content)
+			w.WriteString("</a>")
+		case *LineBreak:
+			w.WriteString("<br>")
+		case *InlineHTML:
+			w.WriteString(in.HTML)
+		}
+	}
+}
+
+// writeStartTag writes a start tag with the given attribute key value pairs,
+// leaving out attributes with empty values.
+func writeStartTag(w *strings.Builder, tag string, attrs ...string) {
+	writeOrderedStartTag(w, tag, nil, attrs...)
+}
+
+// writeOrderedStartTag writes a start tag like writeStartTag, except that the
+// attributes named in order are written first, in that order, even if their
+// values are empty.
+func writeOrderedStartTag(w *strings.Builder, tag string, order []string, attrs ...string) {
+	w.WriteByte('<')
+	w.WriteString(tag)
+
+	for _, key := range order {
+		for i := 0; i+1 < len(attrs); i += 2 {
+			if attrs[i] == key {
+				writeAttr(w, key, attrs[i+1])
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] == "" || slices.Contains(order, attrs[i]) {
+			continue
+		}
+
+		writeAttr(w, attrs[i], attrs[i+1])
+	}
+
+	w.WriteByte('>')
+}
+
+func writeEndTag(w *strings.Builder, tag string) {
+	w.WriteString("</")
+	w.WriteString(tag)
+	w.WriteByte('>')
+}
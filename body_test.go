@@ -0,0 +1,87 @@
+package ttninjs
+
+import (
+	"testing"
+)
+
+func TestBodyRoundTrip(t *testing.T) {
+	cases := []string{
+		`<p>Text</p>`,
+		`<body><p>Text</p></body>`,
+		`<html><body><p>Text</p></body></html>`,
+		`<html lang="sv"><head><title>Rubrik</title></head><body class="ingress"><p>Text</p></body></html>`,
+		`<!-- kommentar --><body><h1>Rubrik</h1><p class="lead">Ingress</p></body>`,
+		`<body><figure id="img1" class="wide"><img src="https://tt.se/1.jpg" alt="Bild"><figcaption>Foto: TT</figcaption></figure></body>`,
+		`<body><figure class="wide" data-association="img1" id="img1"><img alt="Bild" src="https://tt.se/1.jpg"></figure></body>`,
+		`<body><figure id="img1" class=""><img src="https://tt.se/1.jpg" alt=""><figcaption></figcaption></figure></body>`,
+		`<body><figure><img></figure></body>`,
+		`<body><p class="">Text</p><blockquote class=""><p>Citat</p></blockquote></body>`,
+		`<body><table><tr><td>1</td></tr></table></body>`,
+		`<body><table class="result"><caption></caption><thead></thead><tbody></tbody></table></body>`,
+		`<body><table><tbody><tr><td rowspan="2" colspan="3">1</td></tr></tbody></table></body>`,
+		`<body><p>A <strong>B</strong> <em>C</em> <a href="https://tt.se/">D</a><br>E</p></body>`,
+		`<body><ul><li>Ett</li><li>Två</li></ul><div class="x"><p>Rå</p></div></body>`,
+	}
+
+	for _, c := range cases {
+		t.Run(c, func(t *testing.T) {
+			// Compare with the input after normalisation by the HTML
+			// parser, so that the test is about the body model.
+			want := renderHTML(parseHTML(c))
+
+			body, err := ParseBody(&Document{BodyHtml5: c})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := body.RenderHTML5(); got != want {
+				t.Errorf("got  %s\nwant %s", got, want)
+			}
+		})
+	}
+}
+
+func TestBodyRoundTripTyped(t *testing.T) {
+	body, err := ParseBody(&Document{
+		BodyHtml5: `<html><body><figure id="img1"><img src="a.jpg" alt=""><figcaption></figcaption></figure></body></html>`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body.Blocks) != 1 {
+		t.Fatalf("got %d blocks, expected 1", len(body.Blocks))
+	}
+
+	f, ok := body.Blocks[0].(*Figure)
+	if !ok {
+		t.Fatalf("got %T, expected a *Figure", body.Blocks[0])
+	}
+
+	if f.AssociationKey() != "img1" || f.Src != "a.jpg" || f.Caption == nil {
+		t.Errorf("unexpected figure %+v", f)
+	}
+
+	if text := body.RenderText(); text != "" {
+		t.Errorf("unexpected text %q", text)
+	}
+}
+
+func TestBodyRenderNew(t *testing.T) {
+	body := Body{Blocks: []Block{
+		&Figure{ID: "img1", Src: "a.jpg"},
+		&Table{Rows: []TableRow{{Cells: []TableCell{{Content: []Inline{&Text{Text: "1"}}}}}}},
+	}}
+
+	want := `<body><figure id="img1"><img src="a.jpg"></figure><table><tbody><tr><td>1</td></tr></tbody></table></body>`
+	if got := body.RenderHTML5(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestParseBodyContentOutsideBody(t *testing.T) {
+	_, err := ParseBody(&Document{BodyHtml5: `<p>Före</p><body><p>Text</p></body>`})
+	if err != ErrContentOutsideBody {
+		t.Errorf("expected ErrContentOutsideBody, got %v", err)
+	}
+}
@@ -0,0 +1,448 @@
+package ttninjs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Roles used in Bylines.
+const (
+	BylineRoleReporter     = "Reporter"
+	BylineRolePhotographer = "Photographer"
+	BylineRoleGraphics     = "Graphic artist"
+	BylineRoleVideo        = "Video journalist"
+)
+
+// bylinePrefixes maps the role prefixes of byline strings, like "Foto:", to
+// roles.
+var bylinePrefixes = map[string]string{
+	"text":         BylineRoleReporter,
+	"foto":         BylineRolePhotographer,
+	"bild":         BylineRolePhotographer,
+	"grafik":       BylineRoleGraphics,
+	"illustration": BylineRoleGraphics,
+	"video":        BylineRoleVideo,
+}
+
+// bylineRolePrefixes are the prefixes used when rendering roles. Reporters
+// don't get a prefix.
+var bylineRolePrefixes = []struct {
+	role   string
+	prefix string
+}{
+	{BylineRolePhotographer, "Foto"},
+	{BylineRoleGraphics, "Grafik"},
+	{BylineRoleVideo, "Video"},
+}
+
+var (
+	bylinePrefixExp    = regexp.MustCompile(`(?i)(?:^|[\s,.;])(text|foto|bild|grafik|illustration|video)\s*:\s*`)
+	bylineSeparatorExp = regexp.MustCompile(`(?i)\s*(?:,|;|&|\s+och\s+|\s+and\s+|\s+samt\s+)\s*`)
+	bylineEmailExp     = regexp.MustCompile(`[<(]?([\w.+-]+@[\w-]+(?:\.[\w-]+)+)[>)]?`)
+)
+
+// ParseByline parses a byline string like "Anna Andersson/TT" into bylines.
+// Multiple authors can be separated by commas, "och", "and" or "&", and an
+// affiliation at the end is shared by the authors before it that have none,
+// so that "Anna Andersson och Bo Ek/TT" and "Anna Andersson och Bo Ek, TT"
+// are both from TT. Role prefixes like
+// "Foto:" or "Text:" set the role of the authors that follow them. Single
+// lowercase words are taken to be initials, and single uppercase words, like
+// "TT", to be affiliations.
+func ParseByline(s string) []BylinesElem {
+	var bylines []BylinesElem
+
+	matches := bylinePrefixExp.FindAllStringSubmatchIndex(s, -1)
+
+	start, role := 0, ""
+
+	for _, m := range matches {
+		bylines = append(bylines, parseBylineAuthors(s[start:m[2]], role)...)
+		start, role = m[1], bylinePrefixes[strings.ToLower(s[m[2]:m[3]])]
+	}
+
+	return append(bylines, parseBylineAuthors(s[start:], role)...)
+}
+
+func parseBylineAuthors(s string, role string) []BylinesElem {
+	var bylines []BylinesElem
+
+	for _, part := range bylineSeparatorExp.Split(s, -1) {
+		part = strings.Trim(part, " \t\n.")
+		if part == "" {
+			continue
+		}
+
+		b := BylinesElem{Role: role}
+
+		if m := bylineEmailExp.FindStringSubmatch(part); m != nil {
+			b.Email = m[1]
+			part = strings.TrimSpace(strings.Replace(part, m[0], "", 1))
+		}
+
+		name, affiliation, _ := strings.Cut(part, "/")
+		name = strings.Join(strings.Fields(name), " ")
+		affiliation = strings.TrimSpace(affiliation)
+
+		switch {
+		case isAffiliation(name):
+			b.Affiliation = strings.TrimSpace(part)
+		case isInitials(name):
+			b.Initials = name
+			b.Affiliation = affiliation
+		default:
+			b.Firstname, b.Lastname = splitName(name)
+			b.Affiliation = affiliation
+		}
+
+		b.Byline = renderBylineAuthor(b)
+
+		// A standalone affiliation, like in "Anna Andersson, TT",
+		// belongs to the author before it.
+		if n := len(bylines); n > 0 && b.Affiliation != "" &&
+			b.Firstname+b.Lastname+b.Initials+b.Email == "" &&
+			bylines[n-1].Affiliation == "" &&
+			bylines[n-1].Firstname+bylines[n-1].Lastname+bylines[n-1].Initials != "" {
+			bylines[n-1].Affiliation = b.Affiliation
+			bylines[n-1].Byline = ""
+			bylines[n-1].Byline = renderBylineAuthor(bylines[n-1])
+
+			continue
+		}
+
+		bylines = append(bylines, b)
+	}
+
+	// Share a trailing affiliation with the preceding authors.
+	for i := len(bylines) - 2; i >= 0; i-- {
+		if bylines[i].Affiliation == "" && bylines[i].Firstname+bylines[i].Lastname != "" {
+			bylines[i].Affiliation = bylines[i+1].Affiliation
+			bylines[i].Byline = renderBylineAuthor(bylines[i])
+		}
+	}
+
+	return bylines
+}
+
+func isAffiliation(name string) bool {
+	if name == "" || strings.Contains(name, " ") {
+		return false
+	}
+
+	var letters bool
+
+	for _, r := range name {
+		switch {
+		case unicode.IsLower(r):
+			return false
+		case unicode.IsLetter(r):
+			letters = true
+		}
+	}
+
+	return letters
+}
+
+func isInitials(name string) bool {
+	if name == "" || len(name) > 4 || strings.Contains(name, " ") {
+		return false
+	}
+
+	for _, r := range name {
+		if !unicode.IsLower(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// nameParticles are lowercase words that belong to the last name, like in
+// "Carl von Essen".
+var nameParticles = map[string]bool{
+	"af": true, "av": true, "da": true, "de": true, "del": true, "den": true,
+	"der": true, "di": true, "du": true, "la": true, "van": true, "von": true,
+}
+
+// splitName splits a name into first and last name. The last name is the last
+// word and any name particles before it.
+func splitName(name string) (first string, last string) {
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return "", ""
+	}
+
+	i := len(words) - 1
+	for i > 1 && nameParticles[words[i-1]] {
+		i--
+	}
+
+	return strings.Join(words[:i], " "), strings.Join(words[i:], " ")
+}
+
+// BylineStyle selects how RenderByline renders bylines.
+type BylineStyle int
+
+const (
+	// BylineStyleTT renders authors as "Anna Andersson/TT" separated by
+	// commas, with the authors grouped by role and the groups prefixed by
+	// f.ex. "Foto:". Internal bylines are left out.
+	BylineStyleTT BylineStyle = iota
+	// BylineStyleNames renders the names of the authors without
+	// affiliations, like "Anna Andersson och Bo Ek". Internal bylines are
+	// left out.
+	BylineStyleNames
+	// BylineStyleInitials renders the initials of the authors separated by
+	// slashes, like "aa/be", including internal bylines.
+	BylineStyleInitials
+)
+
+// RenderByline renders bylines as a byline string.
+func RenderByline(bylines []BylinesElem, style BylineStyle) string {
+	switch style {
+	case BylineStyleNames:
+		var names []string
+
+		for _, b := range bylines {
+			if name := bylineName(b); name != "" && !isInternal(b) {
+				names = append(names, name)
+			}
+		}
+
+		if len(names) < 2 {
+			return strings.Join(names, "")
+		}
+
+		return strings.Join(names[:len(names)-1], ", ") + " och " + names[len(names)-1]
+	case BylineStyleInitials:
+		var initials []string
+
+		for _, b := range bylines {
+			if i := bylineInitials(b); i != "" {
+				initials = append(initials, i)
+			}
+		}
+
+		return strings.Join(initials, "/")
+	}
+
+	var (
+		groups   []string
+		reporter []string
+		byRole   = make(map[string][]string)
+	)
+
+	for _, b := range bylines {
+		author := renderBylineAuthor(b)
+		if author == "" || isInternal(b) {
+			continue
+		}
+
+		role := bylineRole(b.Role)
+		if role == "" {
+			reporter = append(reporter, author)
+		} else {
+			byRole[role] = append(byRole[role], author)
+		}
+	}
+
+	if len(reporter) > 0 {
+		groups = append(groups, strings.Join(reporter, ", "))
+	}
+
+	for _, rp := range bylineRolePrefixes {
+		if authors := byRole[rp.role]; len(authors) > 0 {
+			groups = append(groups, rp.prefix+": "+strings.Join(authors, ", "))
+		}
+	}
+
+	return strings.Join(groups, ", ")
+}
+
+// bylineRole normalises the role of a byline, returning an empty string for
+// reporters and roles that don't have a prefix.
+func bylineRole(role string) string {
+	switch strings.ToLower(strings.TrimSpace(role)) {
+	case "photographer", "fotograf", "foto", "bild":
+		return BylineRolePhotographer
+	case "graphic artist", "graphics", "grafiker", "grafik", "illustratör":
+		return BylineRoleGraphics
+	case "video journalist", "videojournalist", "video":
+		return BylineRoleVideo
+	}
+
+	return ""
+}
+
+func isInternal(b BylinesElem) bool {
+	return strings.EqualFold(b.Internal, "true")
+}
+
+// bylineName returns the name of the author of a byline.
+func bylineName(b BylinesElem) string {
+	if name := strings.TrimSpace(b.Firstname + " " + b.Lastname); name != "" {
+		return name
+	}
+
+	// Bylines with only an affiliation, like "Foto: AP", have no name.
+	if b.Byline != "" && !strings.EqualFold(b.Byline, b.Affiliation) {
+		name, _, _ := strings.Cut(b.Byline, "/")
+
+		return strings.TrimSpace(name)
+	}
+
+	return b.Initials
+}
+
+func bylineInitials(b BylinesElem) string {
+	if b.Initials != "" {
+		return b.Initials
+	}
+
+	var initials []rune
+
+	for _, w := range strings.Fields(strings.TrimSpace(b.Firstname + " " + b.Lastname)) {
+		if nameParticles[w] {
+			continue
+		}
+
+		for _, r := range w {
+			initials = append(initials, unicode.ToLower(r))
+
+			break
+		}
+	}
+
+	return string(initials)
+}
+
+// renderBylineAuthor renders a single author as "Anna Andersson/TT".
+func renderBylineAuthor(b BylinesElem) string {
+	name := strings.TrimSpace(b.Firstname + " " + b.Lastname)
+
+	switch {
+	case name == "" && b.Byline != "":
+		return b.Byline
+	case name == "":
+		name = b.Initials
+	}
+
+	switch {
+	case name == "":
+		return b.Affiliation
+	case b.Affiliation == "":
+		return name
+	}
+
+	return name + "/" + b.Affiliation
+}
+
+// BylineProblem describes a conflict between Byline and Bylines.
+type BylineProblem struct {
+	// Name is the name of the author the problem concerns.
+	Name string
+	Msg  string
+}
+
+func (p BylineProblem) String() string {
+	return fmt.Sprintf("%s: %s", p.Name, p.Msg)
+}
+
+// ReconcileBylines fills in whichever of Byline and Bylines is missing from the
+// other, and the names of bylines that only have a byline string. If the
+// document has both it reports the authors and affiliations that don't agree
+// between them.
+func ReconcileBylines(doc *Document) []BylineProblem {
+	for i, b := range doc.Bylines {
+		if b.Byline == "" || b.Firstname != "" || b.Lastname != "" || b.Initials != "" {
+			continue
+		}
+
+		parsed := ParseByline(b.Byline)
+		if len(parsed) != 1 {
+			continue
+		}
+
+		doc.Bylines[i].Firstname = parsed[0].Firstname
+		doc.Bylines[i].Lastname = parsed[0].Lastname
+		doc.Bylines[i].Initials = parsed[0].Initials
+
+		if b.Affiliation == "" {
+			doc.Bylines[i].Affiliation = parsed[0].Affiliation
+		}
+	}
+
+	switch {
+	case doc.Byline == "" && len(doc.Bylines) == 0:
+		return nil
+	case len(doc.Bylines) == 0:
+		doc.Bylines = ParseByline(doc.Byline)
+
+		return nil
+	case doc.Byline == "":
+		doc.Byline = RenderByline(doc.Bylines, BylineStyleTT)
+
+		return nil
+	}
+
+	var problems []BylineProblem
+
+	structured := make(map[string]BylinesElem)
+
+	for _, b := range doc.Bylines {
+		if name := bylineName(b); name != "" && !isInternal(b) {
+			structured[strings.ToLower(name)] = b
+		}
+	}
+
+	for _, p := range ParseByline(doc.Byline) {
+		name := bylineName(p)
+		if name == "" {
+			continue
+		}
+
+		key := strings.ToLower(name)
+
+		b, ok := structured[key]
+		if !ok {
+			problems = append(problems, BylineProblem{
+				Name: name,
+				Msg:  "is in byline but not in bylines",
+			})
+
+			continue
+		}
+
+		delete(structured, key)
+
+		if p.Affiliation != "" && b.Affiliation != "" &&
+			!strings.EqualFold(p.Affiliation, b.Affiliation) {
+			problems = append(problems, BylineProblem{
+				Name: name,
+				Msg: fmt.Sprintf("affiliation is %q in byline but %q in bylines",
+					p.Affiliation, b.Affiliation),
+			})
+		}
+
+		if p.Role != "" && bylineRole(p.Role) != bylineRole(b.Role) {
+			problems = append(problems, BylineProblem{
+				Name: name,
+				Msg: fmt.Sprintf("role is %q in byline but %q in bylines",
+					p.Role, b.Role),
+			})
+		}
+	}
+
+	for _, b := range doc.Bylines {
+		if _, missing := structured[strings.ToLower(bylineName(b))]; missing {
+			problems = append(problems, BylineProblem{
+				Name: bylineName(b),
+				Msg:  "is in bylines but not in byline",
+			})
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,229 @@
+package ttninjs
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseByline(t *testing.T) {
+	anna := BylinesElem{
+		Firstname: "Anna", Lastname: "Andersson",
+		Affiliation: "TT", Byline: "Anna Andersson/TT",
+	}
+	bo := BylinesElem{
+		Firstname: "Bo", Lastname: "Ek",
+		Affiliation: "TT", Byline: "Bo Ek/TT",
+	}
+
+	with := func(b BylinesElem, fn func(b *BylinesElem)) BylinesElem {
+		fn(&b)
+
+		return b
+	}
+
+	cases := []struct {
+		in   string
+		want []BylinesElem
+	}{
+		{"", nil},
+		{"Anna Andersson/TT", []BylinesElem{anna}},
+		{"  Anna  Andersson / TT. ", []BylinesElem{anna}},
+		{"Anna Andersson, TT", []BylinesElem{anna}},
+		{"Anna Andersson och Bo Ek/TT", []BylinesElem{anna, bo}},
+		{"Anna Andersson & Bo Ek, TT", []BylinesElem{anna, bo}},
+		{"Anna Andersson/TT, Bo Ek/TT", []BylinesElem{anna, bo}},
+		{"Anna Andersson/TT; Bo Ek/SvD", []BylinesElem{
+			anna,
+			with(bo, func(b *BylinesElem) {
+				b.Affiliation, b.Byline = "SvD", "Bo Ek/SvD"
+			}),
+		}},
+		{"Anna Andersson/TT, AP", []BylinesElem{
+			anna,
+			{Affiliation: "AP", Byline: "AP"},
+		}},
+		{"Anna Andersson/SvD/TT", []BylinesElem{
+			with(anna, func(b *BylinesElem) {
+				b.Affiliation, b.Byline = "SvD/TT", "Anna Andersson/SvD/TT"
+			}),
+		}},
+		{"Carl von Essen/TT", []BylinesElem{{
+			Firstname: "Carl", Lastname: "von Essen",
+			Affiliation: "TT", Byline: "Carl von Essen/TT",
+		}}},
+		{"Anna Andersson <anna.andersson@tt.se>/TT", []BylinesElem{
+			with(anna, func(b *BylinesElem) { b.Email = "anna.andersson@tt.se" }),
+		}},
+		{"aa/TT", []BylinesElem{{Initials: "aa", Affiliation: "TT", Byline: "aa/TT"}}},
+		{"aa, TT", []BylinesElem{{Initials: "aa", Affiliation: "TT", Byline: "aa/TT"}}},
+		{"Foto: AP", []BylinesElem{{
+			Affiliation: "AP", Byline: "AP", Role: BylineRolePhotographer,
+		}}},
+		{"Anna Andersson/TT, Foto: AP", []BylinesElem{
+			anna,
+			{Affiliation: "AP", Byline: "AP", Role: BylineRolePhotographer},
+		}},
+		{"Text: Anna Andersson/TT Foto: Bo Ek/TT", []BylinesElem{
+			with(anna, func(b *BylinesElem) { b.Role = BylineRoleReporter }),
+			with(bo, func(b *BylinesElem) { b.Role = BylineRolePhotographer }),
+		}},
+		{"text: Anna Andersson och Bo Ek, TT. Grafik: Bo Ek/TT", []BylinesElem{
+			with(anna, func(b *BylinesElem) { b.Role = BylineRoleReporter }),
+			with(bo, func(b *BylinesElem) { b.Role = BylineRoleReporter }),
+			with(bo, func(b *BylinesElem) { b.Role = BylineRoleGraphics }),
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			got := ParseByline(c.in)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got\n%+v\nwant\n%+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRenderByline(t *testing.T) {
+	bylines := []BylinesElem{
+		{Firstname: "Anna", Lastname: "Andersson", Affiliation: "TT"},
+		{Firstname: "Bo", Lastname: "Ek", Affiliation: "TT", Role: "fotograf"},
+		{Initials: "ce", Internal: "true"},
+		{Byline: "AP", Affiliation: "AP", Role: BylineRolePhotographer},
+		{Firstname: "Dan", Lastname: "Dahl", Role: BylineRoleGraphics},
+		{Firstname: "Eva", Lastname: "Eriksson", Affiliation: "SvD", Role: BylineRoleReporter},
+	}
+
+	cases := []struct {
+		style BylineStyle
+		want  string
+	}{
+		{BylineStyleTT, "Anna Andersson/TT, Eva Eriksson/SvD, Foto: Bo Ek/TT, AP, Grafik: Dan Dahl"},
+		{BylineStyleNames, "Anna Andersson, Bo Ek, Dan Dahl och Eva Eriksson"},
+		{BylineStyleInitials, "aa/be/ce/dd/ee"},
+	}
+
+	for _, c := range cases {
+		if got := RenderByline(bylines, c.style); got != c.want {
+			t.Errorf("style %d: got %q, want %q", c.style, got, c.want)
+		}
+	}
+
+	if got := RenderByline(bylines[:1], BylineStyleNames); got != "Anna Andersson" {
+		t.Errorf("got %q for a single name", got)
+	}
+
+	if got := RenderByline(nil, BylineStyleTT); got != "" {
+		t.Errorf("got %q for no bylines", got)
+	}
+}
+
+func TestBylineRoundTrip(t *testing.T) {
+	cases := map[string]string{
+		"Anna Andersson/TT":                       "Anna Andersson/TT",
+		"Anna Andersson, TT":                      "Anna Andersson/TT",
+		"Anna Andersson och Bo Ek/TT":             "Anna Andersson/TT, Bo Ek/TT",
+		"Anna Andersson/TT, Bo Ek/SvD":            "Anna Andersson/TT, Bo Ek/SvD",
+		"Anna Andersson/TT, Foto: AP":             "Anna Andersson/TT, Foto: AP",
+		"Text: Anna Andersson/TT Foto: Bo Ek/TT":  "Anna Andersson/TT, Foto: Bo Ek/TT",
+		"Foto: Bo Ek/TT, Text: Anna Andersson/TT": "Anna Andersson/TT, Foto: Bo Ek/TT",
+		"Carl von Essen/SvD/TT":                   "Carl von Essen/SvD/TT",
+	}
+
+	for in, want := range cases {
+		t.Run(in, func(t *testing.T) {
+			doc := Document{Uri: "a", Byline: in}
+
+			if problems := ReconcileBylines(&doc); problems != nil {
+				t.Fatalf("got problems %v", problems)
+			}
+
+			if got := RenderByline(doc.Bylines, BylineStyleTT); got != want {
+				t.Fatalf("rendered %q, want %q", got, want)
+			}
+
+			// Rendering the structured bylines and parsing them
+			// again gives the same bylines.
+			rendered := Document{Uri: "a", Bylines: doc.Bylines}
+
+			ReconcileBylines(&rendered)
+
+			if rendered.Byline != want {
+				t.Fatalf("reconciled byline %q, want %q", rendered.Byline, want)
+			}
+
+			if got := ParseByline(rendered.Byline); len(got) != len(doc.Bylines) {
+				t.Fatalf("parsed %d bylines, want %d", len(got), len(doc.Bylines))
+			}
+
+			if problems := ReconcileBylines(&rendered); problems != nil {
+				t.Errorf("got problems %v for the rendered byline", problems)
+			}
+		})
+	}
+}
+
+func TestReconcileBylines(t *testing.T) {
+	t.Run("names from byline strings", func(t *testing.T) {
+		doc := Document{Uri: "a", Bylines: []BylinesElem{
+			{Byline: "Anna Andersson/TT"},
+			{Byline: "Bo Ek/TT", Affiliation: "SvD"},
+		}}
+
+		if problems := ReconcileBylines(&doc); problems != nil {
+			t.Fatalf("got problems %v", problems)
+		}
+
+		want := []BylinesElem{
+			{Byline: "Anna Andersson/TT", Firstname: "Anna", Lastname: "Andersson", Affiliation: "TT"},
+			{Byline: "Bo Ek/TT", Firstname: "Bo", Lastname: "Ek", Affiliation: "SvD"},
+		}
+
+		if !reflect.DeepEqual(doc.Bylines, want) {
+			t.Errorf("got %+v", doc.Bylines)
+		}
+
+		if doc.Byline != "Anna Andersson/TT, Bo Ek/SvD" {
+			t.Errorf("got byline %q", doc.Byline)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		doc := Document{Uri: "a"}
+
+		if problems := ReconcileBylines(&doc); problems != nil || doc.Byline != "" || doc.Bylines != nil {
+			t.Errorf("got %v for an empty document: %+v", problems, doc)
+		}
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		doc := Document{
+			Uri:    "a",
+			Byline: "Anna Andersson/TT, Foto: Bo Ek/AP, Carl Berg/TT",
+			Bylines: []BylinesElem{
+				{Firstname: "Anna", Lastname: "Andersson", Affiliation: "tt"},
+				{Firstname: "Bo", Lastname: "Ek", Affiliation: "TT", Role: "Reporter"},
+				{Firstname: "Dan", Lastname: "Dahl", Affiliation: "TT"},
+				{Initials: "ee", Internal: "true"},
+			},
+		}
+
+		var got []string
+
+		for _, p := range ReconcileBylines(&doc) {
+			got = append(got, p.String())
+		}
+
+		want := []string{
+			`Bo Ek: affiliation is "AP" in byline but "TT" in bylines`,
+			`Bo Ek: role is "Photographer" in byline but "Reporter" in bylines`,
+			"Carl Berg: is in byline but not in bylines",
+			"Dan Dahl: is in bylines but not in byline",
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
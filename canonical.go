@@ -0,0 +1,284 @@
+package ttninjs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// DefaultHashExclusions are the fields that change without the content of a
+// document changing, and that are excluded from content hashes by default.
+var DefaultHashExclusions = []string{
+	"signature",
+	"versionstored",
+	"versioncreated",
+	"signals.deliverytags",
+}
+
+// Canonicalize returns the canonical JSON serialisation of a document as
+// defined by RFC 8785, the JSON Canonicalization Scheme (JCS).
+func Canonicalize(doc *Document) ([]byte, error) {
+	v, err := documentValue(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return canonicalJSON(v)
+}
+
+// HashOptions controls how content hashes are calculated.
+type HashOptions struct {
+	// Exclude is a list of dot separated field paths to leave out of the
+	// hash. DefaultHashExclusions is used if Exclude is nil.
+	Exclude []string
+}
+
+// ContentHash calculates a SHA-256 hash of the canonical JSON of a document,
+// with volatile fields excluded. The hash is calculated recursively, each
+// association is replaced by its own content hash before the document is
+// hashed. The hash is returned as a hex encoded string.
+func ContentHash(doc *Document, opts HashOptions) (string, error) {
+	exclude := opts.Exclude
+	if exclude == nil {
+		exclude = DefaultHashExclusions
+	}
+
+	v, err := documentValue(doc)
+	if err != nil {
+		return "", err
+	}
+
+	sum, err := contentHash(v, exclude)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(sum), nil
+}
+
+func contentHash(doc map[string]any, exclude []string) ([]byte, error) {
+	for _, path := range exclude {
+		removePath(doc, strings.Split(path, "."))
+	}
+
+	if assoc, ok := doc["associations"].(map[string]any); ok {
+		for key, v := range assoc {
+			a, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			sum, err := contentHash(a, exclude)
+			if err != nil {
+				return nil, fmt.Errorf("hash association %q: %w", key, err)
+			}
+
+			assoc[key] = hex.EncodeToString(sum)
+		}
+	}
+
+	data, err := canonicalJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return sum[:], nil
+}
+
+func removePath(v map[string]any, path []string) {
+	if len(path) == 1 {
+		delete(v, path[0])
+
+		return
+	}
+
+	child, ok := v[path[0]].(map[string]any)
+	if !ok {
+		return
+	}
+
+	removePath(child, path[1:])
+
+	// Don't leave behind empty objects that would make the hash depend on
+	// whether the excluded field was set or not.
+	if len(child) == 0 {
+		delete(v, path[0])
+	}
+}
+
+// documentValue returns the generic JSON representation of a document.
+func documentValue(doc *Document) (map[string]any, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal document: %w", err)
+	}
+
+	dec := stdjson.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v map[string]any
+
+	err = dec.Decode(&v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode marshalled document: %w", err)
+	}
+
+	return v, nil
+}
+
+// canonicalJSON serialises a generic JSON value according to RFC 8785.
+func canonicalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := writeCanonical(&buf, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, v any) error {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(val))
+	case string:
+		writeCanonicalString(buf, val)
+	case stdjson.Number:
+		f, err := val.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid number %q: %w", val, err)
+		}
+
+		n, err := canonicalNumber(f)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString(n)
+	case float64:
+		n, err := canonicalNumber(val)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString(n)
+	case []any:
+		buf.WriteByte('[')
+
+		for i, item := range val {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			err := writeCanonical(buf, item)
+			if err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+
+		for k := range val {
+			keys = append(keys, k)
+		}
+
+		// JCS sorts keys by their UTF-16 code units.
+		slices.SortFunc(keys, func(a, b string) int {
+			return slices.Compare(utf16.Encode([]rune(a)), utf16.Encode([]rune(b)))
+		})
+
+		buf.WriteByte('{')
+
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			writeCanonicalString(buf, k)
+			buf.WriteByte(':')
+
+			err := writeCanonical(buf, val[k])
+			if err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unsupported JSON value type %T", v)
+	}
+
+	return nil
+}
+
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+
+	buf.WriteByte('"')
+}
+
+// canonicalNumber formats a number like ECMAScript Number.prototype.toString,
+// as required by RFC 8785.
+func canonicalNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", errors.New("NaN and Infinity are not valid JSON numbers")
+	}
+
+	if f == 0 {
+		return "0", nil
+	}
+
+	abs := math.Abs(f)
+	if abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+
+	// Go pads the exponent to two digits, ECMAScript doesn't.
+	mantissa, exp, _ := strings.Cut(s, "e")
+	sign := exp[0]
+	exp = strings.TrimLeft(exp[1:], "0")
+
+	return mantissa + "e" + string(sign) + exp, nil
+}
@@ -0,0 +1,234 @@
+package ttninjs
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func canonicalString(t *testing.T, input string) string {
+	t.Helper()
+
+	dec := stdjson.NewDecoder(bytes.NewReader([]byte(input)))
+	dec.UseNumber()
+
+	var v any
+
+	err := dec.Decode(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := canonicalJSON(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(out)
+}
+
+// TestCanonicalNumbers uses the IEEE 754 test vectors of RFC 8785 appendix B.
+func TestCanonicalNumbers(t *testing.T) {
+	cases := []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	}
+
+	for _, c := range cases {
+		got, err := canonicalNumber(math.Float64frombits(c.bits))
+		if err != nil {
+			t.Errorf("%016x: %v", c.bits, err)
+
+			continue
+		}
+
+		if got != c.want {
+			t.Errorf("%016x: got %s, expected %s", c.bits, got, c.want)
+		}
+	}
+
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := canonicalNumber(f); err == nil {
+			t.Errorf("expected an error for %v", f)
+		}
+	}
+}
+
+// TestCanonicalJSON uses the examples of RFC 8785 section 3.2.
+func TestCanonicalJSON(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "serialization",
+			input: `{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`,
+			want: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		{
+			name: "sorting",
+			input: `{
+  "\u20ac": "Euro Sign",
+  "\r": "Carriage Return",
+  "\ufb33": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "\ud83d\ude00": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "\u00f6": "Latin Small Letter O With Diaeresis"
+}`,
+			want: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\"," +
+				"\"ö\":\"Latin Small Letter O With Diaeresis\",\"€\":\"Euro Sign\"," +
+				"\"😀\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			name:  "escapes",
+			input: `["\b\f\n\r\t\u0001\u001f <>&\u2028"]`,
+			want:  "[\"\\b\\f\\n\\r\\t\\u0001\\u001f <>&\u2028\"]",
+		},
+		{
+			name:  "nested",
+			input: `{"b":[{"d":1,"c":{}}],"a":[]}`,
+			want:  `{"a":[],"b":[{"c":{},"d":1}]}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := canonicalString(t, c.input); got != c.want {
+				t.Errorf("got  %s\nwant %s", got, c.want)
+			}
+		})
+	}
+}
+
+func hashTestDocument() *Document {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	return &Document{
+		Uri:            "http://tt.se/text/1",
+		Type:           TypeText,
+		Headline:       "Rubrik",
+		Versioncreated: created,
+		Associations: Associations{
+			"img1": Document{
+				Uri:            "http://tt.se/media/image/1",
+				Type:           TypePicture,
+				Headline:       "Bild",
+				Versioncreated: created,
+			},
+		},
+	}
+}
+
+func contentHashOf(t *testing.T, doc *Document, opts HashOptions) string {
+	t.Helper()
+
+	h, err := ContentHash(doc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+func TestContentHash(t *testing.T) {
+	base := contentHashOf(t, hashTestDocument(), HashOptions{})
+
+	if len(base) != 64 {
+		t.Fatalf("hash %q isn't a hex encoded SHA-256", base)
+	}
+
+	stored := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+
+	volatile := []func(d *Document){
+		func(d *Document) { d.Versioncreated = d.Versioncreated.Add(time.Hour) },
+		func(d *Document) { d.Versionstored = &stored },
+		func(d *Document) { d.Signature = "e30..AAAA" },
+		func(d *Document) { d.Signals.Deliverytags = []string{"a"} },
+		func(d *Document) {
+			a := d.Associations["img1"]
+			a.Versioncreated = a.Versioncreated.Add(time.Hour)
+			d.Associations["img1"] = a
+		},
+	}
+
+	for i, change := range volatile {
+		doc := hashTestDocument()
+		change(doc)
+
+		if h := contentHashOf(t, doc, HashOptions{}); h != base {
+			t.Errorf("volatile change %d changed the hash", i)
+		}
+	}
+
+	content := []func(d *Document){
+		func(d *Document) { d.Headline = "Ny rubrik" },
+		func(d *Document) {
+			a := d.Associations["img1"]
+			a.Headline = "Ny bild"
+			d.Associations["img1"] = a
+		},
+	}
+
+	for i, change := range content {
+		doc := hashTestDocument()
+		change(doc)
+
+		if h := contentHashOf(t, doc, HashOptions{}); h == base {
+			t.Errorf("content change %d didn't change the hash", i)
+		}
+	}
+
+	// With an explicit exclusion list the default exclusions don't apply.
+	doc := hashTestDocument()
+	doc.Signature = "e30..AAAA"
+
+	opts := HashOptions{Exclude: []string{"headline"}}
+	if contentHashOf(t, doc, opts) == contentHashOf(t, hashTestDocument(), opts) {
+		t.Errorf("signature should be hashed when not excluded")
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	out, err := Canonicalize(&Document{Uri: "http://tt.se/text/1", Type: TypeText, Headline: "Å"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"headline":"Å","type":"text","uri":"http://tt.se/text/1"}`
+	if string(out) != want {
+		t.Errorf("got %s, expected %s", out, want)
+	}
+}
@@ -0,0 +1,363 @@
+package ttninjs
+
+import (
+	"bufio"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+var ErrUnsupportedStream = errors.New("stream is neither a JSON array, an object envelope nor a stream of objects")
+
+// DecodeError is returned when a document in a stream cannot be decoded.
+type DecodeError struct {
+	// Offset is the byte offset in the stream where reading of the item
+	// started, or where a syntax error occurred.
+	Offset int64
+	// Index is the zero based index of the item in the stream.
+	Index int
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("item %d at offset %d: %v", e.Index, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+type streamMode int
+
+const (
+	modeUnknown streamMode = iota
+	modeArray
+	modeStream
+	modeDone
+)
+
+// DecoderOption configures a Decoder.
+type DecoderOption func(d *Decoder)
+
+// WithSkip makes the decoder skip items that are valid JSON but cannot be
+// decoded as documents, f.ex. because they lack an uri or have invalid enum
+// values. The onSkip function, which can be nil, is called for every skipped
+// item. Syntax errors in the JSON stream cannot be recovered from and are
+// always returned.
+func WithSkip(onSkip func(err *DecodeError)) DecoderOption {
+	return func(d *Decoder) {
+		d.skip = true
+		d.onSkip = onSkip
+	}
+}
+
+// WithEnvelopeKeys sets the object keys that are searched for the array of
+// documents when the stream is an object envelope. Defaults to "hits". Without
+// keys the stream is never treated as an envelope.
+func WithEnvelopeKeys(keys ...string) DecoderOption {
+	return func(d *Decoder) {
+		d.envelopeKeys = keys
+	}
+}
+
+// Decoder reads documents one at a time from a stream. The stream format is
+// detected automatically and can be a JSON array of documents, a stream of
+// documents (f.ex. NDJSON), or an object envelope like {"hits": [...]}. An
+// envelope key can point to a nested envelope as in {"hits": {"hits": [...]}}.
+//
+// The format is detected from the first object, so a stream of documents where
+// the first document has an array or object member named like an envelope key
+// is read as an envelope. Use WithEnvelopeKeys to change or disable the
+// envelope keys for such streams.
+type Decoder struct {
+	dec          *stdjson.Decoder
+	mode         streamMode
+	index        int
+	skip         bool
+	onSkip       func(err *DecodeError)
+	envelopeKeys []string
+
+	// pending is a document read while detecting the stream format.
+	pending       stdjson.RawMessage
+	pendingOffset int64
+	// depth is the number of envelope objects we're inside of.
+	depth int
+	// detectErr is the error from detecting the stream format.
+	detectErr error
+}
+
+// NewDecoder creates a decoder that reads from r.
+func NewDecoder(r io.Reader, opts ...DecoderOption) *Decoder {
+	d := Decoder{
+		dec:          stdjson.NewDecoder(bufio.NewReader(r)),
+		envelopeKeys: []string{"hits"},
+	}
+
+	// Keep numbers intact when re-encoding values read as tokens.
+	d.dec.UseNumber()
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d
+}
+
+// Decode reads the next document from the stream into doc. It returns io.EOF
+// when there are no more documents.
+func (d *Decoder) Decode(doc *Document) error {
+	for {
+		raw, offset, err := d.nextRaw()
+		if err != nil {
+			return err
+		}
+
+		index := d.index
+		d.index++
+
+		var item Document
+
+		err = json.Unmarshal(raw, &item)
+		if err != nil {
+			decErr := &DecodeError{Offset: offset, Index: index, Err: err}
+
+			if !d.skip {
+				return decErr
+			}
+
+			if d.onSkip != nil {
+				d.onSkip(decErr)
+			}
+
+			continue
+		}
+
+		*doc = item
+
+		return nil
+	}
+}
+
+// More reports whether there is another item in the stream. An error while
+// detecting the format of the stream is reported by the following call to
+// Decode.
+func (d *Decoder) More() bool {
+	err := d.detectOnce()
+	if err != nil {
+		return !errors.Is(err, io.EOF)
+	}
+
+	switch d.mode {
+	case modeArray:
+		return d.dec.More()
+	case modeStream:
+		if d.pending != nil || d.dec.More() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *Decoder) syntaxError(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return &DecodeError{
+		Offset: d.dec.InputOffset(),
+		Index:  d.index,
+		Err:    err,
+	}
+}
+
+// nextRaw returns the next raw item in the stream and its offset.
+func (d *Decoder) nextRaw() (stdjson.RawMessage, int64, error) {
+	err := d.detectOnce()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if d.pending != nil {
+		raw := d.pending
+		d.pending = nil
+
+		return raw, d.pendingOffset, nil
+	}
+
+	switch d.mode {
+	case modeArray:
+		if !d.dec.More() {
+			return nil, 0, d.finishArray()
+		}
+	case modeStream:
+		if !d.dec.More() {
+			d.mode = modeDone
+
+			return nil, 0, io.EOF
+		}
+	case modeDone:
+		return nil, 0, io.EOF
+	}
+
+	var raw stdjson.RawMessage
+
+	err = d.dec.Decode(&raw)
+	if err != nil {
+		return nil, 0, d.syntaxError(err)
+	}
+
+	// The offset before decoding would point at the separating comma and
+	// whitespace, count back from the end of the item instead.
+	offset := d.dec.InputOffset() - int64(len(raw))
+
+	return raw, offset, nil
+}
+
+// finishArray consumes the end of the document array and any envelopes around
+// it.
+func (d *Decoder) finishArray() error {
+	// Closing bracket of the array.
+	_, err := d.dec.Token()
+	if err != nil {
+		return d.syntaxError(err)
+	}
+
+	// Consume the remaining keys of the envelopes.
+	for ; d.depth > 0; d.depth-- {
+		for d.dec.More() {
+			// Key.
+			_, err := d.dec.Token()
+			if err != nil {
+				return d.syntaxError(err)
+			}
+
+			var discard stdjson.RawMessage
+
+			err = d.dec.Decode(&discard)
+			if err != nil {
+				return d.syntaxError(err)
+			}
+		}
+
+		_, err := d.dec.Token()
+		if err != nil {
+			return d.syntaxError(err)
+		}
+	}
+
+	d.mode = modeDone
+
+	return io.EOF
+}
+
+// detectOnce detects the format of the stream if it hasn't been detected yet,
+// and returns the error from the detection.
+func (d *Decoder) detectOnce() error {
+	if d.mode == modeUnknown && d.detectErr == nil {
+		d.detectErr = d.detect()
+	}
+
+	return d.detectErr
+}
+
+// detect reads the start of the stream to determine its format.
+func (d *Decoder) detect() error {
+	tok, err := d.dec.Token()
+	if errors.Is(err, io.EOF) {
+		d.mode = modeDone
+
+		return io.EOF
+	} else if err != nil {
+		return d.syntaxError(err)
+	}
+
+	switch tok {
+	case stdjson.Delim('['):
+		d.mode = modeArray
+
+		return nil
+	case stdjson.Delim('{'):
+		return d.detectObject()
+	}
+
+	return &DecodeError{
+		Offset: d.dec.InputOffset(),
+		Err:    ErrUnsupportedStream,
+	}
+}
+
+// detectObject reads the keys of an object until it finds an envelope key with
+// an array or object value. If none is found the object is treated as the first
+// document of a stream of documents.
+func (d *Decoder) detectObject() error {
+	start := d.dec.InputOffset() - 1
+	fields := make(map[string]stdjson.RawMessage)
+
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return d.syntaxError(err)
+		}
+
+		key, _ := tok.(string)
+
+		if slices.Contains(d.envelopeKeys, key) {
+			valueTok, err := d.dec.Token()
+			if err != nil {
+				return d.syntaxError(err)
+			}
+
+			switch valueTok {
+			case stdjson.Delim('['):
+				d.depth++
+				d.mode = modeArray
+
+				return nil
+			case stdjson.Delim('{'):
+				d.depth++
+
+				return d.detectObject()
+			}
+
+			fields[key], _ = stdjson.Marshal(valueTok)
+
+			continue
+		}
+
+		var value stdjson.RawMessage
+
+		err = d.dec.Decode(&value)
+		if err != nil {
+			return d.syntaxError(err)
+		}
+
+		fields[key] = value
+	}
+
+	// Closing brace.
+	_, err := d.dec.Token()
+	if err != nil {
+		return d.syntaxError(err)
+	}
+
+	if d.depth > 0 {
+		return &DecodeError{
+			Offset: start,
+			Err:    fmt.Errorf("%w: no document array in envelope", ErrUnsupportedStream),
+		}
+	}
+
+	pending, err := stdjson.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to reassemble first document: %w", err)
+	}
+
+	d.mode = modeStream
+	d.pending = pending
+	d.pendingOffset = start
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package ttninjs
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDecoderErrorOffset(t *testing.T) {
+	cases := []struct {
+		stream string
+		offset int64
+		index  int
+	}{
+		{`[{"uri":"a"},   {"nouri":1}]`, 16, 1},
+		{`[{"uri":"a"},{"nouri":1}]`, 13, 1},
+		{"{\"uri\":\"a\"}\n\n  {\"nouri\":1}\n", 15, 1},
+		{`{"hits": [ {"nouri":1}]}`, 11, 0},
+		{` {"nouri":1}`, 1, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.stream, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(c.stream))
+
+			var err error
+
+			for err == nil {
+				var doc Document
+
+				err = dec.Decode(&doc)
+			}
+
+			var decErr *DecodeError
+			if !errors.As(err, &decErr) {
+				t.Fatalf("expected a *DecodeError, got %v", err)
+			}
+
+			if decErr.Offset != c.offset || decErr.Index != c.index {
+				t.Errorf("got item %d at offset %d, expected item %d at offset %d",
+					decErr.Index, decErr.Offset, c.index, c.offset)
+			}
+		})
+	}
+}
+
+func TestDecoderSkip(t *testing.T) {
+	var skipped []int64
+
+	dec := NewDecoder(
+		strings.NewReader(`[{"uri":"a"}, {"nouri":1}, {"uri":"b"}]`),
+		WithSkip(func(err *DecodeError) {
+			skipped = append(skipped, err.Offset)
+		}))
+
+	var uris []string
+
+	for {
+		var doc Document
+
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		uris = append(uris, doc.Uri)
+	}
+
+	if strings.Join(uris, ",") != "a,b" {
+		t.Errorf("got documents %v", uris)
+	}
+
+	if len(skipped) != 1 || skipped[0] != 14 {
+		t.Errorf("skipped items at %v, expected 14", skipped)
+	}
+}
+
+func decodeAll(t *testing.T, dec *Decoder) ([]string, error) {
+	t.Helper()
+
+	var uris []string
+
+	for dec.More() {
+		var doc Document
+
+		err := dec.Decode(&doc)
+		if err != nil {
+			return uris, err
+		}
+
+		uris = append(uris, doc.Uri)
+	}
+
+	var doc Document
+
+	err := dec.Decode(&doc)
+	if !errors.Is(err, io.EOF) {
+		return uris, err
+	}
+
+	return uris, nil
+}
+
+func TestDecoderFormats(t *testing.T) {
+	cases := []struct {
+		name   string
+		stream string
+		opts   []DecoderOption
+		want   []string
+	}{
+		{name: "empty"},
+		{name: "whitespace", stream: " \n"},
+		{name: "empty array", stream: "[]"},
+		{
+			name:   "array",
+			stream: `[{"uri":"a"}, {"uri":"b","headline":"[{"}]`,
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "ndjson",
+			stream: "{\"uri\":\"a\",\"urgency\":4}\n\n{\"uri\":\"b\"}\n{\"uri\":\"c\"}\n",
+			want:   []string{"a", "b", "c"},
+		},
+		{
+			name:   "concatenated",
+			stream: `{"uri":"a"}{"uri":"b"}`,
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "ndjson with non-array hits",
+			stream: "{\"hits\":2,\"uri\":\"a\"}\n{\"uri\":\"b\"}\n",
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "envelope",
+			stream: `{"took":5,"hits":[{"uri":"a"},{"uri":"b"}],"total":2}`,
+			want:   []string{"a", "b"},
+		},
+		{
+			name: "nested envelope",
+			stream: `{"took":5,"hits":{"total":{"value":2},"max_score":null,` +
+				`"hits":[{"uri":"a"},{"uri":"b"}],"after":["x"]},"timed_out":false}`,
+			want: []string{"a", "b"},
+		},
+		{
+			name:   "envelope keys",
+			stream: `{"meta":{"hits":1},"data":{"items":[{"uri":"a"}]}}`,
+			opts:   []DecoderOption{WithEnvelopeKeys("data", "items")},
+			want:   []string{"a"},
+		},
+		{
+			name:   "without envelope keys",
+			stream: "{\"uri\":\"a\",\"hits\":[1]}\n{\"uri\":\"b\"}\n",
+			opts:   []DecoderOption{WithEnvelopeKeys()},
+			want:   []string{"a", "b"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := decodeAll(t, NewDecoder(strings.NewReader(c.stream), c.opts...))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(got, c.want) {
+				t.Errorf("got %v, expected %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDecoderUnsupported(t *testing.T) {
+	cases := []struct {
+		name   string
+		stream string
+		want   error
+	}{
+		{name: "string", stream: `"uri"`, want: ErrUnsupportedStream},
+		{name: "number", stream: `12`, want: ErrUnsupportedStream},
+		{name: "envelope without array", stream: `{"hits":{"total":0}}`, want: ErrUnsupportedStream},
+		// The first document of the stream is read as an envelope, see
+		// the Decoder documentation.
+		{
+			name:   "ndjson with array hits",
+			stream: "{\"uri\":\"a\",\"hits\":[1]}\n{\"uri\":\"b\"}\n",
+		},
+		{name: "truncated", stream: `[{"uri":"a"}`},
+		{name: "truncated envelope", stream: `{"hits":[{"uri":"a"}]`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := decodeAll(t, NewDecoder(strings.NewReader(c.stream)))
+
+			var decErr *DecodeError
+			if !errors.As(err, &decErr) {
+				t.Fatalf("expected a *DecodeError, got %v", err)
+			}
+
+			if c.want != nil && !errors.Is(err, c.want) {
+				t.Errorf("got %v, expected %v", err, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package dedup
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ttab/ttninjs"
+)
+
+type corpusItem struct {
+	ID       string `json:"id"`
+	Cluster  string `json:"cluster"`
+	Headline string `json:"headline"`
+	Body     string `json:"body"`
+}
+
+func loadCorpus(t *testing.T) []corpusItem {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "swedish.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []corpusItem
+
+	err = json.Unmarshal(data, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return items
+}
+
+func (item corpusItem) document() *ttninjs.Document {
+	return &ttninjs.Document{
+		Uri:      item.ID,
+		Type:     ttninjs.TypeText,
+		Headline: item.Headline,
+		BodyText: item.Body,
+	}
+}
+
+// Thresholds for the corpus: near-duplicates must be at least this similar,
+// and unrelated stories at most.
+const (
+	duplicateThreshold = 0.5
+	unrelatedThreshold = 0.1
+)
+
+func TestCorpusSimilarity(t *testing.T) {
+	items := loadCorpus(t)
+
+	fps := make([]Fingerprint, len(items))
+	for i, item := range items {
+		fps[i] = FingerprintDocument(item.document())
+	}
+
+	for i := range items {
+		for j := i + 1; j < len(items); j++ {
+			sim := fps[i].Similarity(fps[j])
+			same := items[i].Cluster == items[j].Cluster
+
+			switch {
+			case same && sim < duplicateThreshold:
+				t.Errorf("%s and %s are near-duplicates but have similarity %.2f",
+					items[i].ID, items[j].ID, sim)
+			case !same && sim > unrelatedThreshold:
+				t.Errorf("%s and %s are unrelated but have similarity %.2f",
+					items[i].ID, items[j].ID, sim)
+			}
+		}
+	}
+}
+
+func TestCorpusIndex(t *testing.T) {
+	items := loadCorpus(t)
+
+	idx := NewIndex()
+
+	for _, item := range items {
+		idx.AddDocument(item.document())
+	}
+
+	if idx.Len() != len(items) {
+		t.Fatalf("index has %d documents, expected %d", idx.Len(), len(items))
+	}
+
+	for _, item := range items {
+		got := make(map[string]bool)
+
+		for _, m := range idx.SimilarDocuments(item.document(), duplicateThreshold) {
+			got[m.ID] = true
+		}
+
+		for _, other := range items {
+			if other.ID == item.ID {
+				continue
+			}
+
+			want := other.Cluster == item.Cluster
+			if got[other.ID] != want {
+				t.Errorf("%s: match for %s is %v, expected %v",
+					item.ID, other.ID, got[other.ID], want)
+			}
+		}
+	}
+
+	idx.Remove("storm-edited")
+
+	for _, m := range idx.SimilarDocuments(items[3].document(), duplicateThreshold) {
+		if m.ID == "storm-edited" {
+			t.Errorf("removed document is still matched")
+		}
+	}
+}
+
+func TestEmptyFingerprint(t *testing.T) {
+	a := FingerprintDocument(&ttninjs.Document{Uri: "a", Type: ttninjs.TypePicture})
+	b := FingerprintDocument(&ttninjs.Document{Uri: "b", Type: ttninjs.TypeGraphic})
+
+	if !a.IsEmpty() || !b.IsEmpty() {
+		t.Fatal("documents without content should have empty fingerprints")
+	}
+
+	if sim := a.Similarity(b); sim != 0 {
+		t.Errorf("empty fingerprints have similarity %v, expected 0", sim)
+	}
+
+	idx := NewIndex()
+	idx.Add("a", a)
+	idx.Add("b", b)
+
+	if idx.Len() != 0 {
+		t.Errorf("empty fingerprints were added to the index")
+	}
+
+	if m := idx.Similar(a, 0); len(m) != 0 {
+		t.Errorf("empty fingerprint matched %v", m)
+	}
+}
@@ -0,0 +1,238 @@
+// Package dedup detects near-duplicate documents, like the same story sent by
+// different sources with small edits, using MinHash and SimHash fingerprints.
+package dedup
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+
+	"github.com/ttab/ttninjs"
+)
+
+// NumHashes is the number of MinHash values in a fingerprint.
+const NumHashes = 64
+
+// ShingleSize is the number of words in each shingle.
+const ShingleSize = 3
+
+// Fingerprint is a compact representation of the content of a document.
+type Fingerprint struct {
+	// SimHash is a 64 bit locality sensitive hash, similar documents have
+	// a small Hamming distance between their hashes.
+	SimHash uint64
+	// MinHash is used to estimate the Jaccard similarity of the shingle
+	// sets of two documents.
+	MinHash [NumHashes]uint64
+}
+
+// IsEmpty returns true if the fingerprint was calculated from a document or
+// text without any content.
+func (fp Fingerprint) IsEmpty() bool {
+	return fp == Fingerprint{}
+}
+
+// Similarity estimates the Jaccard similarity between the content of two
+// fingerprints, from 0 (nothing in common) to 1 (identical). Empty
+// fingerprints aren't similar to anything, not even to each other.
+func (fp Fingerprint) Similarity(other Fingerprint) float64 {
+	if fp.IsEmpty() || other.IsEmpty() {
+		return 0
+	}
+
+	var equal int
+
+	for i := range fp.MinHash {
+		if fp.MinHash[i] == other.MinHash[i] {
+			equal++
+		}
+	}
+
+	return float64(equal) / NumHashes
+}
+
+// Distance returns the Hamming distance between the SimHashes of two
+// fingerprints, from 0 to 64.
+func (fp Fingerprint) Distance(other Fingerprint) int {
+	return bits.OnesCount64(fp.SimHash ^ other.SimHash)
+}
+
+// FingerprintDocument calculates the fingerprint of a document from its
+// headline, body and subject codes. The body is taken from BodyText, or
+// extracted from the HTML body if there is no plain text body.
+func FingerprintDocument(doc *ttninjs.Document) Fingerprint {
+	body := doc.BodyText
+	if body == "" {
+		body = ttninjs.ExtractText(doc)
+	}
+
+	var features []feature
+
+	features = appendShingles(features, doc.Headline, 2)
+	features = appendShingles(features, body, 1)
+
+	for _, s := range doc.Subject {
+		if s.Code == "" {
+			continue
+		}
+
+		features = append(features, feature{
+			hash:   hashString("subject:" + s.Code),
+			weight: 1,
+		})
+	}
+
+	return fingerprint(features)
+}
+
+// FingerprintText calculates the fingerprint of a plain text.
+func FingerprintText(text string) Fingerprint {
+	return fingerprint(appendShingles(nil, text, 1))
+}
+
+type feature struct {
+	hash   uint64
+	weight int
+}
+
+// fingerprint calculates a fingerprint from features. Without features the
+// empty fingerprint is returned.
+func fingerprint(features []feature) Fingerprint {
+	var (
+		fp      Fingerprint
+		weights [64]int
+	)
+
+	if len(features) == 0 {
+		return fp
+	}
+
+	for i := range fp.MinHash {
+		fp.MinHash[i] = ^uint64(0)
+	}
+
+	for _, f := range features {
+		for b := 0; b < 64; b++ {
+			if f.hash&(1<<b) != 0 {
+				weights[b] += f.weight
+			} else {
+				weights[b] -= f.weight
+			}
+		}
+
+		for i := range fp.MinHash {
+			h := mix(f.hash ^ seeds[i])
+			if h < fp.MinHash[i] {
+				fp.MinHash[i] = h
+			}
+		}
+	}
+
+	for b, w := range weights {
+		if w > 0 {
+			fp.SimHash |= 1 << b
+		}
+	}
+
+	return fp
+}
+
+// appendShingles adds the word shingles of the text as features.
+func appendShingles(features []feature, text string, weight int) []feature {
+	words := tokenize(text)
+	if len(words) == 0 {
+		return features
+	}
+
+	if len(words) < ShingleSize {
+		return append(features, feature{
+			hash:   hashString(strings.Join(words, " ")),
+			weight: weight,
+		})
+	}
+
+	for i := 0; i+ShingleSize <= len(words); i++ {
+		features = append(features, feature{
+			hash:   hashString(strings.Join(words[i:i+ShingleSize], " ")),
+			weight: weight,
+		})
+	}
+
+	return features
+}
+
+// tokenize splits a text into lowercased words, dropping stop words.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	words := fields[:0]
+
+	for _, w := range fields {
+		if _, stop := stopWords[w]; stop {
+			continue
+		}
+
+		words = append(words, w)
+	}
+
+	return words
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+
+	return h.Sum64()
+}
+
+// mix is the splitmix64 finalizer, used to derive the MinHash permutations.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
+
+var seeds = func() [NumHashes]uint64 {
+	var s [NumHashes]uint64
+
+	x := uint64(0x9e3779b97f4a7c15)
+
+	for i := range s {
+		x += 0x9e3779b97f4a7c15
+		s[i] = mix(x)
+	}
+
+	return s
+}()
+
+// stopWords are common Swedish and English words that carry little meaning
+// for duplicate detection.
+var stopWords = func() map[string]struct{} {
+	words := []string{
+		// Swedish.
+		"och", "i", "att", "det", "som", "en", "på", "är", "av", "för",
+		"med", "till", "den", "har", "de", "inte", "om", "ett", "han",
+		"hon", "men", "var", "jag", "sig", "från", "vi", "så", "kan",
+		"man", "när", "år", "säger", "efter", "upp", "ut", "vid", "nu",
+		"sin", "sina", "hade", "eller", "också", "där", "då", "under",
+		"mot", "ska", "skulle", "kommer", "blir", "bli", "tt",
+		// English.
+		"the", "a", "an", "and", "of", "to", "in", "is", "for", "on",
+		"that", "with", "as", "at", "by", "it", "was", "from",
+	}
+
+	m := make(map[string]struct{}, len(words))
+
+	for _, w := range words {
+		m[w] = struct{}{}
+	}
+
+	return m
+}()
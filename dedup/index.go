@@ -0,0 +1,196 @@
+package dedup
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/ttab/ttninjs"
+)
+
+// Bands and rows per band used for locality sensitive hashing of the MinHash
+// values. With 16 bands of 4 rows, documents with a similarity of 0.5 are
+// found with a probability of about 64%, and documents with a similarity of
+// 0.7 with a probability of about 98.8%.
+const (
+	lshBands = 16
+	lshRows  = NumHashes / lshBands
+)
+
+// Match is a document found to be similar to a query.
+type Match struct {
+	ID         string
+	Similarity float64
+}
+
+// Index is an in-memory index of fingerprints that can answer which documents
+// are similar to a given one. An Index is safe for concurrent use.
+type Index struct {
+	m       sync.RWMutex
+	entries map[string]Fingerprint
+	buckets [lshBands]map[uint64][]string
+}
+
+// NewIndex creates an empty index.
+func NewIndex() *Index {
+	idx := Index{
+		entries: make(map[string]Fingerprint),
+	}
+
+	for i := range idx.buckets {
+		idx.buckets[i] = make(map[uint64][]string)
+	}
+
+	return &idx
+}
+
+// Len returns the number of fingerprints in the index.
+func (idx *Index) Len() int {
+	idx.m.RLock()
+	defer idx.m.RUnlock()
+
+	return len(idx.entries)
+}
+
+// AddDocument adds the fingerprint of a document to the index using its URI as
+// ID.
+func (idx *Index) AddDocument(doc *ttninjs.Document) {
+	idx.Add(doc.Uri, FingerprintDocument(doc))
+}
+
+// Add adds a fingerprint to the index, replacing any existing fingerprint with
+// the same ID. Empty fingerprints, f.ex. of pictures without any text, aren't
+// added, but still replace an existing fingerprint.
+func (idx *Index) Add(id string, fp Fingerprint) {
+	idx.m.Lock()
+	defer idx.m.Unlock()
+
+	if _, exists := idx.entries[id]; exists {
+		idx.remove(id)
+	}
+
+	if fp.IsEmpty() {
+		return
+	}
+
+	idx.entries[id] = fp
+
+	for band, key := range bandKeys(fp) {
+		idx.buckets[band][key] = append(idx.buckets[band][key], id)
+	}
+}
+
+// Remove removes a fingerprint from the index.
+func (idx *Index) Remove(id string) {
+	idx.m.Lock()
+	defer idx.m.Unlock()
+
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id string) {
+	fp, ok := idx.entries[id]
+	if !ok {
+		return
+	}
+
+	delete(idx.entries, id)
+
+	for band, key := range bandKeys(fp) {
+		ids := idx.buckets[band][key]
+
+		for i := range ids {
+			if ids[i] == id {
+				ids = append(ids[:i], ids[i+1:]...)
+
+				break
+			}
+		}
+
+		if len(ids) == 0 {
+			delete(idx.buckets[band], key)
+		} else {
+			idx.buckets[band][key] = ids
+		}
+	}
+}
+
+// SimilarDocuments returns the indexed documents that are similar to the
+// document above the threshold. The document itself is excluded from the
+// result.
+func (idx *Index) SimilarDocuments(doc *ttninjs.Document, threshold float64) []Match {
+	matches := idx.Similar(FingerprintDocument(doc), threshold)
+
+	res := matches[:0]
+
+	for _, m := range matches {
+		if m.ID != doc.Uri {
+			res = append(res, m)
+		}
+	}
+
+	return res
+}
+
+// Similar returns the indexed fingerprints with an estimated similarity of at
+// least threshold, most similar first. Candidates are found through locality
+// sensitive hashing, so matches with a low similarity can be missed. An empty
+// fingerprint has no matches.
+func (idx *Index) Similar(fp Fingerprint, threshold float64) []Match {
+	if fp.IsEmpty() {
+		return nil
+	}
+
+	idx.m.RLock()
+	defer idx.m.RUnlock()
+
+	seen := make(map[string]struct{})
+
+	var matches []Match
+
+	for band, key := range bandKeys(fp) {
+		for _, id := range idx.buckets[band][key] {
+			if _, done := seen[id]; done {
+				continue
+			}
+
+			seen[id] = struct{}{}
+
+			sim := fp.Similarity(idx.entries[id])
+			if sim >= threshold {
+				matches = append(matches, Match{ID: id, Similarity: sim})
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Similarity != matches[j].Similarity {
+			return matches[i].Similarity > matches[j].Similarity
+		}
+
+		return matches[i].ID < matches[j].ID
+	})
+
+	return matches
+}
+
+func bandKeys(fp Fingerprint) [lshBands]uint64 {
+	var (
+		keys [lshBands]uint64
+		buf  [8]byte
+	)
+
+	for band := range keys {
+		h := fnv.New64a()
+
+		for _, v := range fp.MinHash[band*lshRows : (band+1)*lshRows] {
+			binary.LittleEndian.PutUint64(buf[:], v)
+			_, _ = h.Write(buf[:])
+		}
+
+		keys[band] = h.Sum64()
+	}
+
+	return keys
+}
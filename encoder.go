@@ -0,0 +1,150 @@
+package ttninjs
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrEncoderClosed = errors.New("encoder has been closed")
+
+// EncoderOption configures an Encoder.
+type EncoderOption func(e *Encoder)
+
+// WithJSONArray makes the encoder write the documents as a JSON array instead of
+// as NDJSON. The array is terminated when the encoder is closed.
+func WithJSONArray() EncoderOption {
+	return func(e *Encoder) {
+		e.array = true
+	}
+}
+
+// WithIndent makes the encoder pretty-print the documents. Note that
+// pretty-printed documents span several lines, so the output is a stream of
+// JSON values rather than NDJSON.
+func WithIndent(prefix, indent string) EncoderOption {
+	return func(e *Encoder) {
+		e.prefix = prefix
+		e.indent = indent
+	}
+}
+
+// WithCanonicalKeys makes the encoder write object keys in sorted order. The
+// documents are written in the canonical form produced by Canonicalize.
+func WithCanonicalKeys() EncoderOption {
+	return func(e *Encoder) {
+		e.canonical = true
+	}
+}
+
+// Encoder writes documents to a stream as NDJSON or as a JSON array. The output
+// is deterministic for a given set of options.
+type Encoder struct {
+	w         io.Writer
+	array     bool
+	prefix    string
+	indent    string
+	canonical bool
+
+	count  int
+	opened bool
+	closed bool
+	buf    bytes.Buffer
+}
+
+// NewEncoder creates an encoder that writes to w.
+func NewEncoder(w io.Writer, opts ...EncoderOption) *Encoder {
+	e := Encoder{w: w}
+
+	for _, opt := range opts {
+		opt(&e)
+	}
+
+	return &e
+}
+
+// Encode writes a document to the stream. A document that failed to be written
+// may have been partially written to the stream.
+func (e *Encoder) Encode(doc *Document) error {
+	if e.closed {
+		return ErrEncoderClosed
+	}
+
+	data, err := e.marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal document %q: %w", doc.Uri, err)
+	}
+
+	e.buf.Reset()
+
+	if e.array && e.count > 0 {
+		e.buf.WriteString(",\n")
+	}
+
+	if e.prefix != "" || e.indent != "" {
+		err = stdjson.Indent(&e.buf, data, e.prefix, e.indent)
+		if err != nil {
+			return fmt.Errorf("failed to indent document %q: %w", doc.Uri, err)
+		}
+	} else {
+		e.buf.Write(data)
+	}
+
+	if !e.array {
+		e.buf.WriteByte('\n')
+	}
+
+	if e.array && !e.opened {
+		_, err = io.WriteString(e.w, "[\n")
+		if err != nil {
+			return fmt.Errorf("failed to start array: %w", err)
+		}
+
+		e.opened = true
+	}
+
+	_, err = e.w.Write(e.buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to write document: %w", err)
+	}
+
+	e.count++
+
+	return nil
+}
+
+// Close terminates the JSON array if the encoder writes arrays. It doesn't
+// close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return nil
+	}
+
+	e.closed = true
+
+	if !e.array {
+		return nil
+	}
+
+	end := "\n]\n"
+	if !e.opened {
+		end = "[]\n"
+	}
+
+	_, err := io.WriteString(e.w, end)
+	if err != nil {
+		return fmt.Errorf("failed to terminate array: %w", err)
+	}
+
+	return nil
+}
+
+func (e *Encoder) marshal(doc *Document) ([]byte, error) {
+	if e.canonical {
+		return Canonicalize(doc)
+	}
+
+	return json.Marshal(doc)
+}
@@ -0,0 +1,185 @@
+package ttninjs
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	docs := []Document{
+		{Uri: "a", Headline: "<Rubrik>", Urgency: 4},
+		{Uri: "b"},
+	}
+
+	cases := []struct {
+		name string
+		opts []EncoderOption
+		docs []Document
+		want string
+	}{
+		{
+			name: "ndjson",
+			docs: docs,
+			want: "{\"headline\":\"\\u003cRubrik\\u003e\",\"urgency\":4,\"uri\":\"a\"}\n" +
+				"{\"uri\":\"b\"}\n",
+		},
+		{
+			name: "empty ndjson",
+		},
+		{
+			name: "array",
+			opts: []EncoderOption{WithJSONArray()},
+			docs: docs,
+			want: "[\n{\"headline\":\"\\u003cRubrik\\u003e\",\"urgency\":4,\"uri\":\"a\"},\n" +
+				"{\"uri\":\"b\"}\n]\n",
+		},
+		{
+			name: "empty array",
+			opts: []EncoderOption{WithJSONArray()},
+			want: "[]\n",
+		},
+		{
+			name: "indented array",
+			opts: []EncoderOption{WithJSONArray(), WithIndent("", "  ")},
+			docs: docs,
+			want: "[\n{\n  \"headline\": \"\\u003cRubrik\\u003e\",\n  \"urgency\": 4,\n  \"uri\": \"a\"\n},\n" +
+				"{\n  \"uri\": \"b\"\n}\n]\n",
+		},
+		{
+			name: "canonical",
+			opts: []EncoderOption{WithCanonicalKeys()},
+			docs: docs[:1],
+			want: "{\"headline\":\"<Rubrik>\",\"urgency\":4,\"uri\":\"a\"}\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			enc := NewEncoder(&buf, c.opts...)
+
+			for i := range c.docs {
+				err := enc.Encode(&c.docs[i])
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := enc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if buf.String() != c.want {
+				t.Errorf("got\n%s\nwant\n%s", buf.String(), c.want)
+			}
+
+			// Closing again is a no-op, but encoding fails.
+			err = enc.Close()
+			if err != nil || buf.String() != c.want {
+				t.Errorf("closing again: got %v and %q", err, buf.String())
+			}
+
+			err = enc.Encode(&Document{Uri: "c"})
+			if !errors.Is(err, ErrEncoderClosed) {
+				t.Errorf("got %v, want ErrEncoderClosed", err)
+			}
+		})
+	}
+}
+
+// failingWriter fails the write with the given number, counted from zero.
+type failingWriter struct {
+	buf   strings.Builder
+	fail  int
+	count int
+}
+
+var errWrite = errors.New("write failed")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.count++
+
+	if w.count-1 == w.fail {
+		return 0, errWrite
+	}
+
+	return w.buf.Write(p)
+}
+
+func TestEncoderWriteError(t *testing.T) {
+	// Write 0 starts the array and writes 1 to 3 are the documents.
+	cases := []struct {
+		name string
+		fail int
+		want string
+	}{
+		{"array start", 0, "[\n{\"uri\":\"b\"},\n{\"uri\":\"c\"}\n]\n"},
+		{"first document", 1, "[\n{\"uri\":\"b\"},\n{\"uri\":\"c\"}\n]\n"},
+		{"second document", 2, "[\n{\"uri\":\"a\"},\n{\"uri\":\"c\"}\n]\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := failingWriter{fail: c.fail}
+			enc := NewEncoder(&w, WithJSONArray())
+
+			var failed int
+
+			for _, uri := range []string{"a", "b", "c"} {
+				err := enc.Encode(&Document{Uri: uri})
+				if errors.Is(err, errWrite) {
+					failed++
+				} else if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := enc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if failed != 1 {
+				t.Errorf("got %d failed writes", failed)
+			}
+
+			if w.buf.String() != c.want {
+				t.Errorf("got %q, want %q", w.buf.String(), c.want)
+			}
+		})
+	}
+
+	enc := NewEncoder(&failingWriter{fail: 0}, WithJSONArray())
+
+	err := enc.Close()
+	if !errors.Is(err, errWrite) {
+		t.Errorf("got %v when terminating the array", err)
+	}
+}
+
+func TestEncoderMarshalError(t *testing.T) {
+	var buf bytes.Buffer
+
+	enc := NewEncoder(&buf, WithJSONArray(), WithIndent("", "  "))
+
+	nan := math.NaN()
+
+	err := enc.Encode(&Document{Uri: "a", Signals: Signals{Multipagecount: &nan}})
+	if err == nil {
+		t.Fatal("expected an error for NaN")
+	}
+
+	err = enc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "[]\n" {
+		t.Errorf("got %q", buf.String())
+	}
+}
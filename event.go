@@ -0,0 +1,332 @@
+package ttninjs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EventStatus is the status code of an event, see BodyEvent.Eventstatus. Only
+// the codes documented in the schema are known.
+type EventStatus string
+
+const (
+	EventStatusPlanned   EventStatus = "1"
+	EventStatusCancelled EventStatus = "4"
+)
+
+var eventStatusLabels = map[EventStatus]string{
+	EventStatusPlanned:   "Planerat",
+	EventStatusCancelled: "Inställt",
+}
+
+// Valid returns true if the status is a known status code.
+func (s EventStatus) Valid() bool {
+	_, ok := eventStatusLabels[s]
+
+	return ok
+}
+
+// Label returns the Swedish label of the status, as used in
+// BodyEvent.EventstatusText.
+func (s EventStatus) Label() string {
+	return eventStatusLabels[s]
+}
+
+// EventType is the code of the type of an event, see BodyEvent.Eventtype.
+type EventType string
+
+// EventTypes maps event type codes to their labels, as used in
+// BodyEvent.EventtypeText.
+//
+// The event type codes aren't documented in the schema, so there is no built
+// in set. Load them from TT's event type vocabulary, f.ex. with the concepts
+// of vocab.SchemeTTEventtype.
+type EventTypes map[EventType]string
+
+// Valid returns true if the event type is in the set.
+func (t EventTypes) Valid(code EventType) bool {
+	_, ok := t[code]
+
+	return ok
+}
+
+// Label returns the label of the event type.
+func (t EventTypes) Label(code EventType) string {
+	return t[code]
+}
+
+// Lookup finds the code of an event type by its label, compared case
+// insensitively.
+func (t EventTypes) Lookup(label string) (EventType, bool) {
+	label = strings.TrimSpace(label)
+
+	for code, l := range t {
+		if strings.EqualFold(l, label) {
+			return code, true
+		}
+	}
+
+	return "", false
+}
+
+// Region is the code of a Swedish region (län), see BodyEvent.Region.
+type Region string
+
+var regionLabels = map[Region]string{
+	"01": "Stockholms län",
+	"03": "Uppsala län",
+	"04": "Södermanlands län",
+	"05": "Östergötlands län",
+	"06": "Jönköpings län",
+	"07": "Kronobergs län",
+	"08": "Kalmar län",
+	"09": "Gotlands län",
+	"10": "Blekinge län",
+	"12": "Skåne län",
+	"13": "Hallands län",
+	"14": "Västra Götalands län",
+	"17": "Värmlands län",
+	"18": "Örebro län",
+	"19": "Västmanlands län",
+	"20": "Dalarnas län",
+	"21": "Gävleborgs län",
+	"22": "Västernorrlands län",
+	"23": "Jämtlands län",
+	"24": "Västerbottens län",
+	"25": "Norrbottens län",
+}
+
+// Valid returns true if the region is a known region code.
+func (r Region) Valid() bool {
+	_, ok := regionLabels[r]
+
+	return ok
+}
+
+// Label returns the name of the region.
+func (r Region) Label() string {
+	return regionLabels[r]
+}
+
+// Municipality is the code of a Swedish municipality (kommun), four digits
+// starting with the code of its region. See BodyEvent.Municipality.
+type Municipality string
+
+// Valid returns true if the municipality code is well formed and belongs to a
+// known region.
+func (m Municipality) Valid() bool {
+	if len(m) != 4 || strings.Trim(string(m), "0123456789") != "" {
+		return false
+	}
+
+	return m.Region().Valid()
+}
+
+// Region returns the region of the municipality.
+func (m Municipality) Region() Region {
+	if len(m) < 2 {
+		return ""
+	}
+
+	return Region(m[:2])
+}
+
+// Status returns the status code of the event.
+func (e *BodyEvent) Status() EventStatus {
+	return EventStatus(e.Eventstatus)
+}
+
+// SetStatus sets the status code and label of the event.
+func (e *BodyEvent) SetStatus(s EventStatus) {
+	e.Eventstatus = string(s)
+	e.EventstatusText = s.Label()
+}
+
+// IsCancelled returns true if the event has been cancelled. Events without a
+// status code are checked by their status label.
+func (e *BodyEvent) IsCancelled() bool {
+	return e.hasStatus(EventStatusCancelled, "inställt", "inställd", "avbokat")
+}
+
+// IsPostponed returns true if the event has been postponed. The schema has no
+// status code for postponed events, so only the status label is checked.
+func (e *BodyEvent) IsPostponed() bool {
+	return hasStatusLabel(e.EventstatusText, "uppskjutet", "uppskjuten", "framflyttat")
+}
+
+func (e *BodyEvent) hasStatus(s EventStatus, labels ...string) bool {
+	if e.Eventstatus != "" {
+		return e.Status() == s
+	}
+
+	return hasStatusLabel(e.EventstatusText, labels...)
+}
+
+func hasStatusLabel(label string, labels ...string) bool {
+	text := strings.ToLower(strings.TrimSpace(label))
+
+	for _, l := range labels {
+		if text == l {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Type returns the event type code of the event.
+func (e *BodyEvent) Type() EventType {
+	return EventType(e.Eventtype)
+}
+
+// SetType sets the event type code of the event, and its label from the
+// event types.
+func (e *BodyEvent) SetType(t EventType, types EventTypes) {
+	e.Eventtype = string(t)
+	e.EventtypeText = types.Label(t)
+}
+
+// RegionCode returns the region code of the event.
+func (e *BodyEvent) RegionCode() Region {
+	return Region(e.Region)
+}
+
+// SetRegion sets the region code and name of the event.
+func (e *BodyEvent) SetRegion(r Region) {
+	e.Region = string(r)
+	e.RegionText = r.Label()
+}
+
+// MunicipalityCode returns the municipality code of the event.
+func (e *BodyEvent) MunicipalityCode() Municipality {
+	return Municipality(e.Municipality)
+}
+
+// Tags returns the tags of the event from the comma or semicolon separated
+// Eventtags, with whitespace trimmed and empty tags left out.
+func (e *BodyEvent) Tags() []string {
+	var tags []string
+
+	for _, t := range strings.FieldsFunc(e.Eventtags, func(r rune) bool {
+		return r == ',' || r == ';'
+	}) {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			tags = append(tags, t)
+		}
+	}
+
+	return tags
+}
+
+// SetTags sets Eventtags from a list of tags.
+func (e *BodyEvent) SetTags(tags []string) {
+	e.Eventtags = strings.Join(tags, ", ")
+}
+
+// HasTag returns true if the event has the tag, compared case insensitively.
+func (e *BodyEvent) HasTag(tag string) bool {
+	for _, t := range e.Tags() {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CodeProblem describes an invalid code, or a code and label pair that don't
+// agree.
+type CodeProblem struct {
+	// Field is the code field, f.ex. "eventstatus".
+	Field string
+	Code  string
+	Msg   string
+}
+
+func (p CodeProblem) String() string {
+	return fmt.Sprintf("%s %q: %s", p.Field, p.Code, p.Msg)
+}
+
+// CheckEventCodes validates the status, region and municipality codes of an
+// event, and checks that their labels agree with the codes. Empty codes
+// aren't checked. The event type is checked by CheckEventType.
+func CheckEventCodes(e *BodyEvent) []CodeProblem {
+	var problems []CodeProblem
+
+	add := func(field, code, format string, args ...any) {
+		problems = append(problems, CodeProblem{
+			Field: field,
+			Code:  code,
+			Msg:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	if s := e.Status(); s != "" {
+		switch {
+		case !s.Valid():
+			add("eventstatus", e.Eventstatus, "unknown status")
+		case e.EventstatusText != "" && !strings.EqualFold(e.EventstatusText, s.Label()):
+			add("eventstatus", e.Eventstatus,
+				"label is %q, expected %q", e.EventstatusText, s.Label())
+		}
+	}
+
+	if r := e.RegionCode(); r != "" {
+		switch {
+		case !r.Valid():
+			add("region", e.Region, "unknown region")
+		case e.RegionText != "" && !sameRegionName(e.RegionText, r.Label()):
+			add("region", e.Region,
+				"label is %q, expected %q", e.RegionText, r.Label())
+		}
+	}
+
+	if m := e.MunicipalityCode(); m != "" {
+		switch {
+		case !m.Valid():
+			add("municipality", e.Municipality, "invalid municipality code")
+		case e.Region != "" && m.Region() != e.RegionCode():
+			add("municipality", e.Municipality,
+				"belongs to region %q, not %q", m.Region(), e.Region)
+		}
+	}
+
+	return problems
+}
+
+// sameRegionName compares region names with or without the "län" suffix, and
+// the genitive s of the short form, so that "Skåne" and "Stockholm" match.
+func sameRegionName(name, label string) bool {
+	short := strings.TrimSuffix(label, " län")
+
+	return strings.EqualFold(name, label) ||
+		strings.EqualFold(name, short) ||
+		strings.EqualFold(name, strings.TrimSuffix(short, "s"))
+}
+
+// CheckEventType validates the event type code of an event against a set of
+// event types, and checks that its label agrees with the code. An empty code
+// isn't checked.
+func CheckEventType(e *BodyEvent, types EventTypes) []CodeProblem {
+	t := e.Type()
+
+	switch {
+	case t == "":
+		return nil
+	case !types.Valid(t):
+		return []CodeProblem{{
+			Field: "eventtype",
+			Code:  e.Eventtype,
+			Msg:   "unknown event type",
+		}}
+	case e.EventtypeText != "" && !strings.EqualFold(e.EventtypeText, types.Label(t)):
+		return []CodeProblem{{
+			Field: "eventtype",
+			Code:  e.Eventtype,
+			Msg:   fmt.Sprintf("label is %q, expected %q", e.EventtypeText, types.Label(t)),
+		}}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package ttninjs
+
+import (
+	"slices"
+	"testing"
+)
+
+var testEventTypes = EventTypes{
+	"PK": "Presskonferens",
+	"SP": "Sport",
+}
+
+func TestEventTypes(t *testing.T) {
+	ev := BodyEvent{}
+	ev.SetType("PK", testEventTypes)
+
+	if ev.Eventtype != "PK" || ev.EventtypeText != "Presskonferens" {
+		t.Errorf("unexpected type %q %q", ev.Eventtype, ev.EventtypeText)
+	}
+
+	if code, ok := testEventTypes.Lookup(" sport"); !ok || code != "SP" {
+		t.Errorf("lookup gave %q, %v", code, ok)
+	}
+
+	if _, ok := testEventTypes.Lookup("Konsert"); ok {
+		t.Errorf("unknown label was found")
+	}
+}
+
+func TestCheckEventType(t *testing.T) {
+	cases := []struct {
+		code, text string
+		want       []string
+	}{
+		{"", "", nil},
+		{"PK", "", nil},
+		{"PK", "presskonferens", nil},
+		{"XX", "", []string{`eventtype "XX": unknown event type`}},
+		{"SP", "Presskonferens", []string{`eventtype "SP": label is "Presskonferens", expected "Sport"`}},
+	}
+
+	for _, c := range cases {
+		var got []string
+
+		for _, p := range CheckEventType(&BodyEvent{Eventtype: c.code, EventtypeText: c.text}, testEventTypes) {
+			got = append(got, p.String())
+		}
+
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%q/%q: got %q, expected %q", c.code, c.text, got, c.want)
+		}
+	}
+}
+
+func TestCheckEventCodes(t *testing.T) {
+	ev := BodyEvent{
+		Eventstatus:     "4",
+		EventstatusText: "Planerat",
+		Region:          "12",
+		RegionText:      "Skåne",
+		Municipality:    "1480",
+	}
+
+	var got []string
+
+	for _, p := range CheckEventCodes(&ev) {
+		got = append(got, p.String())
+	}
+
+	want := []string{
+		`eventstatus "4": label is "Planerat", expected "Inställt"`,
+		`municipality "1480": belongs to region "14", not "12"`,
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, expected %q", got, want)
+	}
+
+	if !ev.IsCancelled() {
+		t.Errorf("event should be cancelled")
+	}
+}
+
+func TestEventStatus(t *testing.T) {
+	cases := []struct {
+		ev        BodyEvent
+		cancelled bool
+		postponed bool
+	}{
+		{BodyEvent{Eventstatus: "1", EventstatusText: "Planerat"}, false, false},
+		{BodyEvent{Eventstatus: "4", EventstatusText: "Inställt"}, true, false},
+		{BodyEvent{EventstatusText: "inställd"}, true, false},
+		{BodyEvent{EventstatusText: "Uppskjutet"}, false, true},
+		{BodyEvent{Eventstatus: "1", EventstatusText: "Uppskjutet"}, false, true},
+	}
+
+	for _, c := range cases {
+		if got := c.ev.IsCancelled(); got != c.cancelled {
+			t.Errorf("%+v: IsCancelled is %v", c.ev, got)
+		}
+
+		if got := c.ev.IsPostponed(); got != c.postponed {
+			t.Errorf("%+v: IsPostponed is %v", c.ev, got)
+		}
+	}
+
+	for _, code := range []EventStatus{"2", "3"} {
+		if code.Valid() {
+			t.Errorf("status %q isn't documented and shouldn't be valid", code)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package ttninjs
+
+import (
+	"slices"
+)
+
+// FeatureCollection is a GeoJSON FeatureCollection.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON Feature for a place of a document.
+type Feature struct {
+	Type       string                    `json:"type"`
+	Geometry   *PlaceElemGeometryGeojson `json:"geometry"`
+	Properties FeatureProperties         `json:"properties"`
+}
+
+// FeatureProperties are the properties of a Feature.
+type FeatureProperties struct {
+	URI      string `json:"uri"`
+	Headline string `json:"headline,omitempty"`
+	Type     Type   `json:"type,omitempty"`
+	Urgency  int    `json:"urgency,omitempty"`
+	// Thumbnail is the href of a thumbnail of the document, or of its
+	// first image association.
+	Thumbnail string `json:"thumbnail,omitempty"`
+	// Images are the hrefs of the thumbnails of the image associations of
+	// the document.
+	Images []string `json:"images,omitempty"`
+
+	Rel    string `json:"rel,omitempty"`
+	Code   string `json:"code,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// FeatureOptions control how documents are exported as features.
+type FeatureOptions struct {
+	// RenditionUsage is the preferred usage of thumbnail renditions,
+	// defaults to "Thumbnail".
+	RenditionUsage string
+	// Deduplicate emits only one feature for identical places of a
+	// document. Places are identical if they have the same scheme and code,
+	// or if they have no code, the same name and geometry.
+	Deduplicate bool
+	// ImageTypes are the types of associations that images are taken
+	// from, defaults to picture and graphic.
+	ImageTypes []Type
+	// NoImages leaves out association images. Pictures, graphics and
+	// videos still get their own thumbnail.
+	NoImages bool
+}
+
+// ToFeatureCollection exports the documents as a GeoJSON FeatureCollection
+// with one feature for each place that has a geometry with coordinates.
+func ToFeatureCollection(docs []Document, opts FeatureOptions) FeatureCollection {
+	if opts.RenditionUsage == "" {
+		opts.RenditionUsage = "Thumbnail"
+	}
+
+	if opts.ImageTypes == nil {
+		opts.ImageTypes = []Type{TypePicture, TypeGraphic}
+	}
+
+	fc := FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []Feature{},
+	}
+
+	for i := range docs {
+		fc.Features = append(fc.Features, documentFeatures(&docs[i], opts)...)
+	}
+
+	return fc
+}
+
+func documentFeatures(doc *Document, opts FeatureOptions) []Feature {
+	var (
+		features []Feature
+		seen     = make(map[string]bool)
+		images   []string
+		props    FeatureProperties
+		hasProps bool
+	)
+
+	for _, p := range doc.Place {
+		if p.GeometryGeojson == nil {
+			continue
+		}
+
+		if _, _, ok := p.GeometryGeojson.Bounds(); !ok {
+			continue
+		}
+
+		if opts.Deduplicate {
+			key, ok := placeKey(p)
+			if ok && seen[key] {
+				continue
+			}
+
+			seen[key] = true
+		}
+
+		if !hasProps {
+			hasProps = true
+
+			if !opts.NoImages {
+				images = associationImages(doc, opts)
+			}
+
+			props = FeatureProperties{
+				URI:       doc.Uri,
+				Headline:  doc.Headline,
+				Type:      doc.Type,
+				Urgency:   doc.Urgency,
+				Thumbnail: documentThumbnail(doc, images, opts.RenditionUsage),
+				Images:    images,
+			}
+		}
+
+		fp := props
+		fp.Rel = p.Rel
+		fp.Code = p.Code
+		fp.Name = p.Name
+		fp.Scheme = p.Scheme
+
+		features = append(features, Feature{
+			Type:       "Feature",
+			Geometry:   p.GeometryGeojson,
+			Properties: fp,
+		})
+	}
+
+	return features
+}
+
+// placeKey returns the key used to detect identical places.
+func placeKey(p PlaceElem) (string, bool) {
+	if p.Code != "" {
+		return "code\x00" + p.Scheme + "\x00" + p.Code, true
+	}
+
+	geometry, err := json.Marshal(p.GeometryGeojson)
+	if err != nil {
+		return "", false
+	}
+
+	return "geometry\x00" + p.Name + "\x00" + string(geometry), true
+}
+
+func documentThumbnail(doc *Document, images []string, usage string) string {
+	switch doc.Type {
+	case TypePicture, TypeGraphic, TypeVideo:
+		if href := preferredRendition(doc.Renditions, usage); href != "" {
+			return href
+		}
+	}
+
+	if len(images) > 0 {
+		return images[0]
+	}
+
+	return ""
+}
+
+// associationImages returns the thumbnails of the image associations of the
+// document, ordered by association key.
+func associationImages(doc *Document, opts FeatureOptions) []string {
+	keys := make([]string, 0, len(doc.Associations))
+
+	for key, a := range doc.Associations {
+		if slices.Contains(opts.ImageTypes, a.Type) {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	var images []string
+
+	for _, key := range keys {
+		href := preferredRendition(doc.Associations[key].Renditions, opts.RenditionUsage)
+		if href != "" {
+			images = append(images, href)
+		}
+	}
+
+	return images
+}
@@ -0,0 +1,227 @@
+package ttninjs
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestToFeatureCollection(t *testing.T) {
+	stockholm := NewPoint(18.07, 59.33)
+
+	docs := []Document{
+		{
+			Uri:      "http://tt.se/text/1",
+			Type:     TypeText,
+			Headline: "Rubrik",
+			Urgency:  3,
+			Place: []PlaceElem{
+				{Rel: "mentioned", Scheme: "urn:example:place", Code: "sthlm",
+					Name: "Stockholm", GeometryGeojson: stockholm},
+				{Scheme: "urn:example:place", Code: "sthlm", Name: "Stockholm",
+					GeometryGeojson: stockholm},
+				{Name: "Utan geometri"},
+				{Name: "Tom", GeometryGeojson: &PlaceElemGeometryGeojson{
+					Type: PlaceElemGeometryGeojsonTypePoint,
+				}},
+			},
+			Associations: Associations{
+				"image2": {Type: TypePicture, Renditions: Renditions{
+					"thumbnail": {Href: "2-thumb.jpg", Usage: "Thumbnail"},
+				}},
+				"image1": {Type: TypeGraphic, Renditions: Renditions{
+					"preview":   {Href: "1-preview.jpg", Usage: "Preview"},
+					"thumbnail": {Href: "1-thumb.jpg", Usage: "Thumbnail"},
+				}},
+				"video1": {Type: TypeVideo, Renditions: Renditions{
+					"thumbnail": {Href: "v-thumb.jpg", Usage: "Thumbnail"},
+				}},
+				"image3": {Type: TypePicture},
+			},
+		},
+		{
+			Uri:  "http://tt.se/picture/1",
+			Type: TypePicture,
+			Renditions: Renditions{
+				"thumbnail": {Href: "own-thumb.jpg", Usage: "Thumbnail"},
+			},
+			Place: []PlaceElem{
+				{Name: "Göteborg", GeometryGeojson: NewPoint(11.97, 57.71)},
+				{Name: "Göteborg", GeometryGeojson: NewPoint(11.97, 57.71)},
+				{Name: "Göteborg", GeometryGeojson: NewPoint(11.98, 57.71)},
+			},
+		},
+		{Uri: "http://tt.se/text/2", Type: TypeText},
+	}
+
+	type feature struct {
+		uri, name, thumbnail string
+		images               []string
+	}
+
+	collect := func(fc FeatureCollection) []feature {
+		var res []feature
+
+		for _, f := range fc.Features {
+			if f.Type != "Feature" || f.Geometry == nil {
+				t.Errorf("got invalid feature %+v", f)
+			}
+
+			p := f.Properties
+			res = append(res, feature{p.URI, p.Name, p.Thumbnail, p.Images})
+		}
+
+		return res
+	}
+
+	images := []string{"1-thumb.jpg", "2-thumb.jpg"}
+
+	cases := []struct {
+		name string
+		opts FeatureOptions
+		want []feature
+	}{
+		{
+			name: "defaults",
+			want: []feature{
+				{"http://tt.se/text/1", "Stockholm", "1-thumb.jpg", images},
+				{"http://tt.se/text/1", "Stockholm", "1-thumb.jpg", images},
+				{"http://tt.se/picture/1", "Göteborg", "own-thumb.jpg", nil},
+				{"http://tt.se/picture/1", "Göteborg", "own-thumb.jpg", nil},
+				{"http://tt.se/picture/1", "Göteborg", "own-thumb.jpg", nil},
+			},
+		},
+		{
+			name: "deduplicate",
+			opts: FeatureOptions{Deduplicate: true},
+			want: []feature{
+				{"http://tt.se/text/1", "Stockholm", "1-thumb.jpg", images},
+				{"http://tt.se/picture/1", "Göteborg", "own-thumb.jpg", nil},
+				{"http://tt.se/picture/1", "Göteborg", "own-thumb.jpg", nil},
+			},
+		},
+		{
+			name: "usage and types",
+			opts: FeatureOptions{
+				Deduplicate:    true,
+				RenditionUsage: "Preview",
+				ImageTypes:     []Type{TypeGraphic, TypeVideo},
+			},
+			want: []feature{
+				{"http://tt.se/text/1", "Stockholm", "1-preview.jpg",
+					[]string{"1-preview.jpg", "v-thumb.jpg"}},
+				{"http://tt.se/picture/1", "Göteborg", "own-thumb.jpg", nil},
+				{"http://tt.se/picture/1", "Göteborg", "own-thumb.jpg", nil},
+			},
+		},
+		{
+			name: "no images",
+			opts: FeatureOptions{Deduplicate: true, NoImages: true},
+			want: []feature{
+				{"http://tt.se/text/1", "Stockholm", "", nil},
+				{"http://tt.se/picture/1", "Göteborg", "own-thumb.jpg", nil},
+				{"http://tt.se/picture/1", "Göteborg", "own-thumb.jpg", nil},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := collect(ToFeatureCollection(docs, c.opts))
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %+v, want %+v", got, c.want)
+			}
+		})
+	}
+
+	// The properties of the document are shared, the place properties
+	// are set per feature.
+	fc := ToFeatureCollection(docs[:1], FeatureOptions{})
+
+	want := FeatureProperties{
+		URI:       "http://tt.se/text/1",
+		Headline:  "Rubrik",
+		Type:      TypeText,
+		Urgency:   3,
+		Thumbnail: "1-thumb.jpg",
+		Images:    images,
+		Rel:       "mentioned",
+		Code:      "sthlm",
+		Name:      "Stockholm",
+		Scheme:    "urn:example:place",
+	}
+
+	if !reflect.DeepEqual(fc.Features[0].Properties, want) {
+		t.Errorf("got %+v, want %+v", fc.Features[0].Properties, want)
+	}
+
+	if fc.Features[1].Properties.Rel != "" {
+		t.Errorf("got rel %q for the second place", fc.Features[1].Properties.Rel)
+	}
+}
+
+func TestFeatureCollectionJSON(t *testing.T) {
+	empty := ToFeatureCollection(nil, FeatureOptions{})
+
+	data, err := json.Marshal(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"type":"FeatureCollection","features":[]}` {
+		t.Errorf("got %s", data)
+	}
+
+	fc := ToFeatureCollection([]Document{{
+		Uri:   "a",
+		Place: []PlaceElem{{Name: "Stockholm", GeometryGeojson: NewPoint(18.07, 59.33)}},
+	}}, FeatureOptions{})
+
+	data, err = json.Marshal(fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"type":"FeatureCollection","features":[{"type":"Feature",` +
+		`"geometry":{"type":"Point","coordinates":[18.07,59.33]},` +
+		`"properties":{"uri":"a","name":"Stockholm"}}]}`
+
+	if string(data) != want {
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestPlaceKey(t *testing.T) {
+	point := NewPoint(18.07, 59.33)
+
+	places := []PlaceElem{
+		{Scheme: "s", Code: "a", Name: "A", GeometryGeojson: point},
+		{Scheme: "s", Code: "a", Name: "Other name"},
+		{Scheme: "t", Code: "a", GeometryGeojson: point},
+		{Name: "A", GeometryGeojson: point},
+		{Name: "A", GeometryGeojson: NewPoint(18.07, 59.33)},
+		{Name: "B", GeometryGeojson: point},
+	}
+
+	var keys []string
+
+	for _, p := range places {
+		key, ok := placeKey(p)
+		if !ok {
+			t.Fatalf("no key for %+v", p)
+		}
+
+		keys = append(keys, key)
+	}
+
+	same := [][2]int{{0, 1}, {3, 4}}
+	for _, s := range same {
+		if keys[s[0]] != keys[s[1]] {
+			t.Errorf("expected places %d and %d to be identical", s[0], s[1])
+		}
+	}
+
+	if distinct := slices.Compact(slices.Clone(keys)); len(distinct) != 4 {
+		t.Errorf("got %d distinct keys, want 4", len(distinct))
+	}
+}
@@ -0,0 +1,233 @@
+package filter
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ttab/ttninjs"
+)
+
+type valueKind int
+
+const (
+	kindString valueKind = iota
+	kindInt
+	kindTime
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case kindString:
+		return "string"
+	case kindInt:
+		return "integer"
+	case kindTime:
+		return "time"
+	}
+
+	return "unknown"
+}
+
+// field is an accessor for a value of a document or of an element in one of
+// the document collections. The index i is the index of the element in the
+// collection, and is ignored for document fields. The second return value is
+// false if the value is missing.
+type field struct {
+	kind valueKind
+	str  func(d *ttninjs.Document, i int) (string, bool)
+	num  func(d *ttninjs.Document, i int) (int, bool)
+	tm   func(d *ttninjs.Document, i int) (time.Time, bool)
+	// zeroMissing is set for integer fields where zero means that the
+	// value is missing, comparisons against zero are rejected.
+	zeroMissing bool
+}
+
+type collection struct {
+	length func(d *ttninjs.Document) int
+	fields map[string]field
+}
+
+func strField(fn func(d *ttninjs.Document) string) field {
+	return field{
+		kind: kindString,
+		str: func(d *ttninjs.Document, _ int) (string, bool) {
+			return fn(d), true
+		},
+	}
+}
+
+// intField is a field for integers that are left out of the JSON when they are
+// zero, so a zero value is treated as missing.
+func intField(fn func(d *ttninjs.Document) int) field {
+	return field{
+		kind: kindInt,
+		num: func(d *ttninjs.Document, _ int) (int, bool) {
+			v := fn(d)
+			if v == 0 {
+				return 0, false
+			}
+
+			return v, true
+		},
+		zeroMissing: true,
+	}
+}
+
+// zeroIntField is a field for integers where zero is a value of its own, like
+// webprio 0 for items that need manual attention.
+func zeroIntField(fn func(d *ttninjs.Document) int) field {
+	return field{
+		kind: kindInt,
+		num: func(d *ttninjs.Document, _ int) (int, bool) {
+			return fn(d), true
+		},
+	}
+}
+
+func timeField(fn func(d *ttninjs.Document) *time.Time) field {
+	return field{
+		kind: kindTime,
+		tm: func(d *ttninjs.Document, _ int) (time.Time, bool) {
+			t := fn(d)
+			if t == nil || t.IsZero() {
+				return time.Time{}, false
+			}
+
+			return *t, true
+		},
+	}
+}
+
+func elemStr(fn func(d *ttninjs.Document, i int) string) field {
+	return field{
+		kind: kindString,
+		str: func(d *ttninjs.Document, i int) (string, bool) {
+			return fn(d, i), true
+		},
+	}
+}
+
+func elemInt(fn func(d *ttninjs.Document, i int) *int) field {
+	return field{
+		kind: kindInt,
+		num: func(d *ttninjs.Document, i int) (int, bool) {
+			v := fn(d, i)
+			if v == nil {
+				return 0, false
+			}
+
+			return *v, true
+		},
+	}
+}
+
+var documentFields = map[string]field{
+	"uri":       strField(func(d *ttninjs.Document) string { return d.Uri }),
+	"type":      strField(func(d *ttninjs.Document) string { return string(d.Type) }),
+	"pubstatus": strField(func(d *ttninjs.Document) string { return string(d.Pubstatus) }),
+	"sector": {
+		kind: kindString,
+		str: func(d *ttninjs.Document, _ int) (string, bool) {
+			if d.Sector == nil {
+				return "", false
+			}
+
+			return string(*d.Sector), true
+		},
+	},
+	"profile": {
+		kind: kindString,
+		str: func(d *ttninjs.Document, _ int) (string, bool) {
+			if d.Profile == nil {
+				return "", false
+			}
+
+			return string(*d.Profile), true
+		},
+	},
+	"headline":  strField(func(d *ttninjs.Document) string { return d.Headline }),
+	"slugline":  strField(func(d *ttninjs.Document) string { return d.Slugline }),
+	"slug":      strField(func(d *ttninjs.Document) string { return d.Slug }),
+	"title":     strField(func(d *ttninjs.Document) string { return d.Title }),
+	"byline":    strField(func(d *ttninjs.Document) string { return d.Byline }),
+	"located":   strField(func(d *ttninjs.Document) string { return d.Located }),
+	"language":  strField(func(d *ttninjs.Document) string { return d.Language }),
+	"source":    strField(func(d *ttninjs.Document) string { return d.Source }),
+	"job":       strField(func(d *ttninjs.Document) string { return d.Job }),
+	"mimetype":  strField(func(d *ttninjs.Document) string { return d.Mimetype }),
+	"urgency":   intField(func(d *ttninjs.Document) int { return d.Urgency }),
+	"webprio":   zeroIntField(func(d *ttninjs.Document) int { return d.Webprio }),
+	"week":      intField(func(d *ttninjs.Document) int { return d.Week }),
+	"wordcount": intField(func(d *ttninjs.Document) int { return d.Wordcount }),
+	"newsvalue": elemInt(func(d *ttninjs.Document, _ int) *int { return d.Newsvalue }),
+	"embargoed": timeField(func(d *ttninjs.Document) *time.Time { return d.Embargoed }),
+	"expires":   timeField(func(d *ttninjs.Document) *time.Time { return d.Expires }),
+	"datetime":  timeField(func(d *ttninjs.Document) *time.Time { return d.Datetime }),
+	"enddatetime": timeField(func(d *ttninjs.Document) *time.Time {
+		return d.Enddatetime
+	}),
+	"firstcreated": timeField(func(d *ttninjs.Document) *time.Time {
+		return d.Firstcreated
+	}),
+	"contentcreated": timeField(func(d *ttninjs.Document) *time.Time {
+		return d.Contentcreated
+	}),
+	"versioncreated": timeField(func(d *ttninjs.Document) *time.Time {
+		return &d.Versioncreated
+	}),
+	"versionstored": timeField(func(d *ttninjs.Document) *time.Time {
+		return d.Versionstored
+	}),
+}
+
+var collections = map[string]collection{
+	"subject": {
+		length: func(d *ttninjs.Document) int { return len(d.Subject) },
+		fields: map[string]field{
+			"code":       elemStr(func(d *ttninjs.Document, i int) string { return d.Subject[i].Code }),
+			"scheme":     elemStr(func(d *ttninjs.Document, i int) string { return d.Subject[i].Scheme }),
+			"name":       elemStr(func(d *ttninjs.Document, i int) string { return d.Subject[i].Name }),
+			"rel":        elemStr(func(d *ttninjs.Document, i int) string { return d.Subject[i].Rel }),
+			"creator":    elemStr(func(d *ttninjs.Document, i int) string { return d.Subject[i].Creator }),
+			"relevance":  elemInt(func(d *ttninjs.Document, i int) *int { return d.Subject[i].Relevance }),
+			"confidence": elemInt(func(d *ttninjs.Document, i int) *int { return d.Subject[i].Confidence }),
+		},
+	},
+	"place": {
+		length: func(d *ttninjs.Document) int { return len(d.Place) },
+		fields: map[string]field{
+			"code":   elemStr(func(d *ttninjs.Document, i int) string { return d.Place[i].Code }),
+			"scheme": elemStr(func(d *ttninjs.Document, i int) string { return d.Place[i].Scheme }),
+			"name":   elemStr(func(d *ttninjs.Document, i int) string { return d.Place[i].Name }),
+			"rel":    elemStr(func(d *ttninjs.Document, i int) string { return d.Place[i].Rel }),
+		},
+	},
+	"product": {
+		length: func(d *ttninjs.Document) int { return len(d.Product) },
+		fields: map[string]field{
+			"code":   elemStr(func(d *ttninjs.Document, i int) string { return d.Product[i].Code }),
+			"scheme": elemStr(func(d *ttninjs.Document, i int) string { return d.Product[i].Scheme }),
+			"name":   elemStr(func(d *ttninjs.Document, i int) string { return d.Product[i].Name }),
+		},
+	},
+	"genre": {
+		length: func(d *ttninjs.Document) int { return len(d.Genre) },
+		fields: map[string]field{
+			"code":   elemStr(func(d *ttninjs.Document, i int) string { return d.Genre[i].Code }),
+			"scheme": elemStr(func(d *ttninjs.Document, i int) string { return d.Genre[i].Scheme }),
+			"name":   elemStr(func(d *ttninjs.Document, i int) string { return d.Genre[i].Name }),
+		},
+	},
+}
+
+func fieldNames(fields map[string]field) []string {
+	names := make([]string, 0, len(fields))
+
+	for name := range fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,126 @@
+// Package filter implements a small expression language for matching
+// documents, intended for subscriptions like:
+//
+//	sector in (SPT, EKO) and urgency <= 3 and subject.code = '15000000' and not type = picture
+//
+// Expressions are built from comparisons combined with "and", "or", "not" and
+// parentheses. A comparison has a field on the left hand side and a literal on
+// the right hand side. The operators are =, !=, <, <=, >, >= and "in" for a
+// list of values. String fields also support ~ and !~ for regular expression
+// matching.
+//
+// String values can be quoted with single or double quotes, or be written as
+// bare words. Times are written as quoted RFC 3339 timestamps or dates.
+//
+// Collections (subject, place, product and genre) are matched by either
+// qualifying a field with the collection name, like subject.code = '15000000',
+// which matches if any element matches, or with the quantifiers "any" and "all"
+// that match several fields against the same element:
+//
+//	any subject (scheme = 'http://tt.se/spec/keyword/1.0/' and relevance >= 50)
+//
+// Comparisons against missing values, f.ex. a newsvalue on a document that
+// has none, are always false. Urgency, week and wordcount are left out of
+// documents when they are zero, so a zero value counts as missing and can't be
+// compared with. A webprio of 0 means that the document needs manual
+// attention, so "webprio = 0" matches documents without a webprio.
+package filter
+
+import (
+	"fmt"
+
+	"github.com/ttab/ttninjs"
+)
+
+// Predicate is a compiled filter expression.
+type Predicate func(doc *ttninjs.Document) bool
+
+// Error is a compilation error.
+type Error struct {
+	// Pos is the byte offset in the expression where the error was found.
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("filter: position %d: %s", e.Pos, e.Msg)
+}
+
+func errorf(pos int, format string, a ...any) *Error {
+	return &Error{
+		Pos: pos,
+		Msg: fmt.Sprintf(format, a...),
+	}
+}
+
+// Compile compiles a filter expression to a predicate. Compilation errors are
+// returned as *Error.
+func Compile(expr string) (Predicate, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{tokens: tokens}
+
+	fn, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorf(t.pos, "unexpected %s", t.describe())
+	}
+
+	return func(doc *ttninjs.Document) bool {
+		return fn(doc, 0)
+	}, nil
+}
+
+// MustCompile is like Compile but panics if the expression cannot be compiled.
+func MustCompile(expr string) Predicate {
+	fn, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+
+	return fn
+}
+
+// Set is a collection of named filters that can be matched against documents
+// in one go.
+type Set struct {
+	ids        []string
+	predicates []Predicate
+}
+
+// Add compiles the expression and adds it to the set.
+func (s *Set) Add(id string, expr string) error {
+	fn, err := Compile(expr)
+	if err != nil {
+		return fmt.Errorf("compile %q: %w", id, err)
+	}
+
+	s.ids = append(s.ids, id)
+	s.predicates = append(s.predicates, fn)
+
+	return nil
+}
+
+// Len returns the number of filters in the set.
+func (s *Set) Len() int {
+	return len(s.ids)
+}
+
+// Match returns the IDs of the filters that match the document.
+func (s *Set) Match(doc *ttninjs.Document) []string {
+	var ids []string
+
+	for i, fn := range s.predicates {
+		if fn(doc) {
+			ids = append(ids, s.ids[i])
+		}
+	}
+
+	return ids
+}
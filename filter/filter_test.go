@@ -0,0 +1,233 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ttab/ttninjs"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func testDocument() *ttninjs.Document {
+	sector := ttninjs.Sector("SPT")
+	versioncreated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	return &ttninjs.Document{
+		Uri:            "http://tt.se/text/1",
+		Type:           ttninjs.TypeText,
+		Sector:         &sector,
+		Headline:       "Malmö FF vann derbyt",
+		Urgency:        3,
+		Newsvalue:      intPtr(4),
+		Versioncreated: versioncreated,
+		Subject: []ttninjs.SubjectElem{
+			{
+				Code:      "15000000",
+				Scheme:    "http://cv.iptc.org/newscodes/mediatopic/",
+				Relevance: intPtr(80),
+			},
+			{
+				Code:      "fotboll",
+				Scheme:    "http://tt.se/spec/keyword/1.0/",
+				Relevance: intPtr(40),
+			},
+		},
+		Place: []ttninjs.PlaceElem{
+			{Name: "Malmö", Rel: "ort"},
+		},
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{"", 0, "expected a comparison, got end of expression"},
+		{"urgency", 7, "expected an operator after 'urgency', got end of expression"},
+		{"urgency <= ", 11, "expected an integer, got end of expression"},
+		{"urgency <= high", 11, "expected an integer, got identifier 'high'"},
+		{"urgency ~ 3", 8, "operator '~' cannot be used with integer fields"},
+		{"headline = 'unterminated", 11, "unterminated string"},
+		{"headline ~ '('", 11, "invalid regular expression"},
+		{"colour = red", 0, "unknown field 'colour'"},
+		{"type = text and", 15, "expected a comparison, got end of expression"},
+		{"type = text)", 11, "unexpected ')'"},
+		{"(type = text", 12, "expected ')', got end of expression"},
+		{"sector in (SPT, EKO", 19, "expected ',' or ')', got end of expression"},
+		{"urgency = -", 10, "expected digits after '-'"},
+		{"type = and", 7, "expected a value, got keyword 'and'"},
+		{"versioncreated > 2024", 17, "expected a quoted time, got number '2024'"},
+		{"versioncreated > 'yesterday'", 17, "invalid time 'yesterday'"},
+		{"any colour (code = 1)", 4, "unknown collection 'colour'"},
+		{"any subject (any place (code = x))", 13, "any cannot be used inside a quantifier over subject"},
+		{"headline = x $", 13, "unexpected character '$'"},
+		{"wordcount = 0", 12, "cannot compare with 0"},
+		{"urgency in (1, 0)", 15, "cannot compare with 0"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			_, err := Compile(c.expr)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+
+			var fe *Error
+			if !errors.As(err, &fe) {
+				t.Fatalf("expected a *filter.Error, got %T: %v", err, err)
+			}
+
+			if fe.Pos != c.pos {
+				t.Errorf("error at position %d, expected %d: %v", fe.Pos, c.pos, err)
+			}
+
+			if !strings.Contains(fe.Msg, c.msg) {
+				t.Errorf("error %q doesn't contain %q", fe.Msg, c.msg)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	doc := testDocument()
+
+	picture := &ttninjs.Document{
+		Uri:  "http://tt.se/picture/1",
+		Type: ttninjs.TypePicture,
+	}
+
+	cases := []struct {
+		expr string
+		doc  *ttninjs.Document
+		want bool
+	}{
+		{"type = text", doc, true},
+		{"type = picture", doc, false},
+		{"type != picture", doc, true},
+		{"type = text AND urgency <= 3", doc, true},
+		{"sector in (SPT, EKO) and urgency <= 3", doc, true},
+		{"sector not in (SPT, EKO)", doc, false},
+		{"urgency < 3", doc, false},
+		{"urgency in (1, 2, 3)", doc, true},
+		{"newsvalue >= 4", doc, true},
+		{"headline ~ '^Malmö'", doc, true},
+		{"headline !~ 'AIK'", doc, true},
+		{`headline = "Malmö FF vann derbyt"`, doc, true},
+		{"versioncreated >= '2024-05-01'", doc, true},
+		{"versioncreated > '2024-05-01T12:00:00Z'", doc, false},
+		{"subject.code = '15000000'", doc, true},
+		{"subject.code = '16000000'", doc, false},
+		{"place.name = Malmö", doc, true},
+		{"not (type = text or type = picture)", doc, false},
+		{"type = picture or urgency = 3 and newsvalue = 4", doc, true},
+		{"any subject (scheme = 'http://tt.se/spec/keyword/1.0/' and relevance >= 50)", doc, false},
+		{"any subject (scheme = 'http://tt.se/spec/keyword/1.0/' and relevance >= 40)", doc, true},
+		{"all subject (relevance >= 40)", doc, true},
+		{"all subject (relevance >= 50)", doc, false},
+		{"all genre (code = x)", doc, true},
+		{"any genre (code = x)", doc, false},
+
+		// Missing values never match, not even negated comparisons.
+		{"sector in (SPT, EKO)", picture, false},
+		{"sector not in (SPT, EKO)", picture, false},
+		{"urgency <= 3", picture, false},
+		{"urgency != 3", picture, false},
+		{"wordcount > 1", picture, false},
+
+		// A webprio of 0 is a value, not missing.
+		{"webprio = 0", picture, true},
+		{"webprio = 0", doc, true},
+		{"webprio in (0, 1)", doc, true},
+		{"webprio >= 1", doc, false},
+		{"webprio = 0", &ttninjs.Document{Uri: "x", Type: ttninjs.TypeText, Webprio: 2}, false},
+		{"newsvalue <= 6", picture, false},
+		{"embargoed < '2030-01-01'", picture, false},
+		{"sector in (SPT, EKO) and urgency <= 3 and subject.code = '15000000' and not type = picture", doc, true},
+		{"sector in (SPT, EKO) and urgency <= 3 and subject.code = '15000000' and not type = picture", picture, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			fn, err := Compile(c.expr)
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+
+			if got := fn(c.doc); got != c.want {
+				t.Errorf("got %v, expected %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSetMatch(t *testing.T) {
+	var s Set
+
+	for id, expr := range map[string]string{
+		"sport":   "sector = SPT",
+		"economy": "sector = EKO",
+		"urgent":  "urgency <= 2",
+		"malmo":   "place.name = Malmö",
+	} {
+		err := s.Add(id, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := s.Add("broken", "urgency <")
+	if err == nil || !strings.Contains(err.Error(), `"broken"`) {
+		t.Errorf("expected a compile error naming the filter, got %v", err)
+	}
+
+	if s.Len() != 4 {
+		t.Errorf("set has %d filters, expected 4", s.Len())
+	}
+
+	got := s.Match(testDocument())
+
+	want := map[string]bool{"sport": true, "malmo": true}
+	if len(got) != len(want) {
+		t.Fatalf("matched %v, expected sport and malmo", got)
+	}
+
+	for _, id := range got {
+		if !want[id] {
+			t.Errorf("unexpected match %q", id)
+		}
+	}
+}
+
+func BenchmarkSetMatch(b *testing.B) {
+	var s Set
+
+	sectors := []string{"SPT", "EKO", "INR", "UTR", "KLT", "NOJ"}
+
+	for i := 0; i < 5000; i++ {
+		expr := fmt.Sprintf(
+			"sector in (%s, %s) and urgency <= %d and any subject (code = '%d' and relevance >= %d) and not type = picture",
+			sectors[i%len(sectors)], sectors[(i+1)%len(sectors)],
+			i%8+1, 15000000+i%20*1000, i%100)
+
+		err := s.Add(fmt.Sprint(i), expr)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	doc := testDocument()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.Match(doc)
+	}
+}
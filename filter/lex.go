@@ -0,0 +1,197 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokEOF:
+		return "end of expression"
+	case tokIdent:
+		return "identifier"
+	case tokString:
+		return "string"
+	case tokNumber:
+		return "number"
+	case tokOp:
+		return "operator"
+	case tokLParen:
+		return "'('"
+	case tokRParen:
+		return "')'"
+	case tokComma:
+		return "','"
+	}
+
+	return "unknown token"
+}
+
+type token struct {
+	kind tokenKind
+	// text is the literal text of the token, for strings the unquoted value.
+	text string
+	pos  int
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF, tokLParen, tokRParen, tokComma:
+		return t.kind.String()
+	case tokString:
+		return "string " + quote(t.text)
+	}
+
+	return t.kind.String() + " " + quote(t.text)
+}
+
+// keyword returns the lowercased identifier if the token is an identifier.
+func (t token) keyword() string {
+	if t.kind != tokIdent {
+		return ""
+	}
+
+	return strings.ToLower(t.text)
+}
+
+func quote(s string) string {
+	return "'" + s + "'"
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+
+	pos := 0
+
+	for pos < len(src) {
+		r, size := utf8.DecodeRuneInString(src[pos:])
+
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: pos})
+			pos++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: pos})
+			pos++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ",", pos: pos})
+			pos++
+		case r == '\'' || r == '"':
+			text, end, err := lexString(src, pos)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, token{kind: tokString, text: text, pos: pos})
+			pos = end
+		case r == '-' || (r >= '0' && r <= '9'):
+			end := pos + 1
+			for end < len(src) && src[end] >= '0' && src[end] <= '9' {
+				end++
+			}
+
+			if src[pos:end] == "-" {
+				return nil, errorf(pos, "expected digits after '-'")
+			}
+
+			tokens = append(tokens, token{kind: tokNumber, text: src[pos:end], pos: pos})
+			pos = end
+		case isIdentRune(r):
+			end := pos
+			for end < len(src) {
+				r, size := utf8.DecodeRuneInString(src[end:])
+				if !isIdentRune(r) && !(r >= '0' && r <= '9') && r != '.' {
+					break
+				}
+
+				end += size
+			}
+
+			tokens = append(tokens, token{kind: tokIdent, text: src[pos:end], pos: pos})
+			pos = end
+		default:
+			op := lexOperator(src[pos:])
+			if op == "" {
+				return nil, errorf(pos, "unexpected character %q", r)
+			}
+
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: pos})
+			pos += len(op)
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(src)})
+
+	return tokens, nil
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+var operators = []string{"!=", "<=", ">=", "!~", "=", "<", ">", "~"}
+
+func lexOperator(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+
+	return ""
+}
+
+func lexString(src string, start int) (string, int, error) {
+	quoteChar := src[start]
+
+	var b strings.Builder
+
+	pos := start + 1
+
+	for pos < len(src) {
+		c := src[pos]
+
+		switch c {
+		case quoteChar:
+			return b.String(), pos + 1, nil
+		case '\\':
+			if pos+1 >= len(src) {
+				return "", 0, errorf(pos, "unterminated escape sequence")
+			}
+
+			// Only the quote character and backslash are escaped,
+			// other sequences are kept as is so that regular
+			// expressions like '\d+' can be written without
+			// double escaping.
+			next := src[pos+1]
+			if next != quoteChar && next != '\\' {
+				b.WriteByte('\\')
+			}
+
+			b.WriteByte(next)
+			pos += 2
+		default:
+			b.WriteByte(c)
+			pos++
+		}
+	}
+
+	return "", 0, errorf(start, "unterminated string")
+}
@@ -0,0 +1,548 @@
+package filter
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ttab/ttninjs"
+)
+
+// pred is the internal predicate type, i is the index of the current element
+// when evaluating inside a collection quantifier.
+type pred func(d *ttninjs.Document, i int) bool
+
+type parser struct {
+	tokens []token
+	pos    int
+
+	// scope is the collection that fields are resolved against inside a
+	// quantifier, nil at the document level.
+	scope     *collection
+	scopeName string
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+
+	if t.kind != tokEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) expect(kind tokenKind) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, errorf(t.pos, "expected %s, got %s", kind, t.describe())
+	}
+
+	return t, nil
+}
+
+func (p *parser) parseExpression() (pred, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().keyword() == "or" {
+		p.next()
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		l := left
+		left = func(d *ttninjs.Document, i int) bool {
+			return l(d, i) || right(d, i)
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (pred, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().keyword() == "and" {
+		p.next()
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		l := left
+		left = func(d *ttninjs.Document, i int) bool {
+			return l(d, i) && right(d, i)
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (pred, error) {
+	if p.peek().keyword() != "not" {
+		return p.parsePrimary()
+	}
+
+	p.next()
+
+	inner, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(d *ttninjs.Document, i int) bool {
+		return !inner(d, i)
+	}, nil
+}
+
+func (p *parser) parsePrimary() (pred, error) {
+	t := p.peek()
+
+	switch {
+	case t.kind == tokLParen:
+		p.next()
+
+		inner, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+
+		return inner, nil
+	case t.keyword() == "any" || t.keyword() == "all":
+		return p.parseQuantifier()
+	case t.kind == tokIdent:
+		return p.parseComparison()
+	}
+
+	return nil, errorf(t.pos, "expected a comparison, got %s", t.describe())
+}
+
+func (p *parser) parseQuantifier() (pred, error) {
+	q := p.next()
+
+	if p.scope != nil {
+		return nil, errorf(q.pos, "%s cannot be used inside a quantifier over %s",
+			q.keyword(), p.scopeName)
+	}
+
+	name, err := p.expect(tokIdent)
+	if err != nil {
+		return nil, err
+	}
+
+	coll, ok := collections[name.text]
+	if !ok {
+		return nil, errorf(name.pos, "unknown collection %s, expected one of %s",
+			quote(name.text), strings.Join(collectionNames(), ", "))
+	}
+
+	if _, err := p.expect(tokLParen); err != nil {
+		return nil, err
+	}
+
+	p.scope = &coll
+	p.scopeName = name.text
+
+	inner, err := p.parseExpression()
+
+	p.scope = nil
+	p.scopeName = ""
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(tokRParen); err != nil {
+		return nil, err
+	}
+
+	if q.keyword() == "all" {
+		return allElements(coll, inner), nil
+	}
+
+	return anyElement(coll, inner), nil
+}
+
+func anyElement(coll collection, fn pred) pred {
+	return func(d *ttninjs.Document, _ int) bool {
+		n := coll.length(d)
+
+		for i := 0; i < n; i++ {
+			if fn(d, i) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// allElements matches if all elements match. Like "all" in most query
+// languages it is true for empty collections.
+func allElements(coll collection, fn pred) pred {
+	return func(d *ttninjs.Document, _ int) bool {
+		n := coll.length(d)
+
+		for i := 0; i < n; i++ {
+			if !fn(d, i) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+func collectionNames() []string {
+	names := make([]string, 0, len(collections))
+
+	for name := range collections {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// resolveField resolves a field name, the returned collection is non-nil if
+// the field is a qualified collection field that has to be wrapped in an
+// implicit "any".
+func (p *parser) resolveField(t token) (field, *collection, error) {
+	if p.scope != nil {
+		f, ok := p.scope.fields[t.text]
+		if !ok {
+			return field{}, nil, errorf(t.pos, "unknown %s field %s, expected one of %s",
+				p.scopeName, quote(t.text), strings.Join(fieldNames(p.scope.fields), ", "))
+		}
+
+		return f, nil, nil
+	}
+
+	collName, fieldName, qualified := strings.Cut(t.text, ".")
+	if !qualified {
+		f, ok := documentFields[t.text]
+		if !ok {
+			return field{}, nil, errorf(t.pos, "unknown field %s", quote(t.text))
+		}
+
+		return f, nil, nil
+	}
+
+	coll, ok := collections[collName]
+	if !ok {
+		return field{}, nil, errorf(t.pos, "unknown collection %s, expected one of %s",
+			quote(collName), strings.Join(collectionNames(), ", "))
+	}
+
+	f, ok := coll.fields[fieldName]
+	if !ok {
+		return field{}, nil, errorf(t.pos, "unknown %s field %s, expected one of %s",
+			collName, quote(fieldName), strings.Join(fieldNames(coll.fields), ", "))
+	}
+
+	return f, &coll, nil
+}
+
+func (p *parser) parseComparison() (pred, error) {
+	name := p.next()
+
+	f, coll, err := p.resolveField(name)
+	if err != nil {
+		return nil, err
+	}
+
+	opTok := p.next()
+
+	var op string
+
+	switch {
+	case opTok.kind == tokOp:
+		op = opTok.text
+	case opTok.keyword() == "in":
+		op = "in"
+	case opTok.keyword() == "not" && p.peek().keyword() == "in":
+		p.next()
+
+		op = "not in"
+	default:
+		return nil, errorf(opTok.pos, "expected an operator after %s, got %s",
+			quote(name.text), opTok.describe())
+	}
+
+	var fn pred
+
+	switch f.kind {
+	case kindString:
+		fn, err = p.stringComparison(f, op, opTok)
+	case kindInt:
+		fn, err = p.intComparison(f, op, opTok)
+	case kindTime:
+		fn, err = p.timeComparison(f, op, opTok)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if coll != nil {
+		return anyElement(*coll, fn), nil
+	}
+
+	return fn, nil
+}
+
+func unsupportedOperator(f field, opTok token) error {
+	return errorf(opTok.pos, "operator %s cannot be used with %s fields",
+		quote(opTok.text), f.kind)
+}
+
+// parseList parses a parenthesised, comma separated list of values.
+func (p *parser) parseList(value func() error) error {
+	if _, err := p.expect(tokLParen); err != nil {
+		return err
+	}
+
+	for {
+		if err := value(); err != nil {
+			return err
+		}
+
+		t := p.next()
+
+		switch t.kind {
+		case tokComma:
+			continue
+		case tokRParen:
+			return nil
+		}
+
+		return errorf(t.pos, "expected ',' or ')', got %s", t.describe())
+	}
+}
+
+func (p *parser) stringValue() (token, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokString, tokNumber:
+		return t, nil
+	case tokIdent:
+		switch t.keyword() {
+		case "and", "or", "not", "in", "any", "all":
+			return t, errorf(t.pos, "expected a value, got keyword %s", quote(t.text))
+		}
+
+		return t, nil
+	}
+
+	return t, errorf(t.pos, "expected a string value, got %s", t.describe())
+}
+
+func (p *parser) stringComparison(f field, op string, opTok token) (pred, error) {
+	switch op {
+	case "in", "not in":
+		set := make(map[string]struct{})
+
+		err := p.parseList(func() error {
+			t, err := p.stringValue()
+			if err != nil {
+				return err
+			}
+
+			set[t.text] = struct{}{}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		negate := op == "not in"
+
+		return func(d *ttninjs.Document, i int) bool {
+			v, ok := f.str(d, i)
+			if !ok {
+				return false
+			}
+
+			_, found := set[v]
+
+			return found != negate
+		}, nil
+	case "=", "!=":
+		t, err := p.stringValue()
+		if err != nil {
+			return nil, err
+		}
+
+		want := t.text
+		negate := op == "!="
+
+		return func(d *ttninjs.Document, i int) bool {
+			v, ok := f.str(d, i)
+
+			return ok && (v == want) != negate
+		}, nil
+	case "~", "!~":
+		t, err := p.stringValue()
+		if err != nil {
+			return nil, err
+		}
+
+		re, err := regexp.Compile(t.text)
+		if err != nil {
+			return nil, errorf(t.pos, "invalid regular expression: %v", err)
+		}
+
+		negate := op == "!~"
+
+		return func(d *ttninjs.Document, i int) bool {
+			v, ok := f.str(d, i)
+
+			return ok && re.MatchString(v) != negate
+		}, nil
+	}
+
+	return nil, unsupportedOperator(f, opTok)
+}
+
+func (p *parser) intValue(f field) (int, error) {
+	t := p.next()
+	if t.kind != tokNumber {
+		return 0, errorf(t.pos, "expected an integer, got %s", t.describe())
+	}
+
+	n, err := strconv.Atoi(t.text)
+	if err != nil {
+		return 0, errorf(t.pos, "invalid integer %s", quote(t.text))
+	}
+
+	if n == 0 && f.zeroMissing {
+		return 0, errorf(t.pos, "cannot compare with 0, which means that the value is missing")
+	}
+
+	return n, nil
+}
+
+func (p *parser) intComparison(f field, op string, opTok token) (pred, error) {
+	if op == "in" || op == "not in" {
+		set := make(map[int]struct{})
+
+		err := p.parseList(func() error {
+			n, err := p.intValue(f)
+			if err != nil {
+				return err
+			}
+
+			set[n] = struct{}{}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		negate := op == "not in"
+
+		return func(d *ttninjs.Document, i int) bool {
+			v, ok := f.num(d, i)
+			if !ok {
+				return false
+			}
+
+			_, found := set[v]
+
+			return found != negate
+		}, nil
+	}
+
+	cmp, ok := orderedComparison(op)
+	if !ok {
+		return nil, unsupportedOperator(f, opTok)
+	}
+
+	want, err := p.intValue(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(d *ttninjs.Document, i int) bool {
+		v, ok := f.num(d, i)
+
+		return ok && cmp(v, want)
+	}, nil
+}
+
+func (p *parser) timeValue() (time.Time, error) {
+	t := p.next()
+	if t.kind != tokString {
+		return time.Time{}, errorf(t.pos, "expected a quoted time, got %s", t.describe())
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		v, err := time.Parse(layout, t.text)
+		if err == nil {
+			return v, nil
+		}
+	}
+
+	return time.Time{}, errorf(t.pos,
+		"invalid time %s, expected an RFC 3339 timestamp or a date", quote(t.text))
+}
+
+func (p *parser) timeComparison(f field, op string, opTok token) (pred, error) {
+	cmp, ok := orderedComparison(op)
+	if !ok {
+		return nil, unsupportedOperator(f, opTok)
+	}
+
+	want, err := p.timeValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(d *ttninjs.Document, i int) bool {
+		v, ok := f.tm(d, i)
+
+		return ok && cmp(v.Compare(want), 0)
+	}, nil
+}
+
+func orderedComparison(op string) (func(a, b int) bool, bool) {
+	switch op {
+	case "=":
+		return func(a, b int) bool { return a == b }, true
+	case "!=":
+		return func(a, b int) bool { return a != b }, true
+	case "<":
+		return func(a, b int) bool { return a < b }, true
+	case "<=":
+		return func(a, b int) bool { return a <= b }, true
+	case ">":
+		return func(a, b int) bool { return a > b }, true
+	case ">=":
+		return func(a, b int) bool { return a >= b }, true
+	}
+
+	return nil, false
+}
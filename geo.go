@@ -0,0 +1,298 @@
+package ttninjs
+
+import (
+	"math"
+	"strings"
+)
+
+// PlaceLevel is the type of area that a place refers to, from countries to
+// cities. It's derived from PlaceElem.Rel.
+type PlaceLevel int
+
+const (
+	// PlaceLevelUnknown is used for places without a rel, or with a rel
+	// that isn't a known type of area.
+	PlaceLevelUnknown PlaceLevel = iota
+	PlaceLevelCountry
+	PlaceLevelState
+	PlaceLevelRegion
+	PlaceLevelMunicipality
+	PlaceLevelLocality
+)
+
+var placeLevels = map[string]PlaceLevel{
+	"land":     PlaceLevelCountry,
+	"delstat":  PlaceLevelState,
+	"län":      PlaceLevelRegion,
+	"landskap": PlaceLevelRegion,
+	"kommun":   PlaceLevelMunicipality,
+	"ort":      PlaceLevelLocality,
+	"city":     PlaceLevelLocality,
+	"capital":  PlaceLevelLocality,
+}
+
+// Level returns the type of area that the place refers to.
+func (p PlaceElem) Level() PlaceLevel {
+	return placeLevels[strings.ToLower(strings.TrimSpace(p.Rel))]
+}
+
+// Centroid returns the centroid of the places of the document that have a
+// geometry. Only the most specific places are used, so that a document about a
+// city in a country is centered on the city. Places with an unknown level are
+// treated as localities.
+func (j Document) Centroid() (Position, bool) {
+	var (
+		best     PlaceLevel
+		lon, lat float64
+		n        int
+	)
+
+	for _, p := range j.Place {
+		if p.GeometryGeojson == nil {
+			continue
+		}
+
+		c, ok := p.GeometryGeojson.Centroid()
+		if !ok {
+			continue
+		}
+
+		level := p.Level()
+		if level == PlaceLevelUnknown {
+			level = PlaceLevelLocality
+		}
+
+		switch {
+		case level < best:
+			continue
+		case level > best:
+			best, lon, lat, n = level, 0, 0, 0
+		}
+
+		lon += c.Lon()
+		lat += c.Lat()
+		n++
+	}
+
+	if n == 0 {
+		return nil, false
+	}
+
+	return Position{lon / float64(n), lat / float64(n)}, true
+}
+
+// Centroid returns the centroid of the geometry. Polygons use the area
+// weighted centroid of their exterior rings, other geometries the mean of
+// their positions.
+func (g *PlaceElemGeometryGeojson) Centroid() (Position, bool) {
+	switch g.geometryType() {
+	case PlaceElemGeometryGeojsonTypePoint:
+		if len(g.Coordinates) < 2 {
+			return nil, false
+		}
+
+		return Position{g.Coordinates[0], g.Coordinates[1]}, true
+	case PlaceElemGeometryGeojsonTypePolygon:
+		return polygonsCentroid([][][]Position{g.Lines})
+	case PlaceElemGeometryGeojsonTypeMultiPolygon:
+		return polygonsCentroid(g.Polygons)
+	case PlaceElemGeometryGeojsonTypeGeometryCollection:
+		var (
+			lon, lat float64
+			n        int
+		)
+
+		for i := range g.Geometries {
+			c, ok := g.Geometries[i].Centroid()
+			if ok {
+				lon += c.Lon()
+				lat += c.Lat()
+				n++
+			}
+		}
+
+		if n == 0 {
+			return nil, false
+		}
+
+		return Position{lon / float64(n), lat / float64(n)}, true
+	}
+
+	return meanPosition(g)
+}
+
+func meanPosition(g *PlaceElemGeometryGeojson) (Position, bool) {
+	var (
+		lon, lat float64
+		n        int
+	)
+
+	g.eachPosition(func(p Position) {
+		lon += p.Lon()
+		lat += p.Lat()
+		n++
+	})
+
+	if n == 0 {
+		return nil, false
+	}
+
+	return Position{lon / float64(n), lat / float64(n)}, true
+}
+
+// polygonsCentroid calculates the area weighted centroid of the exterior rings
+// of the polygons, using the planar shoelace formula. Degenerate polygons
+// without an area fall back to the mean of their positions.
+func polygonsCentroid(polygons [][][]Position) (Position, bool) {
+	var area, cx, cy float64
+
+	for _, rings := range polygons {
+		if len(rings) == 0 {
+			continue
+		}
+
+		var ra, rx, ry float64
+
+		ring := rings[0]
+
+		for i := 0; i+1 < len(ring); i++ {
+			a, b := ring[i], ring[i+1]
+			cross := a.Lon()*b.Lat() - b.Lon()*a.Lat()
+
+			ra += cross
+			rx += (a.Lon() + b.Lon()) * cross
+			ry += (a.Lat() + b.Lat()) * cross
+		}
+
+		// Normalise the winding order so that the polygons of a
+		// MultiPolygon don't cancel each other out.
+		if ra < 0 {
+			ra, rx, ry = -ra, -rx, -ry
+		}
+
+		area += ra
+		cx += rx
+		cy += ry
+	}
+
+	if area == 0 {
+		return meanPosition(&PlaceElemGeometryGeojson{
+			Type:     PlaceElemGeometryGeojsonTypeMultiPolygon,
+			Polygons: polygons,
+		})
+	}
+
+	return Position{cx / (3 * area), cy / (3 * area)}, true
+}
+
+// Bounds returns the south-west and north-east corners of the bounding box of
+// the geometry.
+func (g *PlaceElemGeometryGeojson) Bounds() (sw Position, ne Position, ok bool) {
+	minLon, minLat := math.Inf(1), math.Inf(1)
+	maxLon, maxLat := math.Inf(-1), math.Inf(-1)
+
+	g.eachPosition(func(p Position) {
+		if len(p) < 2 {
+			return
+		}
+
+		ok = true
+		minLon = math.Min(minLon, p.Lon())
+		minLat = math.Min(minLat, p.Lat())
+		maxLon = math.Max(maxLon, p.Lon())
+		maxLat = math.Max(maxLat, p.Lat())
+	})
+
+	if !ok {
+		return nil, nil, false
+	}
+
+	return Position{minLon, minLat}, Position{maxLon, maxLat}, true
+}
+
+// Contains returns true if the position lies within a polygon of the
+// geometry, and outside of its holes. Only Polygon and MultiPolygon
+// geometries, also as part of a GeometryCollection, can contain positions.
+func (g *PlaceElemGeometryGeojson) Contains(lon, lat float64) bool {
+	switch g.geometryType() {
+	case PlaceElemGeometryGeojsonTypePolygon:
+		return polygonContains(g.Lines, lon, lat)
+	case PlaceElemGeometryGeojsonTypeMultiPolygon:
+		for _, p := range g.Polygons {
+			if polygonContains(p, lon, lat) {
+				return true
+			}
+		}
+	case PlaceElemGeometryGeojsonTypeGeometryCollection:
+		for i := range g.Geometries {
+			if g.Geometries[i].Contains(lon, lat) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func polygonContains(rings [][]Position, lon, lat float64) bool {
+	if len(rings) == 0 || !ringContains(rings[0], lon, lat) {
+		return false
+	}
+
+	for _, hole := range rings[1:] {
+		if ringContains(hole, lon, lat) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ringContains tests if a position is inside a linear ring using ray casting.
+func ringContains(ring []Position, lon, lat float64) bool {
+	var inside bool
+
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+
+		if (a.Lat() > lat) == (b.Lat() > lat) {
+			continue
+		}
+
+		x := a.Lon() + (lat-a.Lat())*(b.Lon()-a.Lon())/(b.Lat()-a.Lat())
+		if lon < x {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// eachPosition calls fn for every position of the geometry.
+func (g *PlaceElemGeometryGeojson) eachPosition(fn func(p Position)) {
+	if len(g.Coordinates) > 0 {
+		fn(g.Coordinates)
+	}
+
+	for _, p := range g.Positions {
+		fn(p)
+	}
+
+	for _, line := range g.Lines {
+		for _, p := range line {
+			fn(p)
+		}
+	}
+
+	for _, rings := range g.Polygons {
+		for _, ring := range rings {
+			for _, p := range ring {
+				fn(p)
+			}
+		}
+	}
+
+	for i := range g.Geometries {
+		g.Geometries[i].eachPosition(fn)
+	}
+}
@@ -0,0 +1,246 @@
+// Package geo implements geospatial queries over documents, based on the
+// geometries of their places: distance, bounding box and containment queries
+// over slices of documents, and an in-memory index for doing the same over
+// large sets of documents.
+//
+// Queries respect the level of places, see ttninjs.PlaceElem.Level, so that a
+// document about a country doesn't match a radius query around a city in it.
+package geo
+
+import (
+	"math"
+
+	"github.com/ttab/ttninjs"
+)
+
+// EarthRadius is the mean radius of the earth in kilometres.
+const EarthRadius = 6371.0088
+
+// Point is a position given as latitude and longitude in degrees.
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// Distance returns the great-circle distance between two points in
+// kilometres.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat := lat2 - lat1
+	dLon := radians(b.Lon - a.Lon)
+
+	h := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// BoundingBox is an area between two latitudes and two longitudes. Boxes that
+// cross the antimeridian aren't supported.
+type BoundingBox struct {
+	South float64
+	West  float64
+	North float64
+	East  float64
+}
+
+// Contains returns true if the point is inside the box.
+func (b BoundingBox) Contains(p Point) bool {
+	return p.Lat >= b.South && p.Lat <= b.North &&
+		p.Lon >= b.West && p.Lon <= b.East
+}
+
+// Intersects returns true if the boxes overlap.
+func (b BoundingBox) Intersects(o BoundingBox) bool {
+	return b.South <= o.North && o.South <= b.North &&
+		b.West <= o.East && o.West <= b.East
+}
+
+// RadiusBox returns the bounding box of the circle around the point.
+func RadiusBox(p Point, km float64) BoundingBox {
+	dLat := km / EarthRadius * 180 / math.Pi
+
+	box := BoundingBox{
+		South: math.Max(-90, p.Lat-dLat),
+		North: math.Min(90, p.Lat+dLat),
+		West:  -180,
+		East:  180,
+	}
+
+	// Near the poles the circle covers all longitudes. Boxes can't cross
+	// the antimeridian, so circles that do also cover all longitudes.
+	cos := math.Cos(radians(p.Lat))
+	if box.South > -90 && box.North < 90 && cos > 0 {
+		dLon := dLat / cos
+		if p.Lon-dLon >= -180 && p.Lon+dLon <= 180 {
+			box.West = p.Lon - dLon
+			box.East = p.Lon + dLon
+		}
+	}
+
+	return box
+}
+
+// Filter controls which places of documents are considered by queries.
+type Filter struct {
+	// MinLevel is the broadest type of place that is matched. The zero
+	// value defaults to municipalities, so that countries and regions
+	// are left out. Use ttninjs.PlaceLevelCountry to match all places.
+	// Places with an unknown level are always matched.
+	MinLevel ttninjs.PlaceLevel
+}
+
+// Accepts returns true if the place is matched by queries with the filter.
+func (f Filter) Accepts(p ttninjs.PlaceElem) bool {
+	if p.GeometryGeojson == nil {
+		return false
+	}
+
+	level := p.Level()
+
+	return level == ttninjs.PlaceLevelUnknown || level >= f.minLevel()
+}
+
+func (f Filter) minLevel() ttninjs.PlaceLevel {
+	if f.MinLevel == ttninjs.PlaceLevelUnknown {
+		return ttninjs.PlaceLevelMunicipality
+	}
+
+	return f.MinLevel
+}
+
+// WithinRadius returns the documents with a place within km kilometres of the
+// point, using the default filter.
+func WithinRadius(docs []ttninjs.Document, lat, lon, km float64) []ttninjs.Document {
+	return Filter{}.WithinRadius(docs, lat, lon, km)
+}
+
+// InBoundingBox returns the documents with a place inside or overlapping the
+// box, using the default filter.
+func InBoundingBox(docs []ttninjs.Document, box BoundingBox) []ttninjs.Document {
+	return Filter{}.InBoundingBox(docs, box)
+}
+
+// Containing returns the documents with a polygon that contains the point,
+// using the default filter.
+func Containing(docs []ttninjs.Document, lat, lon float64) []ttninjs.Document {
+	return Filter{}.Containing(docs, lat, lon)
+}
+
+// WithinRadius returns the documents with a place within km kilometres of the
+// point. See PlaceDistance for how the distance to a place is measured.
+func (f Filter) WithinRadius(docs []ttninjs.Document, lat, lon, km float64) []ttninjs.Document {
+	p := Point{Lat: lat, Lon: lon}
+
+	return f.matching(docs, func(g *ttninjs.PlaceElemGeometryGeojson) bool {
+		d, ok := PlaceDistance(g, p)
+
+		return ok && d <= km
+	})
+}
+
+// InBoundingBox returns the documents with a place whose bounding box overlaps
+// the box.
+func (f Filter) InBoundingBox(docs []ttninjs.Document, box BoundingBox) []ttninjs.Document {
+	return f.matching(docs, func(g *ttninjs.PlaceElemGeometryGeojson) bool {
+		b, ok := geometryBox(g)
+
+		return ok && box.Intersects(b)
+	})
+}
+
+// Containing returns the documents with a place whose polygon contains the
+// point.
+func (f Filter) Containing(docs []ttninjs.Document, lat, lon float64) []ttninjs.Document {
+	return f.matching(docs, func(g *ttninjs.PlaceElemGeometryGeojson) bool {
+		return g.Contains(lon, lat)
+	})
+}
+
+func (f Filter) matching(
+	docs []ttninjs.Document, fn func(g *ttninjs.PlaceElemGeometryGeojson) bool,
+) []ttninjs.Document {
+	var res []ttninjs.Document
+
+	for _, doc := range docs {
+		for _, p := range doc.Place {
+			if f.Accepts(p) && fn(p.GeometryGeojson) {
+				res = append(res, doc)
+
+				break
+			}
+		}
+	}
+
+	return res
+}
+
+// PlaceDistance returns the distance in kilometres from the point to the
+// geometry. The distance to an area that contains the point is zero, otherwise
+// the distance to the closest of its positions and its centroid is used, which
+// is an approximation for large areas.
+func PlaceDistance(g *ttninjs.PlaceElemGeometryGeojson, p Point) (float64, bool) {
+	if g.Contains(p.Lon, p.Lat) {
+		return 0, true
+	}
+
+	c, ok := g.Centroid()
+	if !ok {
+		return 0, false
+	}
+
+	d := Distance(p, Point{Lat: c.Lat(), Lon: c.Lon()})
+
+	switch g.Type {
+	case "", ttninjs.PlaceElemGeometryGeojsonTypePoint:
+		return d, true
+	}
+
+	for _, pos := range positions(g) {
+		d = math.Min(d, Distance(p, Point{Lat: pos.Lat(), Lon: pos.Lon()}))
+	}
+
+	return d, true
+}
+
+func positions(g *ttninjs.PlaceElemGeometryGeojson) []ttninjs.Position {
+	res := append([]ttninjs.Position(nil), g.Positions...)
+
+	for _, line := range g.Lines {
+		res = append(res, line...)
+	}
+
+	for _, rings := range g.Polygons {
+		for _, ring := range rings {
+			res = append(res, ring...)
+		}
+	}
+
+	for i := range g.Geometries {
+		if c := g.Geometries[i].Coordinates; len(c) >= 2 {
+			res = append(res, c)
+		}
+
+		res = append(res, positions(&g.Geometries[i])...)
+	}
+
+	return res
+}
+
+func geometryBox(g *ttninjs.PlaceElemGeometryGeojson) (BoundingBox, bool) {
+	sw, ne, ok := g.Bounds()
+	if !ok {
+		return BoundingBox{}, false
+	}
+
+	return BoundingBox{
+		South: sw.Lat(),
+		West:  sw.Lon(),
+		North: ne.Lat(),
+		East:  ne.Lon(),
+	}, true
+}
@@ -0,0 +1,343 @@
+package geo
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/ttab/ttninjs"
+)
+
+var (
+	stockholm = Point{Lat: 59.3293, Lon: 18.0686}
+	goteborg  = Point{Lat: 57.7089, Lon: 11.9746}
+	uppsala   = Point{Lat: 59.8586, Lon: 17.6389}
+	newYork   = Point{Lat: 40.7128, Lon: -74.0060}
+)
+
+func square(west, south, east, north float64) []ttninjs.Position {
+	return []ttninjs.Position{
+		{west, south}, {east, south}, {east, north}, {west, north}, {west, south},
+	}
+}
+
+func place(rel string, g *ttninjs.PlaceElemGeometryGeojson) ttninjs.PlaceElem {
+	return ttninjs.PlaceElem{Rel: rel, GeometryGeojson: g}
+}
+
+func pointPlace(rel string, p Point) ttninjs.PlaceElem {
+	return place(rel, ttninjs.NewPoint(p.Lon, p.Lat))
+}
+
+var (
+	swedenPlace = place("land", ttninjs.NewPolygon(square(11, 55, 24, 69)))
+	// A municipality-sized area around Stockholm with a hole for an
+	// island.
+	stockholmArea = place("kommun", ttninjs.NewPolygon(
+		square(17.9, 59.2, 18.2, 59.45),
+		square(18.1, 59.3, 18.15, 59.35),
+	))
+)
+
+func testDocuments() []ttninjs.Document {
+	return []ttninjs.Document{
+		{Uri: "sweden", Place: []ttninjs.PlaceElem{swedenPlace}},
+		{Uri: "stockholm", Place: []ttninjs.PlaceElem{stockholmArea}},
+		{Uri: "goteborg", Place: []ttninjs.PlaceElem{swedenPlace, pointPlace("ort", goteborg)}},
+		{Uri: "uppsala", Place: []ttninjs.PlaceElem{pointPlace("", uppsala)}},
+		{Uri: "newyork", Place: []ttninjs.PlaceElem{pointPlace("city", newYork)}},
+		{Uri: "nowhere", Place: []ttninjs.PlaceElem{{Rel: "ort", Name: "Okänd"}}},
+	}
+}
+
+func uris(docs []ttninjs.Document) []string {
+	var res []string
+
+	for _, d := range docs {
+		res = append(res, d.Uri)
+	}
+
+	slices.Sort(res)
+
+	return res
+}
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b Point
+		km   float64
+	}{
+		{"same point", stockholm, stockholm, 0},
+		{"stockholm-goteborg", stockholm, goteborg, 398},
+		{"stockholm-uppsala", stockholm, uppsala, 64},
+		{"stockholm-new york", stockholm, newYork, 6325},
+		{"one degree at the equator", Point{0, 0}, Point{0, 1}, 111.2},
+		{"across the antimeridian", Point{0, 179.5}, Point{0, -179.5}, 111.2},
+		{"across the pole", Point{89, 0}, Point{89, 180}, 222.4},
+		{"antipodes", Point{0, 0}, Point{0, 180}, math.Pi * EarthRadius},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := Distance(c.a, c.b)
+			if math.Abs(d-c.km) > c.km*0.01+0.01 {
+				t.Errorf("got %.2f km, want about %.2f km", d, c.km)
+			}
+
+			if r := Distance(c.b, c.a); math.Abs(r-d) > 1e-9 {
+				t.Errorf("not symmetric: %v and %v", d, r)
+			}
+		})
+	}
+}
+
+func TestRadiusBox(t *testing.T) {
+	cases := []struct {
+		name string
+		p    Point
+		km   float64
+		want BoundingBox
+	}{
+		{
+			name: "equator",
+			p:    Point{0, 0},
+			km:   111.195,
+			want: BoundingBox{South: -1, West: -1, North: 1, East: 1},
+		},
+		{
+			name: "stockholm",
+			p:    stockholm,
+			km:   111.195,
+			want: BoundingBox{
+				South: 58.3293, West: 18.0686 - 1.9616,
+				North: 60.3293, East: 18.0686 + 1.9616,
+			},
+		},
+		{
+			name: "north pole",
+			p:    Point{89.9, 0},
+			km:   50,
+			want: BoundingBox{South: 89.45, West: -180, North: 90, East: 180},
+		},
+		{
+			name: "south pole",
+			p:    Point{-90, 0},
+			km:   10,
+			want: BoundingBox{South: -90, West: -180, North: -89.91, East: 180},
+		},
+		{
+			name: "antimeridian east",
+			p:    Point{0, 179.9},
+			km:   50,
+			want: BoundingBox{South: -0.45, West: -180, North: 0.45, East: 180},
+		},
+		{
+			name: "antimeridian west",
+			p:    Point{-17, -179.9},
+			km:   50,
+			want: BoundingBox{South: -17.45, West: -180, North: -16.55, East: 180},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := RadiusBox(c.p, c.km)
+
+			for _, v := range [][2]float64{
+				{got.South, c.want.South}, {got.West, c.want.West},
+				{got.North, c.want.North}, {got.East, c.want.East},
+			} {
+				if math.Abs(v[0]-v[1]) > 0.01 {
+					t.Fatalf("got %+v, want %+v", got, c.want)
+				}
+			}
+
+			if !got.Contains(c.p) {
+				t.Errorf("box %+v doesn't contain %+v", got, c.p)
+			}
+		})
+	}
+}
+
+func TestBoundingBox(t *testing.T) {
+	box := BoundingBox{South: 59, West: 17, North: 60, East: 19}
+
+	if !box.Contains(stockholm) || box.Contains(goteborg) {
+		t.Error("unexpected Contains result")
+	}
+
+	if !box.Contains(Point{Lat: 59, Lon: 19}) {
+		t.Error("edges should be inside the box")
+	}
+
+	intersects := map[BoundingBox]bool{
+		{South: 59.5, West: 18, North: 61, East: 20}: true,
+		{South: 60, West: 19, North: 61, East: 20}:   true,
+		{South: 58, West: 16, North: 61, East: 20}:   true,
+		{South: 60.1, West: 17, North: 61, East: 19}: false,
+		{South: 59, West: 19.1, North: 60, East: 20}: false,
+	}
+
+	for o, want := range intersects {
+		if box.Intersects(o) != want || o.Intersects(box) != want {
+			t.Errorf("Intersects(%+v): want %v", o, want)
+		}
+	}
+}
+
+func TestFilterAccepts(t *testing.T) {
+	noGeometry := ttninjs.PlaceElem{Rel: "ort", Name: "Stockholm"}
+	city := pointPlace("ort", stockholm)
+	unknown := pointPlace("", stockholm)
+	region := pointPlace("län", stockholm)
+
+	cases := []struct {
+		name   string
+		filter Filter
+		place  ttninjs.PlaceElem
+		want   bool
+	}{
+		{"no geometry", Filter{}, noGeometry, false},
+		{"default city", Filter{}, city, true},
+		{"default municipality", Filter{}, stockholmArea, true},
+		{"default region", Filter{}, region, false},
+		{"default country", Filter{}, swedenPlace, false},
+		{"default unknown", Filter{}, unknown, true},
+		{"all country", Filter{MinLevel: ttninjs.PlaceLevelCountry}, swedenPlace, true},
+		{"regions", Filter{MinLevel: ttninjs.PlaceLevelRegion}, region, true},
+		{"localities municipality", Filter{MinLevel: ttninjs.PlaceLevelLocality}, stockholmArea, false},
+		{"localities unknown", Filter{MinLevel: ttninjs.PlaceLevelLocality}, unknown, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.filter.Accepts(c.place); got != c.want {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestWithinRadius(t *testing.T) {
+	docs := testDocuments()
+	all := Filter{MinLevel: ttninjs.PlaceLevelCountry}
+
+	cases := []struct {
+		name   string
+		filter Filter
+		p      Point
+		km     float64
+		want   []string
+	}{
+		{"stockholm", Filter{}, stockholm, 10, []string{"stockholm"}},
+		{"stockholm and uppsala", Filter{}, stockholm, 70, []string{"stockholm", "uppsala"}},
+		{"mid sweden", Filter{}, stockholm, 400, []string{"goteborg", "stockholm", "uppsala"}},
+		{"countries", all, Point{Lat: 65, Lon: 15}, 1, []string{"goteborg", "sweden"}},
+		{"new york", Filter{}, newYork, 1, []string{"newyork"}},
+		{"atlantic", all, Point{Lat: 50, Lon: -30}, 100, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := uris(c.filter.WithinRadius(docs, c.p.Lat, c.p.Lon, c.km))
+			if !slices.Equal(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	got := uris(WithinRadius(docs, stockholm.Lat, stockholm.Lon, 70))
+	if !slices.Equal(got, []string{"stockholm", "uppsala"}) {
+		t.Errorf("WithinRadius: got %v", got)
+	}
+}
+
+func TestInBoundingBox(t *testing.T) {
+	docs := testDocuments()
+	mid := BoundingBox{South: 59, West: 16, North: 60, East: 19}
+
+	got := uris(InBoundingBox(docs, mid))
+	if !slices.Equal(got, []string{"stockholm", "uppsala"}) {
+		t.Errorf("got %v", got)
+	}
+
+	got = uris(Filter{MinLevel: ttninjs.PlaceLevelCountry}.InBoundingBox(docs, mid))
+	if !slices.Equal(got, []string{"goteborg", "stockholm", "sweden", "uppsala"}) {
+		t.Errorf("got %v with countries", got)
+	}
+
+	got = uris(InBoundingBox(docs, BoundingBox{South: 0, West: -10, North: 10, East: 0}))
+	if got != nil {
+		t.Errorf("got %v, want no documents", got)
+	}
+}
+
+func TestContaining(t *testing.T) {
+	docs := testDocuments()
+	all := Filter{MinLevel: ttninjs.PlaceLevelCountry}
+
+	cases := []struct {
+		name   string
+		filter Filter
+		p      Point
+		want   []string
+	}{
+		{"stockholm", Filter{}, stockholm, []string{"stockholm"}},
+		{"island", Filter{}, Point{Lat: 59.32, Lon: 18.12}, nil},
+		{"island in sweden", all, Point{Lat: 59.32, Lon: 18.12}, []string{"goteborg", "sweden"}},
+		{"points contain nothing", Filter{}, goteborg, nil},
+		{"outside", all, newYork, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := uris(c.filter.Containing(docs, c.p.Lat, c.p.Lon))
+			if !slices.Equal(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	got := uris(Containing(docs, stockholm.Lat, stockholm.Lon))
+	if !slices.Equal(got, []string{"stockholm"}) {
+		t.Errorf("Containing: got %v", got)
+	}
+}
+
+func TestPlaceDistance(t *testing.T) {
+	line := &ttninjs.PlaceElemGeometryGeojson{
+		Type:      ttninjs.PlaceElemGeometryGeojsonTypeLineString,
+		Positions: []ttninjs.Position{{11.9746, 57.7089}, {18.0686, 59.3293}},
+	}
+
+	cases := []struct {
+		name string
+		g    *ttninjs.PlaceElemGeometryGeojson
+		p    Point
+		km   float64
+	}{
+		{"point", ttninjs.NewPoint(goteborg.Lon, goteborg.Lat), stockholm, 398},
+		{"inside polygon", stockholmArea.GeometryGeojson, stockholm, 0},
+		{"closest position", line, uppsala, 64},
+		{"polygon corner", stockholmArea.GeometryGeojson, Point{Lat: 59.2, Lon: 17.8}, 5.7},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, ok := PlaceDistance(c.g, c.p)
+			if !ok {
+				t.Fatal("expected a distance")
+			}
+
+			if math.Abs(d-c.km) > c.km*0.01+0.01 {
+				t.Errorf("got %.2f km, want about %.2f km", d, c.km)
+			}
+		})
+	}
+
+	_, ok := PlaceDistance(&ttninjs.PlaceElemGeometryGeojson{}, stockholm)
+	if ok {
+		t.Error("expected no distance for an empty geometry")
+	}
+}
@@ -0,0 +1,315 @@
+package geo
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/ttab/ttninjs"
+)
+
+// DefaultCellSize is the size in degrees of the grid cells of an index, about
+// 11 km in latitude.
+const DefaultCellSize = 0.1
+
+// maxPlaceCells is the number of cells that a place can be added to. Places
+// with larger bounding boxes, like countries, are kept in a separate list that
+// is scanned by every query.
+const maxPlaceCells = 256
+
+// Match is a document found by a radius query.
+type Match struct {
+	ID string
+	// Distance is the distance in kilometres to the closest matching place
+	// of the document.
+	Distance float64
+}
+
+// Index is an in-memory grid index of the places of documents, for answering
+// radius, bounding box and containment queries over large sets of documents.
+// An Index is safe for concurrent use.
+type Index struct {
+	m        sync.RWMutex
+	cellSize float64
+	entries  map[string][]indexedPlace
+	cells    map[cellKey][]string
+	wide     map[string]struct{}
+}
+
+type indexedPlace struct {
+	place ttninjs.PlaceElem
+	box   BoundingBox
+}
+
+type cellKey struct {
+	x, y int32
+}
+
+// NewIndex creates an empty index with cells of DefaultCellSize.
+func NewIndex() *Index {
+	return NewIndexWithCellSize(DefaultCellSize)
+}
+
+// NewIndexWithCellSize creates an empty index with cells of the given size in
+// degrees. Smaller cells make queries with a small radius faster at the cost
+// of memory.
+func NewIndexWithCellSize(size float64) *Index {
+	if size <= 0 {
+		size = DefaultCellSize
+	}
+
+	return &Index{
+		cellSize: size,
+		entries:  make(map[string][]indexedPlace),
+		cells:    make(map[cellKey][]string),
+		wide:     make(map[string]struct{}),
+	}
+}
+
+// Len returns the number of documents in the index.
+func (idx *Index) Len() int {
+	idx.m.RLock()
+	defer idx.m.RUnlock()
+
+	return len(idx.entries)
+}
+
+// AddDocument adds the places of a document to the index using its URI as ID.
+func (idx *Index) AddDocument(doc *ttninjs.Document) {
+	idx.Add(doc.Uri, doc.Place)
+}
+
+// Add adds places to the index, replacing any existing places with the same
+// ID. Places without a geometry are ignored, and nothing is added if none of
+// the places have one. All levels of places are indexed, filtering is done
+// when querying.
+func (idx *Index) Add(id string, places []ttninjs.PlaceElem) {
+	var entry []indexedPlace
+
+	for _, p := range places {
+		if p.GeometryGeojson == nil {
+			continue
+		}
+
+		box, ok := geometryBox(p.GeometryGeojson)
+		if !ok {
+			continue
+		}
+
+		entry = append(entry, indexedPlace{place: p, box: box})
+	}
+
+	idx.m.Lock()
+	defer idx.m.Unlock()
+
+	if _, exists := idx.entries[id]; exists {
+		idx.remove(id)
+	}
+
+	if len(entry) == 0 {
+		return
+	}
+
+	idx.entries[id] = entry
+
+	for _, ip := range entry {
+		cells, ok := idx.boxCells(ip.box, maxPlaceCells)
+		if !ok {
+			idx.wide[id] = struct{}{}
+
+			continue
+		}
+
+		for _, c := range cells {
+			if !slices.Contains(idx.cells[c], id) {
+				idx.cells[c] = append(idx.cells[c], id)
+			}
+		}
+	}
+}
+
+// Remove removes a document from the index.
+func (idx *Index) Remove(id string) {
+	idx.m.Lock()
+	defer idx.m.Unlock()
+
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id string) {
+	entry, ok := idx.entries[id]
+	if !ok {
+		return
+	}
+
+	delete(idx.entries, id)
+	delete(idx.wide, id)
+
+	for _, ip := range entry {
+		cells, ok := idx.boxCells(ip.box, maxPlaceCells)
+		if !ok {
+			continue
+		}
+
+		for _, c := range cells {
+			ids := slices.DeleteFunc(idx.cells[c], func(s string) bool {
+				return s == id
+			})
+
+			if len(ids) == 0 {
+				delete(idx.cells, c)
+			} else {
+				idx.cells[c] = ids
+			}
+		}
+	}
+}
+
+// WithinRadius returns the indexed documents with a place accepted by the
+// filter within km kilometres of the point, closest first.
+func (idx *Index) WithinRadius(lat, lon, km float64, f Filter) []Match {
+	p := Point{Lat: lat, Lon: lon}
+	box := RadiusBox(p, km)
+
+	idx.m.RLock()
+	defer idx.m.RUnlock()
+
+	var matches []Match
+
+	for _, id := range idx.candidates(box) {
+		best := math.Inf(1)
+
+		for _, ip := range idx.entries[id] {
+			if !f.Accepts(ip.place) || !box.Intersects(ip.box) {
+				continue
+			}
+
+			d, ok := PlaceDistance(ip.place.GeometryGeojson, p)
+			if ok && d <= km {
+				best = math.Min(best, d)
+			}
+		}
+
+		if !math.IsInf(best, 1) {
+			matches = append(matches, Match{ID: id, Distance: best})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+
+		return matches[i].ID < matches[j].ID
+	})
+
+	return matches
+}
+
+// InBoundingBox returns the IDs of the indexed documents with a place accepted
+// by the filter whose bounding box overlaps the box, in sorted order.
+func (idx *Index) InBoundingBox(box BoundingBox, f Filter) []string {
+	idx.m.RLock()
+	defer idx.m.RUnlock()
+
+	return idx.query(box, f, func(ip indexedPlace) bool {
+		return box.Intersects(ip.box)
+	})
+}
+
+// Containing returns the IDs of the indexed documents with a place accepted by
+// the filter whose polygon contains the point, in sorted order.
+func (idx *Index) Containing(lat, lon float64, f Filter) []string {
+	box := BoundingBox{South: lat, West: lon, North: lat, East: lon}
+
+	idx.m.RLock()
+	defer idx.m.RUnlock()
+
+	return idx.query(box, f, func(ip indexedPlace) bool {
+		return ip.box.Contains(Point{Lat: lat, Lon: lon}) &&
+			ip.place.GeometryGeojson.Contains(lon, lat)
+	})
+}
+
+func (idx *Index) query(box BoundingBox, f Filter, fn func(ip indexedPlace) bool) []string {
+	var ids []string
+
+	for _, id := range idx.candidates(box) {
+		for _, ip := range idx.entries[id] {
+			if f.Accepts(ip.place) && fn(ip) {
+				ids = append(ids, id)
+
+				break
+			}
+		}
+	}
+
+	slices.Sort(ids)
+
+	return ids
+}
+
+// candidates returns the IDs of the documents that might have places in the
+// box. Queries covering more cells than there are documents scan all
+// documents instead of the grid.
+func (idx *Index) candidates(box BoundingBox) []string {
+	cells, ok := idx.boxCells(box, len(idx.entries))
+	if !ok {
+		ids := make([]string, 0, len(idx.entries))
+
+		for id := range idx.entries {
+			ids = append(ids, id)
+		}
+
+		return ids
+	}
+
+	seen := make(map[string]struct{})
+
+	var ids []string
+
+	add := func(id string) {
+		if _, done := seen[id]; !done {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+
+	for _, c := range cells {
+		for _, id := range idx.cells[c] {
+			add(id)
+		}
+	}
+
+	for id := range idx.wide {
+		add(id)
+	}
+
+	return ids
+}
+
+// boxCells returns the grid cells overlapped by the box, or false if there are
+// more than limit cells.
+func (idx *Index) boxCells(box BoundingBox, limit int) ([]cellKey, bool) {
+	x0, y0 := idx.cell(box.West), idx.cell(box.South)
+	x1, y1 := idx.cell(box.East), idx.cell(box.North)
+
+	if (int64(x1)-int64(x0)+1)*(int64(y1)-int64(y0)+1) > int64(limit) {
+		return nil, false
+	}
+
+	var cells []cellKey
+
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			cells = append(cells, cellKey{x: x, y: y})
+		}
+	}
+
+	return cells, true
+}
+
+func (idx *Index) cell(deg float64) int32 {
+	return int32(math.Floor(deg / idx.cellSize))
+}
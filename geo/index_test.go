@@ -0,0 +1,285 @@
+package geo
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ttab/ttninjs"
+)
+
+func testIndex(t *testing.T) *Index {
+	t.Helper()
+
+	idx := NewIndex()
+
+	for _, doc := range testDocuments() {
+		idx.AddDocument(&doc)
+	}
+
+	return idx
+}
+
+func matchIDs(matches []Match) []string {
+	var ids []string
+
+	for _, m := range matches {
+		ids = append(ids, m.ID)
+	}
+
+	return ids
+}
+
+func TestIndexMatchesFilter(t *testing.T) {
+	docs := testDocuments()
+	idx := testIndex(t)
+
+	// Documents without any geometry aren't indexed.
+	if idx.Len() != len(docs)-1 {
+		t.Fatalf("got %d documents, want %d", idx.Len(), len(docs)-1)
+	}
+
+	filters := []Filter{
+		{},
+		{MinLevel: ttninjs.PlaceLevelCountry},
+		{MinLevel: ttninjs.PlaceLevelLocality},
+	}
+	points := []Point{
+		stockholm, goteborg, uppsala, newYork,
+		{Lat: 59.32, Lon: 18.12},
+		{Lat: 65, Lon: 15},
+	}
+
+	for _, f := range filters {
+		for _, p := range points {
+			for _, km := range []float64{1, 10, 70, 400, 8000} {
+				got := matchIDs(idx.WithinRadius(p.Lat, p.Lon, km, f))
+				slices.Sort(got)
+
+				want := uris(f.WithinRadius(docs, p.Lat, p.Lon, km))
+				if !slices.Equal(got, want) {
+					t.Errorf("WithinRadius(%+v, %v, %+v): got %v, want %v",
+						p, km, f, got, want)
+				}
+			}
+
+			got := idx.Containing(p.Lat, p.Lon, f)
+			want := uris(f.Containing(docs, p.Lat, p.Lon))
+
+			if !slices.Equal(got, want) {
+				t.Errorf("Containing(%+v, %+v): got %v, want %v", p, f, got, want)
+			}
+
+			box := RadiusBox(p, 50)
+
+			got = idx.InBoundingBox(box, f)
+			want = uris(f.InBoundingBox(docs, box))
+
+			if !slices.Equal(got, want) {
+				t.Errorf("InBoundingBox(%+v, %+v): got %v, want %v", box, f, got, want)
+			}
+		}
+	}
+}
+
+func TestIndexWithinRadiusOrder(t *testing.T) {
+	idx := testIndex(t)
+
+	matches := idx.WithinRadius(stockholm.Lat, stockholm.Lon, 500, Filter{})
+
+	if got := matchIDs(matches); !slices.Equal(got, []string{"stockholm", "uppsala", "goteborg"}) {
+		t.Fatalf("got %v, want closest first", got)
+	}
+
+	if matches[0].Distance != 0 {
+		t.Errorf("got distance %v inside the municipality, want 0", matches[0].Distance)
+	}
+
+	if d := matches[2].Distance; d < 390 || d > 405 {
+		t.Errorf("got %.1f km to Göteborg, want about 398 km", d)
+	}
+}
+
+func TestIndexWidePlaces(t *testing.T) {
+	idx := testIndex(t)
+
+	// Sweden spans too many cells to be added to the grid.
+	if _, ok := idx.wide["sweden"]; !ok {
+		t.Fatal("expected the country to be a wide place")
+	}
+
+	if _, ok := idx.wide["stockholm"]; ok {
+		t.Fatal("expected the municipality to be in the grid")
+	}
+
+	got := idx.Containing(65, 15, Filter{MinLevel: ttninjs.PlaceLevelCountry})
+	if !slices.Equal(got, []string{"goteborg", "sweden"}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestIndexAddRemove(t *testing.T) {
+	idx := testIndex(t)
+
+	idx.Remove("stockholm")
+	idx.Remove("unknown")
+
+	if got := idx.Containing(stockholm.Lat, stockholm.Lon, Filter{}); got != nil {
+		t.Errorf("got %v after removing the document", got)
+	}
+
+	// Moving a document replaces its old places.
+	idx.Add("uppsala", []ttninjs.PlaceElem{pointPlace("ort", goteborg)})
+
+	got := matchIDs(idx.WithinRadius(uppsala.Lat, uppsala.Lon, 10, Filter{}))
+	if got != nil {
+		t.Errorf("got %v at the old position", got)
+	}
+
+	got = matchIDs(idx.WithinRadius(goteborg.Lat, goteborg.Lon, 1, Filter{}))
+	if !slices.Equal(got, []string{"goteborg", "uppsala"}) {
+		t.Errorf("got %v at the new position", got)
+	}
+
+	// Adding a document without geometries removes it.
+	idx.Add("sweden", []ttninjs.PlaceElem{{Rel: "land", Name: "Sverige"}})
+
+	if _, ok := idx.wide["sweden"]; ok {
+		t.Error("expected the wide place to be removed")
+	}
+
+	if idx.Len() != 3 {
+		t.Errorf("got %d documents, want 3", idx.Len())
+	}
+
+	for _, id := range []string{"goteborg", "uppsala", "newyork", "sweden", "stockholm"} {
+		idx.Remove(id)
+	}
+
+	if idx.Len() != 0 || len(idx.cells) != 0 || len(idx.wide) != 0 {
+		t.Errorf("expected an empty index, got %d documents, %d cells and %d wide",
+			idx.Len(), len(idx.cells), len(idx.wide))
+	}
+}
+
+func TestIndexAntimeridian(t *testing.T) {
+	idx := NewIndex()
+
+	idx.Add("east", []ttninjs.PlaceElem{pointPlace("ort", Point{Lat: -17, Lon: 179.95})})
+	idx.Add("west", []ttninjs.PlaceElem{pointPlace("ort", Point{Lat: -17, Lon: -179.95})})
+	idx.Add("far", []ttninjs.PlaceElem{pointPlace("ort", Point{Lat: -17, Lon: 178})})
+
+	cases := []struct {
+		name string
+		p    Point
+		want []string
+	}{
+		{"from the east", Point{Lat: -17, Lon: 179.99}, []string{"east", "west"}},
+		{"from the west", Point{Lat: -17, Lon: -179.99}, []string{"west", "east"}},
+		{"on the antimeridian", Point{Lat: -17, Lon: 180}, []string{"east", "west"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			matches := idx.WithinRadius(c.p.Lat, c.p.Lon, 20, Filter{})
+
+			if got := matchIDs(matches); !slices.Equal(got, c.want) {
+				t.Fatalf("got %v, want %v", got, c.want)
+			}
+
+			for _, m := range matches {
+				if m.Distance > 10 {
+					t.Errorf("got %.1f km to %q, want less than 10 km", m.Distance, m.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexPoles(t *testing.T) {
+	idx := NewIndex()
+
+	idx.Add("north-0", []ttninjs.PlaceElem{pointPlace("ort", Point{Lat: 89.95, Lon: 0})})
+	idx.Add("north-180", []ttninjs.PlaceElem{pointPlace("ort", Point{Lat: 89.95, Lon: 180})})
+	idx.Add("north-90", []ttninjs.PlaceElem{pointPlace("ort", Point{Lat: 89.95, Lon: -90})})
+	idx.Add("south", []ttninjs.PlaceElem{pointPlace("ort", Point{Lat: -89.99, Lon: 45})})
+	idx.Add("svalbard", []ttninjs.PlaceElem{pointPlace("ort", Point{Lat: 78.22, Lon: 15.65})})
+
+	got := matchIDs(idx.WithinRadius(89.99, 90, 20, Filter{}))
+	slices.Sort(got)
+
+	if !slices.Equal(got, []string{"north-0", "north-180", "north-90"}) {
+		t.Errorf("got %v near the north pole", got)
+	}
+
+	got = matchIDs(idx.WithinRadius(90, 0, 10, Filter{}))
+	slices.Sort(got)
+
+	if !slices.Equal(got, []string{"north-0", "north-180", "north-90"}) {
+		t.Errorf("got %v at the north pole", got)
+	}
+
+	got = matchIDs(idx.WithinRadius(-90, -135, 5, Filter{}))
+	if !slices.Equal(got, []string{"south"}) {
+		t.Errorf("got %v at the south pole", got)
+	}
+
+	ids := idx.InBoundingBox(BoundingBox{South: 85, West: -180, North: 90, East: 180}, Filter{})
+	if !slices.Equal(ids, []string{"north-0", "north-180", "north-90"}) {
+		t.Errorf("got %v in the arctic box", ids)
+	}
+}
+
+func TestIndexCellSize(t *testing.T) {
+	docs := testDocuments()
+
+	for _, size := range []float64{-1, 0.01, 1, 45} {
+		idx := NewIndexWithCellSize(size)
+
+		for _, doc := range docs {
+			idx.AddDocument(&doc)
+		}
+
+		got := matchIDs(idx.WithinRadius(stockholm.Lat, stockholm.Lon, 70, Filter{}))
+		if !slices.Equal(got, []string{"stockholm", "uppsala"}) {
+			t.Errorf("cell size %v: got %v", size, got)
+		}
+	}
+}
+
+func TestIndexConcurrent(t *testing.T) {
+	idx := NewIndex()
+	rnd := rand.New(rand.NewSource(1))
+
+	var wg sync.WaitGroup
+
+	for w := range 4 {
+		lat, lon := rnd.Float64()*10+55, rnd.Float64()*10+10
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 100 {
+				id := fmt.Sprintf("%d-%d", w, i%10)
+
+				idx.Add(id, []ttninjs.PlaceElem{pointPlace("ort", Point{Lat: lat, Lon: lon})})
+				idx.WithinRadius(lat, lon, 10, Filter{})
+				idx.Containing(lat, lon, Filter{})
+
+				if i%3 == 0 {
+					idx.Remove(id)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if n := idx.Len(); n == 0 || n > 40 {
+		t.Errorf("got %d documents", n)
+	}
+}
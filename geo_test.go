@@ -0,0 +1,235 @@
+package ttninjs
+
+import (
+	"math"
+	"testing"
+)
+
+func square(west, south, east, north float64) []Position {
+	return []Position{
+		{west, south}, {east, south}, {east, north}, {west, north}, {west, south},
+	}
+}
+
+func TestPlaceLevel(t *testing.T) {
+	cases := map[string]PlaceLevel{
+		"land":     PlaceLevelCountry,
+		"Land":     PlaceLevelCountry,
+		"delstat":  PlaceLevelState,
+		"län":      PlaceLevelRegion,
+		"LÄN":      PlaceLevelRegion,
+		"landskap": PlaceLevelRegion,
+		" kommun ": PlaceLevelMunicipality,
+		"ort":      PlaceLevelLocality,
+		"city":     PlaceLevelLocality,
+		"capital":  PlaceLevelLocality,
+		"":         PlaceLevelUnknown,
+		"område":   PlaceLevelUnknown,
+	}
+
+	for rel, want := range cases {
+		if got := (PlaceElem{Rel: rel}).Level(); got != want {
+			t.Errorf("Level() for rel %q: got %d, want %d", rel, got, want)
+		}
+	}
+}
+
+func closeTo(p Position, lon, lat float64) bool {
+	return math.Abs(p.Lon()-lon) < 1e-9 && math.Abs(p.Lat()-lat) < 1e-9
+}
+
+func TestDocumentCentroid(t *testing.T) {
+	sweden := PlaceElem{
+		Rel:             "land",
+		GeometryGeojson: NewPolygon(square(11, 55, 24, 69)),
+	}
+	stockholm := PlaceElem{Rel: "ort", GeometryGeojson: NewPoint(18, 59)}
+	uppsala := PlaceElem{Rel: "city", GeometryGeojson: NewPoint(17, 60)}
+
+	cases := []struct {
+		name     string
+		places   []PlaceElem
+		ok       bool
+		lon, lat float64
+	}{
+		{name: "no places"},
+		{name: "no geometry", places: []PlaceElem{{Rel: "ort", Name: "Stockholm"}}},
+		{name: "country", places: []PlaceElem{sweden}, ok: true, lon: 17.5, lat: 62},
+		{
+			name:   "most specific level",
+			places: []PlaceElem{sweden, stockholm},
+			ok:     true, lon: 18, lat: 59,
+		},
+		{
+			name:   "mean of same level",
+			places: []PlaceElem{stockholm, sweden, uppsala},
+			ok:     true, lon: 17.5, lat: 59.5,
+		},
+		{
+			name: "unknown level as locality",
+			places: []PlaceElem{
+				sweden, {GeometryGeojson: NewPoint(16, 58)},
+			},
+			ok: true, lon: 16, lat: 58,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := Document{Uri: "a", Place: c.places}.Centroid()
+			if ok != c.ok {
+				t.Fatalf("got ok %v, want %v", ok, c.ok)
+			}
+
+			if ok && !closeTo(got, c.lon, c.lat) {
+				t.Errorf("got %v, want [%v %v]", got, c.lon, c.lat)
+			}
+		})
+	}
+}
+
+func TestGeometryCentroid(t *testing.T) {
+	// The rings of the polygons have opposite winding orders.
+	reversed := square(4, 0, 6, 2)
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+
+	cases := []struct {
+		name     string
+		g        *PlaceElemGeometryGeojson
+		ok       bool
+		lon, lat float64
+	}{
+		{name: "point", g: NewPoint(18.07, 59.33), ok: true, lon: 18.07, lat: 59.33},
+		{name: "empty point", g: &PlaceElemGeometryGeojson{Type: PlaceElemGeometryGeojsonTypePoint}},
+		{name: "polygon", g: NewPolygon(square(0, 0, 2, 2)), ok: true, lon: 1, lat: 1},
+		{
+			name: "area weighted multipolygon",
+			g: &PlaceElemGeometryGeojson{
+				Type: PlaceElemGeometryGeojsonTypeMultiPolygon,
+				Polygons: [][][]Position{
+					{square(0, 0, 2, 2)},
+					{square(4, 0, 8, 2)},
+				},
+			},
+			// Areas 4 and 8, centroids x=1 and x=6.
+			ok: true, lon: (4*1 + 8*6) / 12.0, lat: 1,
+		},
+		{
+			name: "winding order",
+			g: &PlaceElemGeometryGeojson{
+				Type: PlaceElemGeometryGeojsonTypeMultiPolygon,
+				Polygons: [][][]Position{
+					{square(0, 0, 2, 2)},
+					{reversed},
+				},
+			},
+			ok: true, lon: 3, lat: 1,
+		},
+		{
+			name: "degenerate polygon",
+			g:    NewPolygon([]Position{{1, 1}, {3, 3}, {1, 1}}),
+			ok:   true, lon: 5 / 3.0, lat: 5 / 3.0,
+		},
+		{
+			name: "linestring",
+			g: &PlaceElemGeometryGeojson{
+				Type:      PlaceElemGeometryGeojsonTypeLineString,
+				Positions: []Position{{0, 0}, {2, 4}},
+			},
+			ok: true, lon: 1, lat: 2,
+		},
+		{
+			name: "collection",
+			g: &PlaceElemGeometryGeojson{
+				Type: PlaceElemGeometryGeojsonTypeGeometryCollection,
+				Geometries: []PlaceElemGeometryGeojson{
+					*NewPoint(0, 0),
+					*NewPolygon(square(2, 2, 4, 4)),
+				},
+			},
+			ok: true, lon: 1.5, lat: 1.5,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := c.g.Centroid()
+			if ok != c.ok {
+				t.Fatalf("got ok %v, want %v", ok, c.ok)
+			}
+
+			if ok && !closeTo(got, c.lon, c.lat) {
+				t.Errorf("got %v, want [%v %v]", got, c.lon, c.lat)
+			}
+		})
+	}
+}
+
+func TestGeometryBounds(t *testing.T) {
+	g := &PlaceElemGeometryGeojson{
+		Type: PlaceElemGeometryGeojsonTypeGeometryCollection,
+		Geometries: []PlaceElemGeometryGeojson{
+			*NewPoint(-10, 70),
+			*NewPolygon(square(11, 55, 24, 69)),
+		},
+	}
+
+	sw, ne, ok := g.Bounds()
+	if !ok {
+		t.Fatal("expected bounds")
+	}
+
+	if !closeTo(sw, -10, 55) || !closeTo(ne, 24, 70) {
+		t.Errorf("got %v %v", sw, ne)
+	}
+
+	_, _, ok = (&PlaceElemGeometryGeojson{Type: PlaceElemGeometryGeojsonTypePolygon}).Bounds()
+	if ok {
+		t.Error("expected no bounds for an empty polygon")
+	}
+}
+
+func TestGeometryContains(t *testing.T) {
+	withHole := NewPolygon(square(0, 0, 10, 10), square(4, 4, 6, 6))
+	multi := &PlaceElemGeometryGeojson{
+		Type: PlaceElemGeometryGeojsonTypeMultiPolygon,
+		Polygons: [][][]Position{
+			{square(0, 0, 1, 1)},
+			{square(5, 5, 6, 6)},
+		},
+	}
+	collection := &PlaceElemGeometryGeojson{
+		Type: PlaceElemGeometryGeojsonTypeGeometryCollection,
+		Geometries: []PlaceElemGeometryGeojson{
+			*NewPoint(20, 20),
+			*withHole,
+		},
+	}
+
+	cases := []struct {
+		name     string
+		g        *PlaceElemGeometryGeojson
+		lon, lat float64
+		want     bool
+	}{
+		{"inside", withHole, 2, 2, true},
+		{"in hole", withHole, 5, 5, false},
+		{"outside", withHole, 11, 5, false},
+		{"first polygon", multi, 0.5, 0.5, true},
+		{"second polygon", multi, 5.5, 5.5, true},
+		{"between polygons", multi, 3, 3, false},
+		{"collection", collection, 2, 2, true},
+		{"collection hole", collection, 5, 5, false},
+		{"point", NewPoint(20, 20), 20, 20, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.g.Contains(c.lon, c.lat); got != c.want {
+				t.Errorf("Contains(%v, %v): got %v, want %v", c.lon, c.lat, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package ttninjs
+
+import (
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidGeometry is returned for geometries that aren't valid RFC 7946
+// GeoJSON.
+var ErrInvalidGeometry = errors.New("invalid geometry")
+
+// Position is a GeoJSON position: longitude, latitude and an optional
+// altitude.
+type Position []float64
+
+// Lon returns the longitude of the position.
+func (p Position) Lon() float64 {
+	if len(p) < 1 {
+		return 0
+	}
+
+	return p[0]
+}
+
+// Lat returns the latitude of the position.
+func (p Position) Lat() float64 {
+	if len(p) < 2 {
+		return 0
+	}
+
+	return p[1]
+}
+
+// NewPoint creates a Point geometry.
+func NewPoint(lon, lat float64) *PlaceElemGeometryGeojson {
+	return &PlaceElemGeometryGeojson{
+		Type:        PlaceElemGeometryGeojsonTypePoint,
+		Coordinates: []float64{lon, lat},
+	}
+}
+
+// NewPolygon creates a Polygon geometry from linear rings, the exterior ring
+// first.
+func NewPolygon(rings ...[]Position) *PlaceElemGeometryGeojson {
+	return &PlaceElemGeometryGeojson{
+		Type:  PlaceElemGeometryGeojsonTypePolygon,
+		Lines: rings,
+	}
+}
+
+// geojsonGeometry is the JSON representation of a geometry.
+type geojsonGeometry struct {
+	Type        PlaceElemGeometryGeojsonType `json:"type,omitempty"`
+	Coordinates stdjson.RawMessage           `json:"coordinates,omitempty"`
+	Geometries  []PlaceElemGeometryGeojson   `json:"geometries,omitempty"`
+}
+
+// coordinates returns the coordinates field matching the type of the
+// geometry, or nil if it has none.
+func (g PlaceElemGeometryGeojson) coordinates() any {
+	switch g.Type {
+	case PlaceElemGeometryGeojsonTypeMultiPoint, PlaceElemGeometryGeojsonTypeLineString:
+		return g.Positions
+	case PlaceElemGeometryGeojsonTypeMultiLineString, PlaceElemGeometryGeojsonTypePolygon:
+		return g.Lines
+	case PlaceElemGeometryGeojsonTypeMultiPolygon:
+		return g.Polygons
+	case PlaceElemGeometryGeojsonTypeGeometryCollection:
+		return nil
+	}
+
+	if len(g.Coordinates) > 0 {
+		return g.Coordinates
+	}
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (g PlaceElemGeometryGeojson) MarshalJSON() ([]byte, error) {
+	coordinates := g.coordinates()
+
+	out := geojsonGeometry{
+		Type:       g.Type,
+		Geometries: g.Geometries,
+	}
+
+	if coordinates != nil {
+		data, err := json.Marshal(coordinates)
+		if err != nil {
+			return nil, err
+		}
+
+		out.Coordinates = data
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. For backwards compatibility
+// points without a type and points with their position wrapped in an array,
+// "[[lon, lat]]", are accepted.
+func (g *PlaceElemGeometryGeojson) UnmarshalJSON(data []byte) error {
+	var raw geojsonGeometry
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	*g = PlaceElemGeometryGeojson{
+		Type:       raw.Type,
+		Geometries: raw.Geometries,
+	}
+
+	if len(raw.Coordinates) == 0 || string(raw.Coordinates) == "null" {
+		return nil
+	}
+
+	switch raw.Type {
+	case PlaceElemGeometryGeojsonTypeMultiPoint, PlaceElemGeometryGeojsonTypeLineString:
+		err = json.Unmarshal(raw.Coordinates, &g.Positions)
+	case PlaceElemGeometryGeojsonTypeMultiLineString, PlaceElemGeometryGeojsonTypePolygon:
+		err = json.Unmarshal(raw.Coordinates, &g.Lines)
+	case PlaceElemGeometryGeojsonTypeMultiPolygon:
+		err = json.Unmarshal(raw.Coordinates, &g.Polygons)
+	case PlaceElemGeometryGeojsonTypeGeometryCollection:
+		return fmt.Errorf("%w: a GeometryCollection has no coordinates",
+			ErrInvalidGeometry)
+	default:
+		err = json.Unmarshal(raw.Coordinates, &g.Coordinates)
+		if err != nil {
+			var wrapped []Position
+
+			if json.Unmarshal(raw.Coordinates, &wrapped) == nil && len(wrapped) == 1 {
+				g.Coordinates, err = wrapped[0], nil
+			}
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: coordinates of %s: %v",
+			ErrInvalidGeometry, g.geometryType(), err)
+	}
+
+	return nil
+}
+
+// geojsonGeometryYAML is the YAML form of a geometry, with the same members
+// as the JSON form.
+type geojsonGeometryYAML struct {
+	Type        PlaceElemGeometryGeojsonType `yaml:"type,omitempty"`
+	Coordinates any                          `yaml:"coordinates,omitempty"`
+	Geometries  []PlaceElemGeometryGeojson   `yaml:"geometries,omitempty"`
+}
+
+// MarshalYAML implements the yaml.Marshaler interface of gopkg.in/yaml.v2 and
+// v3, so that geometries have the same form in YAML as in JSON.
+func (g PlaceElemGeometryGeojson) MarshalYAML() (any, error) {
+	return geojsonGeometryYAML{
+		Type:        g.Type,
+		Coordinates: g.coordinates(),
+		Geometries:  g.Geometries,
+	}, nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface of gopkg.in/yaml.v2,
+// which v3 also supports. The coordinates are decoded like in UnmarshalJSON.
+func (g *PlaceElemGeometryGeojson) UnmarshalYAML(unmarshal func(any) error) error {
+	var raw geojsonGeometryYAML
+
+	err := unmarshal(&raw)
+	if err != nil {
+		return err
+	}
+
+	coordinates, err := json.Marshal(raw.Coordinates)
+	if err != nil {
+		return fmt.Errorf("%w: coordinates of %s: %v",
+			ErrInvalidGeometry, raw.Type, err)
+	}
+
+	data, err := json.Marshal(geojsonGeometry{
+		Type:        raw.Type,
+		Coordinates: coordinates,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = g.UnmarshalJSON(data)
+	if err != nil {
+		return err
+	}
+
+	g.Geometries = raw.Geometries
+
+	return nil
+}
+
+func (g *PlaceElemGeometryGeojson) geometryType() PlaceElemGeometryGeojsonType {
+	if g.Type == "" {
+		return PlaceElemGeometryGeojsonTypePoint
+	}
+
+	return g.Type
+}
+
+// Validate checks that the geometry is valid according to RFC 7946: that
+// positions have a longitude between -180 and 180 and a latitude between -90
+// and 90, that line strings have at least two positions, that polygon rings
+// are closed and have at least four positions, and that multi geometries have
+// at least one member. A geometry without a type is validated as a Point.
+func (g *PlaceElemGeometryGeojson) Validate() error {
+	switch g.geometryType() {
+	case PlaceElemGeometryGeojsonTypePoint:
+		return validatePosition(g.Coordinates, "point")
+	case PlaceElemGeometryGeojsonTypeMultiPoint:
+		return validatePositions(g.Positions, 1, "multipoint")
+	case PlaceElemGeometryGeojsonTypeLineString:
+		return validatePositions(g.Positions, 2, "linestring")
+	case PlaceElemGeometryGeojsonTypeMultiLineString:
+		if len(g.Lines) == 0 {
+			return fmt.Errorf("%w: multilinestring has no lines", ErrInvalidGeometry)
+		}
+
+		for i, line := range g.Lines {
+			err := validatePositions(line, 2, fmt.Sprintf("line %d", i))
+			if err != nil {
+				return err
+			}
+		}
+	case PlaceElemGeometryGeojsonTypePolygon:
+		return validatePolygon(g.Lines, "polygon")
+	case PlaceElemGeometryGeojsonTypeMultiPolygon:
+		if len(g.Polygons) == 0 {
+			return fmt.Errorf("%w: multipolygon has no polygons", ErrInvalidGeometry)
+		}
+
+		for i, p := range g.Polygons {
+			err := validatePolygon(p, fmt.Sprintf("polygon %d", i))
+			if err != nil {
+				return err
+			}
+		}
+	case PlaceElemGeometryGeojsonTypeGeometryCollection:
+		for i := range g.Geometries {
+			err := g.Geometries[i].Validate()
+			if err != nil {
+				return fmt.Errorf("geometry %d: %w", i, err)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidGeometry, g.Type)
+	}
+
+	return nil
+}
+
+func validatePosition(p Position, what string) error {
+	switch {
+	case len(p) < 2 || len(p) > 3:
+		return fmt.Errorf("%w: %s has %d coordinates, expected 2 or 3",
+			ErrInvalidGeometry, what, len(p))
+	case p.Lon() < -180 || p.Lon() > 180:
+		return fmt.Errorf("%w: %s has longitude %v outside of -180 to 180",
+			ErrInvalidGeometry, what, p.Lon())
+	case p.Lat() < -90 || p.Lat() > 90:
+		return fmt.Errorf("%w: %s has latitude %v outside of -90 to 90",
+			ErrInvalidGeometry, what, p.Lat())
+	}
+
+	return nil
+}
+
+func validatePositions(positions []Position, minimum int, what string) error {
+	if len(positions) < minimum {
+		return fmt.Errorf("%w: %s has %d positions, expected at least %d",
+			ErrInvalidGeometry, what, len(positions), minimum)
+	}
+
+	for i, p := range positions {
+		err := validatePosition(p, fmt.Sprintf("%s position %d", what, i))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validatePolygon(rings [][]Position, what string) error {
+	if len(rings) == 0 {
+		return fmt.Errorf("%w: %s has no rings", ErrInvalidGeometry, what)
+	}
+
+	for i, ring := range rings {
+		name := fmt.Sprintf("%s ring %d", what, i)
+
+		err := validatePositions(ring, 4, name)
+		if err != nil {
+			return err
+		}
+
+		first, last := ring[0], ring[len(ring)-1]
+
+		if first.Lon() != last.Lon() || first.Lat() != last.Lat() {
+			return fmt.Errorf("%w: %s is not closed", ErrInvalidGeometry, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package ttninjs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGeometryYAML(t *testing.T) {
+	geometries := []*PlaceElemGeometryGeojson{
+		NewPoint(18.07, 59.33),
+		NewPolygon([]Position{{18, 59}, {19, 59}, {19, 60}, {18, 59}}),
+		{
+			Type:      PlaceElemGeometryGeojsonTypeLineString,
+			Positions: []Position{{18, 59}, {19, 60}},
+		},
+		{
+			Type:       PlaceElemGeometryGeojsonTypeGeometryCollection,
+			Geometries: []PlaceElemGeometryGeojson{*NewPoint(18.07, 59.33)},
+		},
+	}
+
+	for _, g := range geometries {
+		t.Run(string(g.Type), func(t *testing.T) {
+			v, err := g.MarshalYAML()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			y := v.(geojsonGeometryYAML)
+
+			if !reflect.DeepEqual(y.Coordinates, g.coordinates()) {
+				t.Errorf("got coordinates %v, expected %v", y.Coordinates, g.coordinates())
+			}
+
+			// Decode the marshalled value through JSON, as a stand-in
+			// for a YAML library.
+			data, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got PlaceElemGeometryGeojson
+
+			err = got.UnmarshalYAML(func(v any) error {
+				return json.Unmarshal(data, v)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(&got, g) {
+				t.Errorf("got %#v, expected %#v", got, *g)
+			}
+		})
+	}
+}
+
+func TestGeometryTags(t *testing.T) {
+	typ := reflect.TypeOf(PlaceElemGeometryGeojson{})
+
+	for _, name := range []string{"Positions", "Lines", "Polygons"} {
+		f, _ := typ.FieldByName(name)
+
+		for _, tag := range []string{"json", "yaml", "mapstructure"} {
+			if v := f.Tag.Get(tag); v != "-" {
+				t.Errorf("%s has %s tag %q, expected \"-\"", name, tag, v)
+			}
+		}
+	}
+}
+
+func TestGeometryJSON(t *testing.T) {
+	cases := []struct {
+		name     string
+		geometry *PlaceElemGeometryGeojson
+		want     string
+	}{
+		{
+			name:     "point",
+			geometry: NewPoint(18.07, 59.33),
+			want:     `{"type":"Point","coordinates":[18.07,59.33]}`,
+		},
+		{
+			name: "multipoint",
+			geometry: &PlaceElemGeometryGeojson{
+				Type:      PlaceElemGeometryGeojsonTypeMultiPoint,
+				Positions: []Position{{18, 59}, {19, 60, 12}},
+			},
+			want: `{"type":"MultiPoint","coordinates":[[18,59],[19,60,12]]}`,
+		},
+		{
+			name: "multilinestring",
+			geometry: &PlaceElemGeometryGeojson{
+				Type:  PlaceElemGeometryGeojsonTypeMultiLineString,
+				Lines: [][]Position{{{18, 59}, {19, 60}}, {{11, 57}, {12, 58}}},
+			},
+			want: `{"type":"MultiLineString","coordinates":[[[18,59],[19,60]],[[11,57],[12,58]]]}`,
+		},
+		{
+			name:     "polygon",
+			geometry: NewPolygon([]Position{{18, 59}, {19, 59}, {19, 60}, {18, 59}}),
+			want:     `{"type":"Polygon","coordinates":[[[18,59],[19,59],[19,60],[18,59]]]}`,
+		},
+		{
+			name: "multipolygon",
+			geometry: &PlaceElemGeometryGeojson{
+				Type: PlaceElemGeometryGeojsonTypeMultiPolygon,
+				Polygons: [][][]Position{
+					{{{18, 59}, {19, 59}, {19, 60}, {18, 59}}},
+				},
+			},
+			want: `{"type":"MultiPolygon","coordinates":[[[[18,59],[19,59],[19,60],[18,59]]]]}`,
+		},
+		{
+			name: "collection",
+			geometry: &PlaceElemGeometryGeojson{
+				Type: PlaceElemGeometryGeojsonTypeGeometryCollection,
+				Geometries: []PlaceElemGeometryGeojson{
+					*NewPoint(18.07, 59.33),
+					{
+						Type:      PlaceElemGeometryGeojsonTypeLineString,
+						Positions: []Position{{18, 59}, {19, 60}},
+					},
+				},
+			},
+			want: `{"type":"GeometryCollection","geometries":[` +
+				`{"type":"Point","coordinates":[18.07,59.33]},` +
+				`{"type":"LineString","coordinates":[[18,59],[19,60]]}]}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := json.Marshal(c.geometry)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != c.want {
+				t.Errorf("got\n%s\nwant\n%s", data, c.want)
+			}
+
+			var got PlaceElemGeometryGeojson
+
+			err = json.Unmarshal(data, &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(&got, c.geometry) {
+				t.Errorf("got %#v, expected %#v", got, *c.geometry)
+			}
+		})
+	}
+}
+
+func TestGeometryLegacyJSON(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want PlaceElemGeometryGeojson
+	}{
+		{
+			name: "wrapped point",
+			data: `{"type":"Point","coordinates":[[18.07,59.33]]}`,
+			want: *NewPoint(18.07, 59.33),
+		},
+		{
+			name: "point without type",
+			data: `{"coordinates":[18.07,59.33]}`,
+			want: PlaceElemGeometryGeojson{Coordinates: []float64{18.07, 59.33}},
+		},
+		{
+			name: "wrapped point without type",
+			data: `{"coordinates":[[18.07,59.33,12]]}`,
+			want: PlaceElemGeometryGeojson{Coordinates: []float64{18.07, 59.33, 12}},
+		},
+		{
+			name: "null coordinates",
+			data: `{"type":"Point","coordinates":null}`,
+			want: PlaceElemGeometryGeojson{Type: PlaceElemGeometryGeojsonTypePoint},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got PlaceElemGeometryGeojson
+
+			err := json.Unmarshal([]byte(c.data), &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %#v, expected %#v", got, c.want)
+			}
+		})
+	}
+
+	invalid := map[string]string{
+		"several wrapped positions": `{"type":"Point","coordinates":[[18,59],[19,60]]}`,
+		"collection coordinates":    `{"type":"GeometryCollection","coordinates":[18,59]}`,
+		"polygon positions":         `{"type":"Polygon","coordinates":[[18,59],[19,60]]}`,
+	}
+
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			var got PlaceElemGeometryGeojson
+
+			// Called directly, as jsoniter doesn't wrap the error.
+			err := got.UnmarshalJSON([]byte(data))
+			if !errors.Is(err, ErrInvalidGeometry) {
+				t.Errorf("got %v, expected ErrInvalidGeometry", err)
+			}
+		})
+	}
+}
+
+func TestGeometryValidate(t *testing.T) {
+	square := []Position{{18, 59}, {19, 59}, {19, 60}, {18, 60}, {18, 59}}
+
+	cases := []struct {
+		name     string
+		geometry PlaceElemGeometryGeojson
+		valid    bool
+	}{
+		{"point", *NewPoint(18.07, 59.33), true},
+		{"point with altitude", PlaceElemGeometryGeojson{Coordinates: []float64{18, 59, 12}}, true},
+		{"point on the edges", *NewPoint(-180, 90), true},
+		{"longitude out of range", *NewPoint(180.5, 59), false},
+		{"latitude out of range", *NewPoint(18, -90.5), false},
+		{"swapped coordinates", *NewPoint(59.33, 118.07), false},
+		{"point without coordinates", PlaceElemGeometryGeojson{}, false},
+		{"point with four coordinates", PlaceElemGeometryGeojson{Coordinates: []float64{1, 2, 3, 4}}, false},
+		{"polygon", *NewPolygon(square), true},
+		{"unclosed ring", *NewPolygon(square[:4]), false},
+		{"short ring", *NewPolygon([]Position{{18, 59}, {19, 60}, {18, 59}}), false},
+		{"unclosed hole", *NewPolygon(square, []Position{
+			{18.2, 59.2}, {18.4, 59.2}, {18.4, 59.4}, {18.2, 59.4},
+		}), false},
+		{"polygon without rings", PlaceElemGeometryGeojson{Type: PlaceElemGeometryGeojsonTypePolygon}, false},
+		{"linestring", PlaceElemGeometryGeojson{
+			Type: PlaceElemGeometryGeojsonTypeLineString, Positions: square[:2],
+		}, true},
+		{"short linestring", PlaceElemGeometryGeojson{
+			Type: PlaceElemGeometryGeojsonTypeLineString, Positions: square[:1],
+		}, false},
+		{"multipoint out of range", PlaceElemGeometryGeojson{
+			Type: PlaceElemGeometryGeojsonTypeMultiPoint, Positions: []Position{{18, 59}, {200, 59}},
+		}, false},
+		{"empty multipoint", PlaceElemGeometryGeojson{Type: PlaceElemGeometryGeojsonTypeMultiPoint}, false},
+		{"multilinestring", PlaceElemGeometryGeojson{
+			Type: PlaceElemGeometryGeojsonTypeMultiLineString, Lines: [][]Position{square[:2]},
+		}, true},
+		{"empty multilinestring", PlaceElemGeometryGeojson{Type: PlaceElemGeometryGeojsonTypeMultiLineString}, false},
+		{"multipolygon", PlaceElemGeometryGeojson{
+			Type: PlaceElemGeometryGeojsonTypeMultiPolygon, Polygons: [][][]Position{{square}},
+		}, true},
+		{"multipolygon with unclosed ring", PlaceElemGeometryGeojson{
+			Type: PlaceElemGeometryGeojsonTypeMultiPolygon, Polygons: [][][]Position{{square}, {square[:4]}},
+		}, false},
+		{"empty multipolygon", PlaceElemGeometryGeojson{Type: PlaceElemGeometryGeojsonTypeMultiPolygon}, false},
+		{"collection", PlaceElemGeometryGeojson{
+			Type:       PlaceElemGeometryGeojsonTypeGeometryCollection,
+			Geometries: []PlaceElemGeometryGeojson{*NewPoint(18, 59), *NewPolygon(square)},
+		}, true},
+		{"collection with invalid geometry", PlaceElemGeometryGeojson{
+			Type:       PlaceElemGeometryGeojsonTypeGeometryCollection,
+			Geometries: []PlaceElemGeometryGeojson{*NewPoint(18, 59), *NewPoint(18, 95)},
+		}, false},
+		{"unknown type", PlaceElemGeometryGeojson{Type: "Circle"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.geometry.Validate()
+
+			switch {
+			case c.valid && err != nil:
+				t.Errorf("got %v", err)
+			case !c.valid && !errors.Is(err, ErrInvalidGeometry):
+				t.Errorf("got %v, expected ErrInvalidGeometry", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+package ttninjs
+
+import (
+	"html"
+	"strings"
+)
+
+// The HTML support in this file is a small parser for the well-formed HTML5
+// fragments used in body_html5 and body_richhtml5. It isn't a full HTML5
+// parser, unknown end tags are ignored and unclosed elements are closed when an
+// ancestor is closed.
+
+type htmlNodeType int
+
+const (
+	htmlDocumentNode htmlNodeType = iota
+	htmlElementNode
+	htmlTextNode
+	htmlCommentNode
+)
+
+type htmlAttr struct {
+	Key string
+	Val string
+}
+
+type htmlNode struct {
+	Type htmlNodeType
+	// Tag is the lowercased tag name of elements.
+	Tag   string
+	Attrs []htmlAttr
+	// Text is the unescaped text of text and comment nodes.
+	Text     string
+	Parent   *htmlNode
+	Children []*htmlNode
+}
+
+func (n *htmlNode) appendChild(c *htmlNode) {
+	c.Parent = n
+	n.Children = append(n.Children, c)
+}
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+var rawTextElements = map[string]bool{
+	"script": true, "style": true,
+}
+
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"body": true, "dd": true, "div": true, "dl": true, "dt": true,
+	"figcaption": true, "figure": true, "footer": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "li": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "table": true,
+	"tr": true, "ul": true,
+}
+
+// parseHTML parses an HTML fragment into a tree.
+func parseHTML(src string) *htmlNode {
+	root := &htmlNode{Type: htmlDocumentNode}
+	current := root
+
+	pos := 0
+
+	for pos < len(src) {
+		lt := strings.IndexByte(src[pos:], '<')
+		if lt == -1 {
+			current.appendChild(textNode(src[pos:]))
+
+			break
+		}
+
+		if lt > 0 {
+			current.appendChild(textNode(src[pos : pos+lt]))
+			pos += lt
+		}
+
+		rest := src[pos:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end == -1 {
+				current.appendChild(&htmlNode{Type: htmlCommentNode, Text: rest[4:]})
+				pos = len(src)
+
+				continue
+			}
+
+			current.appendChild(&htmlNode{Type: htmlCommentNode, Text: rest[4 : 4+end]})
+			pos += 4 + end + 3
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			// Doctype or processing instruction, ignored.
+			end := strings.IndexByte(rest, '>')
+			if end == -1 {
+				pos = len(src)
+
+				continue
+			}
+
+			pos += end + 1
+		case strings.HasPrefix(rest, "</"):
+			name, _ := scanTagName(rest[2:])
+			end := strings.IndexByte(rest, '>')
+
+			if name == "" || end == -1 {
+				current.appendChild(textNode(rest[:1]))
+				pos++
+
+				continue
+			}
+
+			pos += end + 1
+
+			// Close the innermost open element with the name,
+			// ignoring stray end tags.
+			for n := current; n != root; n = n.Parent {
+				if n.Tag == name {
+					current = n.Parent
+
+					break
+				}
+			}
+		default:
+			name, _ := scanTagName(rest[1:])
+			if name == "" {
+				current.appendChild(textNode(rest[:1]))
+				pos++
+
+				continue
+			}
+
+			el, selfClosing, n := scanStartTag(rest)
+			pos += n
+
+			current.appendChild(el)
+
+			if rawTextElements[el.Tag] && !selfClosing {
+				closing := "</" + el.Tag
+				end := strings.Index(strings.ToLower(src[pos:]), closing)
+
+				if end == -1 {
+					end = len(src) - pos
+				}
+
+				if end > 0 {
+					el.appendChild(&htmlNode{Type: htmlTextNode, Text: src[pos : pos+end]})
+				}
+
+				pos += end
+
+				if gt := strings.IndexByte(src[pos:], '>'); gt != -1 {
+					pos += gt + 1
+				}
+
+				continue
+			}
+
+			if !voidElements[el.Tag] && !selfClosing {
+				current = el
+			}
+		}
+	}
+
+	return root
+}
+
+func textNode(raw string) *htmlNode {
+	return &htmlNode{Type: htmlTextNode, Text: html.UnescapeString(raw)}
+}
+
+func isNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+		c >= '0' && c <= '9' || c == '-' || c == ':' || c == '_'
+}
+
+// scanTagName returns the lowercased tag name at the start of s and its length.
+func scanTagName(s string) (string, int) {
+	if s == "" || !(s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z') {
+		return "", 0
+	}
+
+	n := 0
+	for n < len(s) && isNameByte(s[n]) {
+		n++
+	}
+
+	return strings.ToLower(s[:n]), n
+}
+
+// scanStartTag scans a start tag at the start of s and returns the element,
+// whether it was self-closing and the number of bytes consumed.
+func scanStartTag(s string) (*htmlNode, bool, int) {
+	name, n := scanTagName(s[1:])
+	el := &htmlNode{Type: htmlElementNode, Tag: name}
+	pos := 1 + n
+
+	skipSpace := func() {
+		for pos < len(s) && isSpace(s[pos]) {
+			pos++
+		}
+	}
+
+	for {
+		skipSpace()
+
+		if pos >= len(s) {
+			return el, false, pos
+		}
+
+		switch {
+		case s[pos] == '>':
+			return el, false, pos + 1
+		case strings.HasPrefix(s[pos:], "/>"):
+			return el, true, pos + 2
+		case s[pos] == '/':
+			pos++
+
+			continue
+		}
+
+		start := pos
+		for pos < len(s) && !isSpace(s[pos]) && s[pos] != '=' && s[pos] != '>' &&
+			!strings.HasPrefix(s[pos:], "/>") {
+			pos++
+		}
+
+		key := strings.ToLower(s[start:pos])
+
+		skipSpace()
+
+		if pos >= len(s) || s[pos] != '=' {
+			el.Attrs = append(el.Attrs, htmlAttr{Key: key})
+
+			continue
+		}
+
+		pos++
+		skipSpace()
+
+		var val string
+
+		if pos < len(s) && (s[pos] == '"' || s[pos] == '\'') {
+			q := s[pos]
+			end := strings.IndexByte(s[pos+1:], q)
+
+			if end == -1 {
+				val = s[pos+1:]
+				pos = len(s)
+			} else {
+				val = s[pos+1 : pos+1+end]
+				pos += end + 2
+			}
+		} else {
+			start := pos
+			for pos < len(s) && !isSpace(s[pos]) && s[pos] != '>' {
+				pos++
+			}
+
+			val = s[start:pos]
+		}
+
+		el.Attrs = append(el.Attrs, htmlAttr{Key: key, Val: html.UnescapeString(val)})
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func (n *htmlNode) attr(key string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+
+	return "", false
+}
+
+func (n *htmlNode) setAttr(key, val string) {
+	for i := range n.Attrs {
+		if n.Attrs[i].Key == key {
+			n.Attrs[i].Val = val
+
+			return
+		}
+	}
+
+	n.Attrs = append(n.Attrs, htmlAttr{Key: key, Val: val})
+}
+
+// renderHTML serialises the children of a node.
+func renderHTML(n *htmlNode) string {
+	var b strings.Builder
+
+	for _, c := range n.Children {
+		writeHTML(&b, c)
+	}
+
+	return b.String()
+}
+
+func writeHTML(b *strings.Builder, n *htmlNode) {
+	switch n.Type {
+	case htmlDocumentNode:
+		for _, c := range n.Children {
+			writeHTML(b, c)
+		}
+	case htmlTextNode:
+		if n.Parent != nil && rawTextElements[n.Parent.Tag] {
+			b.WriteString(n.Text)
+		} else {
+			b.WriteString(escapeHTMLText(n.Text))
+		}
+	case htmlCommentNode:
+		b.WriteString("<!--")
+		b.WriteString(n.Text)
+		b.WriteString("-->")
+	case htmlElementNode:
+		writeHTMLStartTag(b, n)
+
+		if voidElements[n.Tag] {
+			return
+		}
+
+		for _, c := range n.Children {
+			writeHTML(b, c)
+		}
+
+		b.WriteString("</")
+		b.WriteString(n.Tag)
+		b.WriteByte('>')
+	}
+}
+
+// writeHTMLStartTag writes the start tag of an element with its attributes.
+func writeHTMLStartTag(b *strings.Builder, n *htmlNode) {
+	b.WriteByte('<')
+	b.WriteString(n.Tag)
+
+	for _, a := range n.Attrs {
+		writeAttr(b, a.Key, a.Val)
+	}
+
+	b.WriteByte('>')
+}
+
+func writeAttr(b *strings.Builder, key, val string) {
+	b.WriteByte(' ')
+	b.WriteString(key)
+	b.WriteString(`="`)
+	b.WriteString(html.EscapeString(val))
+	b.WriteByte('"')
+}
+
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeHTMLText escapes text content, leaving quotes as they are.
+func escapeHTMLText(s string) string {
+	return textEscaper.Replace(s)
+}
@@ -0,0 +1,989 @@
+package ttninjs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrMissingURI is returned for documents without a URI where one is needed,
+// f.ex. by ToICS as the URI is used as the UID of the event, and by
+// AssignmentURI.
+var ErrMissingURI = errors.New("document has no uri")
+
+// ErrMissingStart is returned by ToICS for documents without a start date or
+// time.
+var ErrMissingStart = errors.New("document has no date or datetime")
+
+const (
+	icsDateFormat     = "20060102"
+	icsDateTimeFormat = "20060102T150405"
+)
+
+// ToICS renders event and planning documents as an RFC 5545 calendar with one
+// VEVENT per document.
+//
+// The UID of an event is the URI of the document. Documents with Datetime
+// become events with a start time in UTC, documents with only Date become
+// all-day events. DTSTAMP is the version time of the document, or its start
+// if it has none, so that the output only depends on the documents.
+// Location, organizer, status and URL are taken from BodyEvent. The arena,
+// address and city are joined in LOCATION, and also written as X-TT-ARENA,
+// X-TT-ADDRESS and X-TT-CITY so that ParseICS can restore them.
+func ToICS(docs []Document) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := icsWriter{w: &buf}
+
+	w.line("BEGIN", nil, "VCALENDAR")
+	w.line("VERSION", nil, "2.0")
+	w.line("PRODID", nil, "-//TT//ttninjs//SV")
+	w.line("CALSCALE", nil, "GREGORIAN")
+
+	for i := range docs {
+		err := w.event(&docs[i])
+		if err != nil {
+			return nil, fmt.Errorf("document %d (%s): %w", i, docs[i].Uri, err)
+		}
+	}
+
+	w.line("END", nil, "VCALENDAR")
+
+	return buf.Bytes(), nil
+}
+
+type icsWriter struct {
+	w *bytes.Buffer
+}
+
+func (w *icsWriter) event(doc *Document) error {
+	if doc.Uri == "" {
+		return ErrMissingURI
+	}
+
+	if doc.Datetime == nil && doc.Date == nil {
+		return ErrMissingStart
+	}
+
+	w.line("BEGIN", nil, "VEVENT")
+	w.line("UID", nil, doc.Uri)
+
+	stamp := doc.Versioncreated
+
+	switch {
+	case !stamp.IsZero():
+	case doc.Firstcreated != nil && !doc.Firstcreated.IsZero():
+		stamp = *doc.Firstcreated
+	case doc.Datetime != nil:
+		stamp = *doc.Datetime
+	default:
+		stamp = doc.Date.Time
+	}
+
+	w.line("DTSTAMP", nil, icsUTC(stamp))
+
+	if !doc.Versioncreated.IsZero() {
+		w.line("LAST-MODIFIED", nil, icsUTC(doc.Versioncreated))
+	}
+
+	if doc.Datetime != nil {
+		w.line("DTSTART", nil, icsUTC(*doc.Datetime))
+
+		if doc.Enddatetime != nil {
+			w.line("DTEND", nil, icsUTC(*doc.Enddatetime))
+		}
+	} else {
+		end := doc.Date.Time
+		if doc.Enddate != nil {
+			end = doc.Enddate.Time
+		}
+
+		// The end date of all-day events is exclusive.
+		date := []string{"VALUE", "DATE"}
+
+		w.line("DTSTART", date, doc.Date.Format(icsDateFormat))
+		w.line("DTEND", date, end.AddDate(0, 0, 1).Format(icsDateFormat))
+	}
+
+	w.text("SUMMARY", nil, doc.Headline)
+
+	description := doc.BodyText
+	if description == "" {
+		description = ExtractText(doc)
+	}
+
+	w.text("DESCRIPTION", nil, description)
+
+	ev := doc.BodyEvent
+	if ev == nil {
+		ev = &BodyEvent{}
+	}
+
+	var location []string
+
+	for _, s := range []string{ev.Arena, ev.Address, ev.City} {
+		if s != "" {
+			location = append(location, s)
+		}
+	}
+
+	w.text("LOCATION", nil, strings.Join(location, ", "))
+	w.text("X-TT-ARENA", nil, ev.Arena)
+	w.text("X-TT-ADDRESS", nil, ev.Address)
+	w.text("X-TT-CITY", nil, ev.City)
+
+	if ev.Organizermail != "" {
+		var params []string
+
+		if ev.Organizer != "" {
+			params = []string{"CN", ev.Organizer}
+		}
+
+		w.line("ORGANIZER", params, "mailto:"+ev.Organizermail)
+	}
+
+	var contact []string
+
+	for _, s := range []string{ev.Organizer, ev.Organizerphone, ev.Organizerurl} {
+		if s != "" {
+			contact = append(contact, s)
+		}
+	}
+
+	w.text("CONTACT", nil, strings.Join(contact, ", "))
+
+	switch {
+	case ev.IsCancelled():
+		w.line("STATUS", nil, "CANCELLED")
+	case ev.Status() == EventStatusPlanned:
+		w.line("STATUS", nil, "CONFIRMED")
+	case ev.Eventstatus != "":
+		w.line("STATUS", nil, "TENTATIVE")
+	}
+
+	if ev.Eventurl != "" {
+		w.line("URL", []string{"VALUE", "URI"}, ev.Eventurl)
+	}
+
+	w.text("CATEGORIES", nil, ev.EventtypeText)
+
+	w.line("END", nil, "VEVENT")
+
+	return nil
+}
+
+func icsUTC(t time.Time) string {
+	return t.UTC().Format(icsDateTimeFormat) + "Z"
+}
+
+var icsTextEscaper = strings.NewReplacer(
+	`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`,
+)
+
+// text writes a property with a TEXT value, leaving out empty values.
+func (w *icsWriter) text(name string, params []string, value string) {
+	if value == "" {
+		return
+	}
+
+	w.line(name, params, icsTextEscaper.Replace(value))
+}
+
+// line writes a content line folded at 75 octets, params are key value pairs.
+// Control characters, like CR and LF, are removed from params and value, so
+// that they can't start new properties.
+func (w *icsWriter) line(name string, params []string, value string) {
+	var b strings.Builder
+
+	b.WriteString(name)
+
+	for i := 0; i+1 < len(params); i += 2 {
+		param := stripICSControls(params[i+1])
+
+		b.WriteByte(';')
+		b.WriteString(params[i])
+		b.WriteByte('=')
+
+		if strings.ContainsAny(param, ";:,") {
+			b.WriteString(`"` + strings.ReplaceAll(param, `"`, "'") + `"`)
+		} else {
+			b.WriteString(param)
+		}
+	}
+
+	b.WriteByte(':')
+	b.WriteString(stripICSControls(value))
+
+	line := b.String()
+	limit := 75
+
+	for len(line) > limit {
+		// Don't split multi-byte characters.
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		w.w.WriteString(line[:cut])
+		w.w.WriteString("\r\n ")
+		line = line[cut:]
+
+		// Continuation lines start with a space.
+		limit = 74
+	}
+
+	w.w.WriteString(line)
+	w.w.WriteString("\r\n")
+}
+
+// stripICSControls removes the control characters, except tab, that RFC 5545
+// doesn't allow in values.
+func stripICSControls(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r < 0x20 && r != '\t') || r == 0x7f {
+			return -1
+		}
+
+		return r
+	}, s)
+}
+
+// ICSError is returned by ParseICS for invalid calendars.
+type ICSError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ICSError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+type icsProperty struct {
+	line   int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ICSOption configures ParseICS.
+type ICSOption func(p *icsParser)
+
+// WithICSLocation sets the time zone that floating date-times, and date-times
+// with a TZID that can't be resolved, are interpreted in. Defaults to UTC.
+func WithICSLocation(loc *time.Location) ICSOption {
+	return func(p *icsParser) {
+		if loc != nil {
+			p.loc = loc
+		}
+	}
+}
+
+type icsParser struct {
+	loc   *time.Location
+	zones map[string]*icsTimeZone
+}
+
+// ParseICS parses the VEVENTs of an RFC 5545 calendar into event documents.
+//
+// Date-times in UTC or with a TZID are converted to absolute times. A TZID is
+// resolved as an IANA time zone, a Windows time zone name, like "W. Europe
+// Standard Time", or through the VTIMEZONE of the calendar that defines it.
+// Floating date-times, and date-times with a TZID that can't be resolved, are
+// interpreted in the time zone set by WithICSLocation. All-day events get Date
+// and Enddate, with the exclusive DTEND converted to an inclusive Enddate.
+//
+// LOCATION is imported as the address of the event, unless the event has the
+// X-TT-ARENA, X-TT-ADDRESS or X-TT-CITY properties written by ToICS.
+func ParseICS(r io.Reader, opts ...ICSOption) ([]Document, error) {
+	props, err := readICSProperties(r)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := icsParser{loc: time.UTC}
+
+	for _, opt := range opts {
+		opt(&parser)
+	}
+
+	parser.zones, err = icsTimeZones(props)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		docs  []Document
+		event []icsProperty
+		depth []string
+		begin int
+	)
+
+	for _, p := range props {
+		switch p.name {
+		case "BEGIN":
+			depth = append(depth, strings.ToUpper(p.value))
+
+			if depth[len(depth)-1] == "VEVENT" {
+				event = event[:0]
+				begin = p.line
+			}
+
+			continue
+		case "END":
+			if len(depth) == 0 || depth[len(depth)-1] != strings.ToUpper(p.value) {
+				return nil, &ICSError{Line: p.line, Msg: "unexpected END:" + p.value}
+			}
+
+			depth = depth[:len(depth)-1]
+
+			if strings.EqualFold(p.value, "VEVENT") {
+				doc, err := parser.event(event, begin)
+				if err != nil {
+					return nil, err
+				}
+
+				docs = append(docs, doc)
+			}
+
+			continue
+		}
+
+		// Only direct properties of the VEVENT, not of f.ex. VALARM.
+		if len(depth) > 0 && depth[len(depth)-1] == "VEVENT" {
+			event = append(event, p)
+		}
+	}
+
+	if len(depth) > 0 {
+		return nil, &ICSError{
+			Line: props[len(props)-1].line,
+			Msg:  "unterminated " + depth[len(depth)-1],
+		}
+	}
+
+	return docs, nil
+}
+
+// readICSProperties reads and unfolds the content lines of a calendar.
+func readICSProperties(r io.Reader) ([]icsProperty, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+
+	var (
+		props   []icsProperty
+		current strings.Builder
+		start   int
+		lineNo  int
+	)
+
+	flush := func() error {
+		if current.Len() == 0 {
+			return nil
+		}
+
+		p, err := parseICSLine(current.String())
+		if err != nil {
+			return &ICSError{Line: start, Msg: err.Error()}
+		}
+
+		p.line = start
+		props = append(props, p)
+		current.Reset()
+
+		return nil
+	}
+
+	for scanner.Scan() {
+		lineNo++
+
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			current.WriteString(line[1:])
+
+			continue
+		}
+
+		err := flush()
+		if err != nil {
+			return nil, err
+		}
+
+		if line == "" {
+			continue
+		}
+
+		start = lineNo
+		current.WriteString(line)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("read calendar: %w", err)
+	}
+
+	err = flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return props, nil
+}
+
+func parseICSLine(line string) (icsProperty, error) {
+	p := icsProperty{params: make(map[string]string)}
+
+	end := strings.IndexAny(line, ";:")
+	if end == -1 {
+		return p, fmt.Errorf("invalid content line %q", line)
+	}
+
+	p.name = strings.ToUpper(line[:end])
+	rest := line[end:]
+
+	for strings.HasPrefix(rest, ";") {
+		eq := strings.IndexByte(rest, '=')
+		if eq == -1 {
+			return p, fmt.Errorf("invalid parameter in %s", p.name)
+		}
+
+		key := strings.ToUpper(rest[1:eq])
+		rest = rest[eq+1:]
+
+		var val string
+
+		if strings.HasPrefix(rest, `"`) {
+			q := strings.IndexByte(rest[1:], '"')
+			if q == -1 {
+				return p, fmt.Errorf("unterminated quoted parameter in %s", p.name)
+			}
+
+			val = rest[1 : q+1]
+			rest = rest[q+2:]
+		} else {
+			e := strings.IndexAny(rest, ";:")
+			if e == -1 {
+				return p, fmt.Errorf("missing value in %s", p.name)
+			}
+
+			val = rest[:e]
+			rest = rest[e:]
+		}
+
+		p.params[key] = val
+	}
+
+	if !strings.HasPrefix(rest, ":") {
+		return p, fmt.Errorf("missing value in %s", p.name)
+	}
+
+	p.value = rest[1:]
+
+	return p, nil
+}
+
+var icsTextUnescaper = strings.NewReplacer(
+	`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n",
+)
+
+// event creates a document from the properties of a VEVENT starting on the
+// given line.
+func (ip *icsParser) event(props []icsProperty, line int) (Document, error) {
+	doc := Document{
+		Type:      TypeEvent,
+		BodyEvent: &BodyEvent{},
+	}
+
+	ev := doc.BodyEvent
+
+	var (
+		hasStart   bool
+		location   string
+		structured bool
+	)
+
+	for _, p := range props {
+		text := icsTextUnescaper.Replace(p.value)
+
+		switch p.name {
+		case "UID":
+			doc.Uri = p.value
+		case "SUMMARY":
+			doc.Headline = text
+		case "DESCRIPTION":
+			doc.BodyText = text
+		case "LOCATION":
+			location = text
+		case "X-TT-ARENA":
+			ev.Arena, structured = text, true
+		case "X-TT-ADDRESS":
+			ev.Address, structured = text, true
+		case "X-TT-CITY":
+			ev.City, structured = text, true
+		case "URL":
+			ev.Eventurl = p.value
+		case "CATEGORIES":
+			ev.EventtypeText = text
+		case "STATUS":
+			switch strings.ToUpper(p.value) {
+			case "CANCELLED":
+				ev.SetStatus(EventStatusCancelled)
+			case "CONFIRMED":
+				ev.SetStatus(EventStatusPlanned)
+			}
+		case "ORGANIZER":
+			ev.Organizer = p.params["CN"]
+
+			if mail, ok := strings.CutPrefix(strings.ToLower(p.value), "mailto:"); ok {
+				ev.Organizermail = p.value[len(p.value)-len(mail):]
+			}
+		case "LAST-MODIFIED":
+			t, _, err := ip.time(p)
+			if err != nil {
+				return doc, err
+			}
+
+			doc.Versioncreated = t
+		case "DTSTART", "DTEND":
+			t, allDay, err := ip.time(p)
+			if err != nil {
+				return doc, err
+			}
+
+			switch {
+			case p.name == "DTSTART" && allDay:
+				hasStart = true
+				doc.Date = &SerializableDate{Time: t}
+			case p.name == "DTSTART":
+				hasStart = true
+				doc.Datetime = &t
+			case allDay:
+				// DTEND is exclusive, Enddate inclusive.
+				doc.Enddate = &SerializableDate{Time: t.AddDate(0, 0, -1)}
+			default:
+				doc.Enddatetime = &t
+			}
+		}
+	}
+
+	if !hasStart {
+		return doc, &ICSError{Line: line, Msg: "event without DTSTART"}
+	}
+
+	if !structured {
+		ev.Address = location
+	}
+
+	// A one day all-day event doesn't need an end date.
+	if doc.Date != nil && doc.Enddate != nil && doc.Enddate.Equal(doc.Date.Time) {
+		doc.Enddate = nil
+	}
+
+	if *ev == (BodyEvent{}) {
+		doc.BodyEvent = nil
+	}
+
+	return doc, nil
+}
+
+// time parses a DATE or DATE-TIME value, returning true for dates.
+func (ip *icsParser) time(p icsProperty) (time.Time, bool, error) {
+	value := p.value
+
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(value) == len(icsDateFormat) {
+		t, err := time.Parse(icsDateFormat, value)
+		if err != nil {
+			return t, false, &ICSError{Line: p.line, Msg: "invalid date in " + p.name}
+		}
+
+		return t, true, nil
+	}
+
+	utc := strings.HasSuffix(value, "Z")
+
+	// The wall clock time, converted to the time zone below.
+	t, err := time.Parse(icsDateTimeFormat, strings.TrimSuffix(value, "Z"))
+	if err != nil {
+		return t, false, &ICSError{Line: p.line, Msg: "invalid date-time in " + p.name}
+	}
+
+	tzid := p.params["TZID"]
+
+	switch {
+	case utc:
+		return t, false, nil
+	case tzid == "":
+		return wallTime(t, ip.loc), false, nil
+	}
+
+	if loc, ok := icsLocation(tzid); ok {
+		return wallTime(t, loc), false, nil
+	}
+
+	if zone, ok := ip.zones[tzid]; ok {
+		if loc, ok := icsLocation(zone.location); ok {
+			return wallTime(t, loc), false, nil
+		}
+
+		if len(zone.rules) > 0 {
+			offset := zone.offset(t)
+
+			return t.Add(-time.Duration(offset) * time.Second).
+				In(time.FixedZone(tzid, offset)), false, nil
+		}
+	}
+
+	return wallTime(t, ip.loc), false, nil
+}
+
+// wallTime returns the time in loc with the same wall clock as t.
+func wallTime(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(),
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// icsLocation loads the time zone of a TZID, which can be an IANA name or a
+// Windows time zone name.
+func icsLocation(tzid string) (*time.Location, bool) {
+	name := strings.TrimPrefix(tzid, "/")
+
+	// LoadLocation returns UTC for "" and the host time zone for "Local".
+	if name == "" || name == "Local" {
+		return nil, false
+	}
+
+	if iana, ok := windowsTimeZones[name]; ok {
+		name = iana
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, false
+	}
+
+	return loc, true
+}
+
+// icsTimeZone is a time zone defined by a VTIMEZONE component.
+type icsTimeZone struct {
+	// location is the IANA time zone from X-LIC-LOCATION, if any.
+	location string
+	rules    []icsZoneRule
+}
+
+// icsZoneRule is a STANDARD or DAYLIGHT observance of a VTIMEZONE. Only
+// yearly recurrences on a weekday of a month are supported, which is what
+// calendar software uses for daylight saving time.
+type icsZoneRule struct {
+	// start is the wall clock time of the first onset.
+	start      time.Time
+	offsetFrom int
+	offsetTo   int
+
+	// month is zero for observances that don't recur.
+	month   time.Month
+	weekday time.Weekday
+	// week is the week of the month, 1 to 5, or -1 for the last week.
+	week int
+	// firstDay is the first day of the month that the weekday can fall
+	// on, for rules given with BYMONTHDAY instead of a week.
+	firstDay int
+	until    time.Time
+}
+
+// icsTimeZones reads the VTIMEZONE components of a calendar, keyed by TZID.
+func icsTimeZones(props []icsProperty) (map[string]*icsTimeZone, error) {
+	zones := make(map[string]*icsTimeZone)
+
+	var (
+		zone  *icsTimeZone
+		tzid  string
+		rule  *icsZoneRule
+		depth int
+	)
+
+	for _, p := range props {
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VTIMEZONE"):
+			zone, tzid, depth = &icsTimeZone{}, "", 0
+		case zone == nil:
+		case p.name == "BEGIN":
+			depth++
+
+			if depth == 1 && (strings.EqualFold(p.value, "STANDARD") ||
+				strings.EqualFold(p.value, "DAYLIGHT")) {
+				rule = &icsZoneRule{}
+			}
+		case p.name == "END" && depth == 0:
+			if tzid != "" {
+				zones[tzid] = zone
+			}
+
+			zone = nil
+		case p.name == "END":
+			depth--
+
+			if depth == 0 && rule != nil {
+				if !rule.start.IsZero() {
+					zone.rules = append(zone.rules, *rule)
+				}
+
+				rule = nil
+			}
+		case depth == 0 && p.name == "TZID":
+			tzid = p.value
+		case depth == 0 && p.name == "X-LIC-LOCATION":
+			zone.location = p.value
+		case depth == 1 && rule != nil:
+			err := rule.set(p)
+			if err != nil {
+				return nil, &ICSError{Line: p.line, Msg: err.Error()}
+			}
+		}
+	}
+
+	return zones, nil
+}
+
+func (r *icsZoneRule) set(p icsProperty) error {
+	var err error
+
+	switch p.name {
+	case "DTSTART":
+		r.start, err = time.Parse(icsDateTimeFormat, p.value)
+		if err != nil {
+			return errors.New("invalid DTSTART in time zone")
+		}
+	case "TZOFFSETFROM":
+		r.offsetFrom, err = parseICSOffset(p.value)
+	case "TZOFFSETTO":
+		r.offsetTo, err = parseICSOffset(p.value)
+	case "RRULE":
+		r.recurrence(p.value)
+	}
+
+	return err
+}
+
+// parseICSOffset parses a UTC offset like "+0100" or "-053000" into seconds.
+func parseICSOffset(value string) (int, error) {
+	if (len(value) != 5 && len(value) != 7) || (value[0] != '+' && value[0] != '-') {
+		return 0, fmt.Errorf("invalid UTC offset %q", value)
+	}
+
+	var seconds int
+
+	for i, unit := range []int{3600, 60, 1} {
+		if 1+2*i >= len(value) {
+			break
+		}
+
+		var n int
+
+		for _, c := range value[1+2*i : 3+2*i] {
+			if c < '0' || c > '9' {
+				return 0, fmt.Errorf("invalid UTC offset %q", value)
+			}
+
+			n = n*10 + int(c-'0')
+		}
+
+		seconds += n * unit
+	}
+
+	if value[0] == '-' {
+		seconds = -seconds
+	}
+
+	return seconds, nil
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday,
+	"WE": time.Wednesday, "TH": time.Thursday, "FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// recurrence reads a yearly RRULE like "FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU".
+// Other rules leave the observance without a recurrence.
+func (r *icsZoneRule) recurrence(rrule string) {
+	parts := make(map[string]string)
+
+	for _, part := range strings.Split(rrule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		parts[strings.ToUpper(key)] = strings.ToUpper(value)
+	}
+
+	month, err := strconv.Atoi(parts["BYMONTH"])
+	if parts["FREQ"] != "YEARLY" || err != nil || month < 1 || month > 12 {
+		return
+	}
+
+	day := parts["BYDAY"]
+	if len(day) < 2 {
+		return
+	}
+
+	weekday, ok := icsWeekdays[day[len(day)-2:]]
+	if !ok {
+		return
+	}
+
+	week, firstDay := 0, 0
+
+	if n := day[:len(day)-2]; n != "" {
+		week, err = strconv.Atoi(strings.TrimPrefix(n, "+"))
+		if err != nil || week == 0 || week < -1 || week > 5 {
+			return
+		}
+	} else {
+		for _, d := range strings.Split(parts["BYMONTHDAY"], ",") {
+			n, err := strconv.Atoi(d)
+			if err == nil && n > 0 && (firstDay == 0 || n < firstDay) {
+				firstDay = n
+			}
+		}
+
+		if firstDay == 0 {
+			return
+		}
+	}
+
+	if until := strings.TrimSuffix(parts["UNTIL"], "Z"); until != "" {
+		t, err := time.Parse(icsDateTimeFormat, until)
+		if err != nil {
+			t, err = time.Parse(icsDateFormat, until)
+		}
+
+		if err != nil {
+			return
+		}
+
+		r.until = t
+	}
+
+	r.month, r.weekday, r.week, r.firstDay = time.Month(month), weekday, week, firstDay
+}
+
+// onset returns the wall clock time of the onset of the observance in a year.
+func (r icsZoneRule) onset(year int) time.Time {
+	h, m, s := r.start.Clock()
+
+	var day int
+
+	switch {
+	case r.firstDay > 0:
+		first := time.Date(year, r.month, r.firstDay, 0, 0, 0, 0, time.UTC)
+		day = r.firstDay + (int(r.weekday)-int(first.Weekday())+7)%7
+	case r.week > 0:
+		first := time.Date(year, r.month, 1, 0, 0, 0, 0, time.UTC)
+		day = 1 + (int(r.weekday)-int(first.Weekday())+7)%7 + 7*(r.week-1)
+	default:
+		last := time.Date(year, r.month+1, 0, 0, 0, 0, 0, time.UTC)
+		day = last.Day() - (int(last.Weekday())-int(r.weekday)+7)%7
+	}
+
+	return time.Date(year, r.month, day, h, m, s, 0, time.UTC)
+}
+
+// latestOnset returns the last onset of the observance at or before the wall
+// clock time t.
+func (r icsZoneRule) latestOnset(t time.Time) (time.Time, bool) {
+	if r.start.After(t) {
+		return time.Time{}, false
+	}
+
+	if r.month == 0 {
+		return r.start, true
+	}
+
+	// UNTIL is in UTC, onsets are in the wall clock time before them.
+	if !r.until.IsZero() {
+		limit := r.until.Add(time.Duration(r.offsetFrom) * time.Second)
+		if t.After(limit) {
+			t = limit
+		}
+	}
+
+	for year := t.Year(); year >= r.start.Year() && year >= t.Year()-1; year-- {
+		o := r.onset(year)
+		if !o.After(t) && !o.Before(r.start) {
+			return o, true
+		}
+	}
+
+	return r.start, true
+}
+
+// offset returns the UTC offset in seconds at the wall clock time t.
+func (z *icsTimeZone) offset(t time.Time) int {
+	var (
+		latest time.Time
+		found  bool
+		offset int
+	)
+
+	for _, r := range z.rules {
+		o, ok := r.latestOnset(t)
+		if ok && (!found || o.After(latest)) {
+			latest, found, offset = o, true, r.offsetTo
+		}
+	}
+
+	if found {
+		return offset
+	}
+
+	// Before the first observance the offset before it applies.
+	first := z.rules[0]
+
+	for _, r := range z.rules[1:] {
+		if r.start.Before(first.start) {
+			first = r
+		}
+	}
+
+	return first.offsetFrom
+}
+
+// windowsTimeZones maps common Windows time zone names, as used by Outlook and
+// Exchange, to IANA time zones, following the CLDR windowsZones mapping.
+var windowsTimeZones = map[string]string{
+	"UTC":                            "UTC",
+	"GMT Standard Time":              "Europe/London",
+	"Greenwich Standard Time":        "Atlantic/Reykjavik",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Central European Standard Time": "Europe/Warsaw",
+	"Romance Standard Time":          "Europe/Paris",
+	"FLE Standard Time":              "Europe/Kiev",
+	"GTB Standard Time":              "Europe/Bucharest",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"Russian Standard Time":          "Europe/Moscow",
+	"Turkey Standard Time":           "Europe/Istanbul",
+	"Israel Standard Time":           "Asia/Jerusalem",
+	"South Africa Standard Time":     "Africa/Johannesburg",
+	"Arabian Standard Time":          "Asia/Dubai",
+	"India Standard Time":            "Asia/Kolkata",
+	"China Standard Time":            "Asia/Shanghai",
+	"Singapore Standard Time":        "Asia/Singapore",
+	"Korea Standard Time":            "Asia/Seoul",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"New Zealand Standard Time":      "Pacific/Auckland",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"US Mountain Standard Time":      "America/Phoenix",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"Alaskan Standard Time":          "America/Anchorage",
+	"Hawaiian Standard Time":         "Pacific/Honolulu",
+	"Atlantic Standard Time":         "America/Halifax",
+	"E. South America Standard Time": "America/Sao_Paulo",
+}
@@ -0,0 +1,375 @@
+package ttninjs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestToICSStripsLineBreaks(t *testing.T) {
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	out, err := ToICS([]Document{{
+		Uri:      "http://tt.se/event/1\r\nX-INJECTED:uid",
+		Headline: "Möte",
+		Datetime: &start,
+		BodyEvent: &BodyEvent{
+			Organizer:     "TT\r\nX-INJECTED:cn",
+			Organizermail: "info@tt.se",
+			Eventurl:      "https://tt.se/\r\nX-INJECTED:url",
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(string(out), "\r\n") {
+		if strings.HasPrefix(line, "X-INJECTED") {
+			t.Errorf("injected property %q", line)
+		}
+	}
+
+	docs, err := ParseICS(strings.NewReader(string(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(docs) != 1 {
+		t.Fatalf("got %d events, expected 1", len(docs))
+	}
+
+	if docs[0].Uri != "http://tt.se/event/1X-INJECTED:uid" {
+		t.Errorf("unexpected uid %q", docs[0].Uri)
+	}
+}
+
+func TestParseICSTimeZones(t *testing.T) {
+	cal := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:1",
+		"DTSTART;TZID=W. Europe Standard Time:20240601T100000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:2",
+		"DTSTART;TZID=Europe/Stockholm:20240601T100000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:3",
+		"DTSTART;TZID=Okänd tidszon:20240601T100000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	docs, err := ParseICS(strings.NewReader(cal))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(docs) != 3 {
+		t.Fatalf("got %d events, expected 3", len(docs))
+	}
+
+	want := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	for _, doc := range docs[:2] {
+		if !doc.Datetime.Equal(want) {
+			t.Errorf("event %s starts at %v, expected %v", doc.Uri, doc.Datetime.UTC(), want)
+		}
+	}
+
+	// Unknown time zones are floating, which defaults to UTC.
+	floating := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	if !docs[2].Datetime.Equal(floating) {
+		t.Errorf("event 3 starts at %v, expected %v", docs[2].Datetime, floating)
+	}
+}
+
+func TestParseICSFloating(t *testing.T) {
+	cal := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:1",
+		"DTSTART:20240601T100000",
+		"DTEND;TZID=Local:20240601T120000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		opts       []ICSOption
+		start, end time.Time
+	}{
+		{
+			name:  "default",
+			start: time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC),
+			end:   time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "stockholm",
+			opts:  []ICSOption{WithICSLocation(stockholm)},
+			start: time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC),
+			end:   time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			docs, err := ParseICS(strings.NewReader(cal), c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !docs[0].Datetime.Equal(c.start) || !docs[0].Enddatetime.Equal(c.end) {
+				t.Errorf("got %v to %v, want %v to %v",
+					docs[0].Datetime, docs[0].Enddatetime, c.start, c.end)
+			}
+		})
+	}
+}
+
+func TestParseICSVTimezone(t *testing.T) {
+	cal := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VTIMEZONE",
+		"TZID:Svensk tid",
+		"BEGIN:STANDARD",
+		"DTSTART:16010101T030000",
+		"TZOFFSETFROM:+0200",
+		"TZOFFSETTO:+0100",
+		"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=10",
+		"END:STANDARD",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:16010101T020000",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0200",
+		"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=3",
+		"END:DAYLIGHT",
+		"END:VTIMEZONE",
+		"BEGIN:VTIMEZONE",
+		"TZID:Östkusten",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:20070311T020000",
+		"TZOFFSETFROM:-0500",
+		"TZOFFSETTO:-0400",
+		"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU",
+		"END:DAYLIGHT",
+		"BEGIN:STANDARD",
+		"DTSTART:20071104T020000",
+		"TZOFFSETFROM:-0400",
+		"TZOFFSETTO:-0500",
+		"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VTIMEZONE",
+		"TZID:Gammal regel",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:19870405T020000",
+		"TZOFFSETFROM:-0500",
+		"TZOFFSETTO:-0400",
+		"RRULE:FREQ=YEARLY;BYMONTH=4;BYDAY=SU;BYMONTHDAY=1,2,3,4,5,6,7",
+		"END:DAYLIGHT",
+		"BEGIN:STANDARD",
+		"DTSTART:19871025T020000",
+		"TZOFFSETFROM:-0400",
+		"TZOFFSETTO:-0500",
+		"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VTIMEZONE",
+		"TZID:Indien",
+		"BEGIN:STANDARD",
+		"DTSTART:19451015T000000",
+		"TZOFFSETFROM:+0630",
+		"TZOFFSETTO:+0530",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VTIMEZONE",
+		"TZID:tz1",
+		"X-LIC-LOCATION:Europe/Stockholm",
+		"END:VTIMEZONE",
+		"BEGIN:VTIMEZONE",
+		"TZID:Tom",
+		"END:VTIMEZONE",
+	}, "\r\n")
+
+	events := []struct {
+		tzid, start string
+		want        time.Time
+	}{
+		{"Svensk tid", "20240601T100000", time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)},
+		{"Svensk tid", "20240115T100000", time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)},
+		// The last Sunday of March 2024 is the 31st.
+		{"Svensk tid", "20240331T013000", time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC)},
+		{"Svensk tid", "20240331T033000", time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC)},
+		{"Svensk tid", "20241027T040000", time.Date(2024, 10, 27, 3, 0, 0, 0, time.UTC)},
+		{"Svensk tid", "20241231T230000", time.Date(2024, 12, 31, 22, 0, 0, 0, time.UTC)},
+		// The second Sunday of March 2024 is the 10th.
+		{"Östkusten", "20240309T120000", time.Date(2024, 3, 9, 17, 0, 0, 0, time.UTC)},
+		{"Östkusten", "20240310T120000", time.Date(2024, 3, 10, 16, 0, 0, 0, time.UTC)},
+		{"Östkusten", "20241103T120000", time.Date(2024, 11, 3, 17, 0, 0, 0, time.UTC)},
+		// Before the first observance.
+		{"Östkusten", "20000601T120000", time.Date(2000, 6, 1, 17, 0, 0, 0, time.UTC)},
+		// The first Sunday of April 1990 is the 1st.
+		{"Gammal regel", "19900401T120000", time.Date(1990, 4, 1, 16, 0, 0, 0, time.UTC)},
+		{"Gammal regel", "19900331T120000", time.Date(1990, 3, 31, 17, 0, 0, 0, time.UTC)},
+		{"Indien", "20240601T100000", time.Date(2024, 6, 1, 4, 30, 0, 0, time.UTC)},
+		{"tz1", "20240601T100000", time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)},
+		{"Tom", "20240601T100000", time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)},
+	}
+
+	var b strings.Builder
+
+	b.WriteString(cal)
+
+	for i, e := range events {
+		fmt.Fprintf(&b, "\r\nBEGIN:VEVENT\r\nUID:%d\r\nDTSTART;TZID=%s:%s\r\nEND:VEVENT",
+			i, e.tzid, e.start)
+	}
+
+	b.WriteString("\r\nEND:VCALENDAR\r\n")
+
+	docs, err := ParseICS(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, e := range events {
+		if got := *docs[i].Datetime; !got.Equal(e.want) {
+			t.Errorf("%s %s: got %v, want %v", e.tzid, e.start, got.UTC(), e.want)
+		}
+	}
+
+	_, err = ParseICS(strings.NewReader(strings.Replace(cal, "TZOFFSETTO:+0100", "TZOFFSETTO:1", 1)))
+
+	var icsErr *ICSError
+	if !errors.As(err, &icsErr) || icsErr.Line != 7 {
+		t.Errorf("got %v, want an error on line 7 for an invalid offset", err)
+	}
+}
+
+func TestToICSStamp(t *testing.T) {
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	modified := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name string
+		doc  Document
+		want string
+	}{
+		{
+			name: "versioncreated",
+			doc:  Document{Datetime: &start, Versioncreated: modified, Firstcreated: &created},
+			want: "20240502T120000Z",
+		},
+		{
+			name: "firstcreated",
+			doc:  Document{Datetime: &start, Firstcreated: &created},
+			want: "20240501T120000Z",
+		},
+		{
+			name: "datetime",
+			doc:  Document{Datetime: &start},
+			want: "20240601T100000Z",
+		},
+		{
+			name: "date",
+			doc:  Document{Date: &SerializableDate{Time: start.Truncate(24 * time.Hour)}},
+			want: "20240601T000000Z",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.doc.Uri = "http://tt.se/event/1"
+
+			out, err := ToICS([]Document{c.doc})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.Contains(string(out), "\r\nDTSTAMP:"+c.want+"\r\n") {
+				t.Errorf("got\n%s\nwant DTSTAMP %s", out, c.want)
+			}
+
+			again, err := ToICS([]Document{c.doc})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(again) != string(out) {
+				t.Error("the output isn't reproducible")
+			}
+		})
+	}
+}
+
+func TestICSLocation(t *testing.T) {
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	ev := BodyEvent{
+		Arena:   "Friends Arena",
+		Address: "Råsta Strandväg 1, Solna",
+		City:    "Stockholm",
+	}
+
+	out, err := ToICS([]Document{{
+		Uri:       "http://tt.se/event/1",
+		Datetime:  &start,
+		BodyEvent: &ev,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `LOCATION:Friends Arena\, Råsta Strandväg 1\, Solna\, Stockholm`
+	if !strings.Contains(string(out), want+"\r\n") {
+		t.Errorf("got\n%s\nwant %s", out, want)
+	}
+
+	docs, err := ParseICS(strings.NewReader(string(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := docs[0].BodyEvent; got == nil || *got != ev {
+		t.Errorf("got %+v, want %+v", got, ev)
+	}
+
+	// Calendars from elsewhere only have LOCATION.
+	cal := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTART:20240601T100000Z\r\n" +
+		"LOCATION:Konserthuset\\, Stockholm\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+	docs, err = ParseICS(strings.NewReader(cal))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := docs[0].BodyEvent; got == nil || *got != (BodyEvent{Address: "Konserthuset, Stockholm"}) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestParseICSTentative(t *testing.T) {
+	cal := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTART:20240601T100000Z\r\n" +
+		"STATUS:TENTATIVE\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+	docs, err := ParseICS(strings.NewReader(cal))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if docs[0].BodyEvent != nil {
+		t.Errorf("a tentative event got the status %+v", docs[0].BodyEvent)
+	}
+}
@@ -0,0 +1,983 @@
+package ttninjs
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MarkdownOptions control how documents are rendered as Markdown.
+type MarkdownOptions struct {
+	// RenditionUsage is the preferred usage of image renditions, defaults
+	// to "Preview".
+	RenditionUsage string
+	// NoImages leaves out figures and image associations.
+	NoImages bool
+}
+
+// ToMarkdown renders a text document as Markdown, with the headline as a
+// level one heading followed by the byline, the located, the image
+// associations that aren't referenced from the body, and the body.
+func ToMarkdown(doc *Document, opts MarkdownOptions) (string, error) {
+	body, err := ParseBody(doc)
+	if err != nil {
+		return "", fmt.Errorf("parse body: %w", err)
+	}
+
+	if opts.RenditionUsage == "" {
+		opts.RenditionUsage = "Preview"
+	}
+
+	m := markdownWriter{doc: doc, opts: opts}
+
+	if doc.Headline != "" {
+		m.block("# " + escapeMarkdown(doc.Headline, true))
+	}
+
+	var byline []string
+
+	if b := markdownByline(doc); b != "" {
+		byline = append(byline, "*"+escapeMarkdown(b, false)+"*")
+	}
+
+	if doc.Located != "" {
+		byline = append(byline, "**"+escapeMarkdown(doc.Located, false)+"**")
+	}
+
+	if len(byline) > 0 {
+		m.block(strings.Join(byline, "  \n"))
+	}
+
+	if !opts.NoImages {
+		m.associatedImages(body)
+	}
+
+	for i, b := range body.Blocks {
+		// Skip the headline when it's repeated as the first heading.
+		if h, ok := b.(*Heading); ok && i == 0 && h.Level == 1 &&
+			inlineText(h.Content) == doc.Headline {
+			continue
+		}
+
+		m.blocks = append(m.blocks, m.renderBlock(b)...)
+	}
+
+	return strings.Join(m.blocks, "\n\n") + "\n", nil
+}
+
+// markdownByline returns Byline, or the bylines of Bylines if it isn't set.
+func markdownByline(doc *Document) string {
+	if doc.Byline != "" {
+		return doc.Byline
+	}
+
+	return RenderByline(doc.Bylines, BylineStyleTT)
+}
+
+type markdownWriter struct {
+	doc    *Document
+	opts   MarkdownOptions
+	blocks []string
+}
+
+func (m *markdownWriter) block(s string) {
+	m.blocks = append(m.blocks, s)
+}
+
+// associatedImages renders the picture and graphic associations that aren't
+// referenced by figures in the body.
+func (m *markdownWriter) associatedImages(body *Body) {
+	referenced := make(map[string]bool)
+
+	for _, b := range body.Blocks {
+		if f, ok := b.(*Figure); ok {
+			referenced[f.AssociationKey()] = true
+		}
+	}
+
+	keys := make([]string, 0, len(m.doc.Associations))
+
+	for key, a := range m.doc.Associations {
+		if referenced[key] || a.Type != TypePicture && a.Type != TypeGraphic {
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		a := m.doc.Associations[key]
+
+		href := preferredRendition(a.Renditions, m.opts.RenditionUsage)
+		if href == "" {
+			continue
+		}
+
+		caption := a.DescriptionText
+		if a.Byline != "" {
+			caption = strings.TrimSpace(caption + " Foto: " + a.Byline)
+		}
+
+		m.block(markdownImage(a.Headline, href, escapeMarkdown(caption, false)))
+	}
+}
+
+// markdownImage renders an image with an emphasised caption on the following
+// line.
+func markdownImage(alt, href, caption string) string {
+	img := "![" + escapeMarkdown(alt, false) + "](" + markdownURL(href) + ")"
+
+	if caption != "" {
+		img += "  \n_" + caption + "_"
+	}
+
+	return img
+}
+
+func (m *markdownWriter) renderBlock(b Block) []string {
+	switch b := b.(type) {
+	case *Paragraph:
+		return []string{markdownInlines(b.Content, true)}
+	case *Heading:
+		return []string{strings.Repeat("#", min(max(b.Level, 1), 6)) + " " +
+			markdownInlines(b.Content, false)}
+	case *List:
+		lines := make([]string, len(b.Items))
+
+		for i, item := range b.Items {
+			marker := "- "
+			if b.Ordered {
+				marker = strconv.Itoa(i+1) + ". "
+			}
+
+			lines[i] = marker + indentLines(markdownInlines(item, false), "   ")
+		}
+
+		return []string{strings.Join(lines, "\n")}
+	case *Blockquote:
+		var inner []string
+
+		for _, c := range b.Blocks {
+			inner = append(inner, m.renderBlock(c)...)
+		}
+
+		quoted := strings.ReplaceAll(strings.Join(inner, "\n\n"), "\n", "\n> ")
+
+		return []string{strings.ReplaceAll("> "+quoted, "> \n", ">\n")}
+	case *Figure:
+		return m.renderFigure(b)
+	case *Table:
+		return []string{markdownTable(b)}
+	case *RawHTML:
+		text := htmlText(parseHTML(b.HTML))
+		if text == "" {
+			return nil
+		}
+
+		var paras []string
+
+		for _, line := range strings.Split(text, "\n") {
+			paras = append(paras, escapeMarkdown(line, true))
+		}
+
+		return paras
+	}
+
+	return nil
+}
+
+func (m *markdownWriter) renderFigure(f *Figure) []string {
+	if m.opts.NoImages {
+		return nil
+	}
+
+	href := f.Src
+
+	if a, ok := m.doc.Associations[f.AssociationKey()]; ok {
+		if h := preferredRendition(a.Renditions, m.opts.RenditionUsage); h != "" {
+			href = h
+		}
+	}
+
+	if href == "" {
+		return nil
+	}
+
+	return []string{markdownImage(f.Alt, href, markdownInlines(f.Caption, false))}
+}
+
+func markdownTable(t *Table) string {
+	rows := t.Rows
+	head := t.Head
+
+	if len(head) == 0 && len(rows) > 0 {
+		head, rows = rows[:1], rows[1:]
+	}
+
+	var columns int
+
+	for _, r := range append(slices.Clone(head), rows...) {
+		columns = max(columns, len(r.Cells))
+	}
+
+	if columns == 0 {
+		return ""
+	}
+
+	var lines []string
+
+	if t.Caption != nil {
+		lines = append(lines, "**"+markdownInlines(t.Caption, false)+"**", "")
+	}
+
+	row := func(r TableRow) string {
+		cells := make([]string, columns)
+
+		for i, c := range r.Cells {
+			text := markdownInlines(c.Content, false)
+			cells[i] = strings.ReplaceAll(
+				strings.ReplaceAll(text, "|", `\|`), "\n", " ")
+		}
+
+		return "| " + strings.Join(cells, " | ") + " |"
+	}
+
+	lines = append(lines, row(head[0]),
+		"|"+strings.Repeat(" --- |", columns))
+
+	for _, r := range head[1:] {
+		lines = append(lines, row(r))
+	}
+
+	for _, r := range rows {
+		lines = append(lines, row(r))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func indentLines(s, indent string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+indent)
+}
+
+// inlineText returns the plain text of inline content.
+func inlineText(content []Inline) string {
+	var b strings.Builder
+
+	var walk func(content []Inline)
+
+	walk = func(content []Inline) {
+		for _, in := range content {
+			switch in := in.(type) {
+			case *Text:
+				b.WriteString(in.Text)
+			case *Strong:
+				walk(in.Content)
+			case *Emphasis:
+				walk(in.Content)
+			case *Link:
+				walk(in.Content)
+			case *LineBreak:
+				b.WriteByte('\n')
+			case *InlineHTML:
+				b.WriteString(htmlText(parseHTML(in.HTML)))
+			}
+		}
+	}
+
+	walk(content)
+
+	return b.String()
+}
+
+func markdownInlines(content []Inline, lineStart bool) string {
+	var b strings.Builder
+
+	for i, in := range content {
+		switch in := in.(type) {
+		case *Text:
+			if in.Text == "" {
+				continue
+			}
+
+			text := escapeMarkdown(strings.Join(strings.Fields(in.Text), " "),
+				lineStart && i == 0)
+
+			if unicode.IsSpace(rune(in.Text[0])) {
+				text = " " + text
+			}
+
+			if text != " " && unicode.IsSpace(rune(in.Text[len(in.Text)-1])) {
+				text += " "
+			}
+
+			b.WriteString(text)
+		case *Strong:
+			b.WriteString("**" + markdownInlines(in.Content, false) + "**")
+		case *Emphasis:
+			b.WriteString("*" + markdownInlines(in.Content, false) + "*")
+		case *Link:
+			b.WriteString("[" + markdownInlines(in.Content, false) + "](" +
+				markdownURL(in.Href) + ")")
+		case *LineBreak:
+			b.WriteString("\\\n")
+		case *InlineHTML:
+			root := parseHTML(in.HTML)
+
+			if len(root.Children) == 1 && root.Children[0].Tag == "code" {
+				b.WriteString("`" + htmlText(root) + "`")
+
+				continue
+			}
+
+			b.WriteString(escapeMarkdown(htmlText(root), false))
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`",
+	"[", `\[`, "]", `\]`, "<", `\<`,
+)
+
+// escapeMarkdown escapes characters with a meaning in Markdown. If lineStart is
+// set, block markers at the start of the text are escaped as well.
+func escapeMarkdown(s string, lineStart bool) string {
+	s = markdownEscaper.Replace(s)
+
+	if !lineStart || s == "" {
+		return s
+	}
+
+	switch s[0] {
+	case '#', '>', '-', '+', '|':
+		return `\` + s
+	}
+
+	digits := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if digits > 0 && (s[digits] == '.' || s[digits] == ')') {
+		return s[:digits] + `\` + s[digits:]
+	}
+
+	return s
+}
+
+func markdownURL(href string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(href)
+}
+
+// MarkdownError is returned by FromMarkdown for Markdown it can't convert.
+type MarkdownError struct {
+	Line int
+	Msg  string
+}
+
+func (e *MarkdownError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// FromMarkdown builds a text document from Markdown. The first level one
+// heading is used as headline. BodyHtml5, BodyText, Charcount and Wordcount
+// are set from the body.
+//
+// Headings, paragraphs, lists, blockquotes, fenced code blocks, thematic
+// breaks, images, pipe tables, emphasis, links and code spans are supported.
+// Nested lists are flattened. Links and images with a URL scheme that
+// BodyHTML5Policy doesn't allow, like "javascript:", are kept as plain text.
+func FromMarkdown(md string) (*Document, error) {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+
+	blocks, err := parseMarkdownBlocks(lines, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	body := Body{Blocks: blocks}
+
+	doc := Document{
+		Type:     TypeText,
+		Mimetype: "text/html",
+	}
+
+	for _, b := range blocks {
+		if h, ok := b.(*Heading); ok && h.Level == 1 {
+			doc.Headline = inlineText(h.Content)
+
+			break
+		}
+	}
+
+	doc.BodyHtml5 = body.RenderHTML5()
+	doc.BodyText = body.RenderText()
+
+	ComputeCounts(&doc)
+
+	return &doc, nil
+}
+
+// parseMarkdownBlocks parses lines of Markdown, first is the line number of
+// the first line.
+func parseMarkdownBlocks(lines []string, first int) ([]Block, error) {
+	var (
+		blocks []Block
+		para   []string
+	)
+
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+
+		blocks = append(blocks, &Paragraph{
+			Content: parseMarkdownInlines(strings.Join(para, "\n")),
+		})
+		para = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+
+			fence := trimmed[:3]
+			end := -1
+
+			for j := i + 1; j < len(lines); j++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[j]), fence) {
+					end = j
+
+					break
+				}
+			}
+
+			if end == -1 {
+				return nil, &MarkdownError{
+					Line: first + i,
+					Msg:  "unterminated code block",
+				}
+			}
+
+			code := strings.Join(lines[i+1:end], "\n")
+
+			blocks = append(blocks, &RawHTML{
+				HTML: "<pre><code>" + escapeHTMLText(code) + "</code></pre>",
+			})
+			i = end
+		case markdownHeadingLevel(trimmed) > 0:
+			flush()
+
+			level := markdownHeadingLevel(trimmed)
+			text := strings.TrimRight(strings.TrimSpace(trimmed[level:]), "#")
+
+			blocks = append(blocks, &Heading{
+				Level:   level,
+				Content: parseMarkdownInlines(strings.TrimSpace(text)),
+			})
+		case isThematicBreak(trimmed):
+			flush()
+
+			blocks = append(blocks, &RawHTML{HTML: "<hr>"})
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+
+			var quoted []string
+
+			start := i
+
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(t, ">") {
+					break
+				}
+
+				t = strings.TrimPrefix(t, ">")
+				quoted = append(quoted, strings.TrimPrefix(t, " "))
+			}
+
+			i--
+
+			inner, err := parseMarkdownBlocks(quoted, first+start)
+			if err != nil {
+				return nil, err
+			}
+
+			blocks = append(blocks, &Blockquote{Blocks: inner})
+		case listMarker(trimmed) != "":
+			flush()
+
+			list := List{Ordered: listMarker(trimmed) == "1."}
+
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if t == "" {
+					break
+				}
+
+				if marker := listMarker(t); marker != "" {
+					item := strings.TrimSpace(t[len(listPrefix(t)):])
+					list.Items = append(list.Items, []Inline{&Text{Text: item}})
+
+					continue
+				}
+
+				// Continuation of the current item.
+				last := list.Items[len(list.Items)-1][0].(*Text)
+				last.Text += "\n" + t
+			}
+
+			i--
+
+			for j, item := range list.Items {
+				list.Items[j] = parseMarkdownInlines(item[0].(*Text).Text)
+			}
+
+			blocks = append(blocks, &list)
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) &&
+			isTableDelimiter(strings.TrimSpace(lines[i+1])):
+			flush()
+
+			t := Table{
+				Head: []TableRow{markdownTableRow(trimmed, true)},
+			}
+
+			for i += 2; i < len(lines); i++ {
+				t2 := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(t2, "|") {
+					break
+				}
+
+				t.Rows = append(t.Rows, markdownTableRow(t2, false))
+			}
+
+			i--
+
+			blocks = append(blocks, &t)
+		case len(para) == 0 && isMarkdownImage(trimmed) && safeMarkdownURL(trimmed[1:]):
+			alt, src := splitMarkdownLink(trimmed[1:])
+
+			f := Figure{Src: src, Alt: alt}
+
+			// A line emphasised with underscores directly after the
+			// image is its caption.
+			if i+1 < len(lines) {
+				next := strings.TrimSpace(lines[i+1])
+				if len(next) > 2 && next[0] == '_' && next[len(next)-1] == '_' {
+					f.Caption = parseMarkdownInlines(next[1 : len(next)-1])
+					i++
+				}
+			}
+
+			blocks = append(blocks, &f)
+		default:
+			para = append(para, line)
+		}
+	}
+
+	flush()
+
+	return blocks, nil
+}
+
+func markdownHeadingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+
+	if level == 0 || level > 6 {
+		return 0
+	}
+
+	if level < len(line) && line[level] != ' ' {
+		return 0
+	}
+
+	return level
+}
+
+func isThematicBreak(line string) bool {
+	line = strings.ReplaceAll(line, " ", "")
+	if len(line) < 3 {
+		return false
+	}
+
+	for _, c := range []string{"-", "*", "_"} {
+		if strings.Trim(line, c) == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listPrefix returns the list marker of a line including the following space.
+func listPrefix(line string) string {
+	if len(line) >= 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return line[:2]
+	}
+
+	digits := strings.IndexFunc(line, func(r rune) bool { return r < '0' || r > '9' })
+	if digits > 0 && digits+1 < len(line) &&
+		(line[digits] == '.' || line[digits] == ')') && line[digits+1] == ' ' {
+		return line[:digits+2]
+	}
+
+	return ""
+}
+
+// listMarker returns "-" for bullet list items, "1." for ordered list items
+// and "" for other lines.
+func listMarker(line string) string {
+	switch p := listPrefix(line); {
+	case p == "":
+		return ""
+	case strings.ContainsRune("-*+", rune(p[0])):
+		return "-"
+	default:
+		return "1."
+	}
+}
+
+func isTableDelimiter(line string) bool {
+	if !strings.HasPrefix(line, "|") {
+		return false
+	}
+
+	return strings.Trim(line, "|-: ") == "" && strings.Contains(line, "-")
+}
+
+func markdownTableRow(line string, header bool) TableRow {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+
+	var (
+		row  TableRow
+		cell strings.Builder
+	)
+
+	add := func() {
+		row.Cells = append(row.Cells, TableCell{
+			Header:  header,
+			Content: parseMarkdownInlines(strings.TrimSpace(cell.String())),
+		})
+		cell.Reset()
+	}
+
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			add()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+
+	add()
+
+	return row
+}
+
+func isMarkdownImage(line string) bool {
+	if !strings.HasPrefix(line, "![") {
+		return false
+	}
+
+	_, _, n := scanMarkdownLink(line[1:])
+
+	return n == len(line)-1
+}
+
+func splitMarkdownLink(s string) (string, string) {
+	text, href, _ := scanMarkdownLink(s)
+
+	return text, href
+}
+
+// scanMarkdownLink scans a "[text](href)" at the start of s and returns the
+// text, the href and the number of bytes consumed, or zero if there is no link.
+func scanMarkdownLink(s string) (string, string, int) {
+	if !strings.HasPrefix(s, "[") {
+		return "", "", 0
+	}
+
+	depth := 0
+	closeText := -1
+
+	for i := 0; i < len(s) && closeText == -1; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closeText = i
+			}
+		}
+	}
+
+	if closeText == -1 || closeText+1 >= len(s) || s[closeText+1] != '(' {
+		return "", "", 0
+	}
+
+	// Parentheses in the destination must be balanced or escaped, unless
+	// it's enclosed in angle brackets.
+	start := closeText + 2
+	end := -1
+	depth = 0
+
+	if rest := strings.TrimLeft(s[start:], " "); strings.HasPrefix(rest, "<") {
+		gt := strings.IndexByte(rest, '>')
+		if gt != -1 {
+			closeParen := strings.IndexByte(rest[gt:], ')')
+			if closeParen != -1 && strings.TrimSpace(rest[gt+1:gt+closeParen]) == "" {
+				end = len(s) - len(rest) + gt + closeParen
+			}
+		}
+	}
+
+	for i := start; i < len(s) && end == -1; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				end = i
+			}
+
+			depth--
+		}
+	}
+
+	if end == -1 {
+		return "", "", 0
+	}
+
+	href := strings.TrimSpace(s[start:end])
+	if strings.HasPrefix(href, "<") && strings.HasSuffix(href, ">") {
+		href = href[1 : len(href)-1]
+	}
+
+	return s[1:closeText], unescapeMarkdown(href), end + 1
+}
+
+// unescapeMarkdown removes the backslash from backslash escaped punctuation.
+func unescapeMarkdown(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(asciiPunctuation, s[i+1]) != -1 {
+			i++
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// markdownURLChecker checks link and image URLs against the schemes allowed
+// by BodyHTML5Policy.
+var markdownURLChecker = sanitizer{policy: BodyHTML5Policy()}
+
+// safeMarkdownURL reports whether the "[text](href)" at the start of s has a
+// URL that is safe to use in the body.
+func safeMarkdownURL(s string) bool {
+	_, href, _ := scanMarkdownLink(s)
+
+	return markdownURLChecker.safeURL(href)
+}
+
+// asciiPunctuation are the characters that can be backslash escaped.
+const asciiPunctuation = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// parseMarkdownInlines parses emphasis, strong emphasis, code spans, links,
+// images and hard line breaks.
+func parseMarkdownInlines(s string) []Inline {
+	var (
+		res  []Inline
+		text strings.Builder
+	)
+
+	flush := func() {
+		if text.Len() > 0 {
+			res = append(res, &Text{Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			flush()
+
+			res = append(res, &LineBreak{})
+			i++
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(asciiPunctuation, s[i+1]) != -1:
+			text.WriteByte(s[i+1])
+			i++
+		case c == ' ' && strings.HasPrefix(s[i:], "  \n"):
+			flush()
+
+			res = append(res, &LineBreak{})
+			i += 2
+		case c == '`':
+			end := strings.IndexByte(s[i+1:], '`')
+			if end == -1 {
+				text.WriteByte(c)
+
+				continue
+			}
+
+			flush()
+
+			res = append(res, &InlineHTML{
+				HTML: "<code>" + escapeHTMLText(s[i+1:i+1+end]) + "</code>",
+			})
+			i += end + 1
+		case c == '!' && strings.HasPrefix(s[i+1:], "["):
+			alt, src, n := scanMarkdownLink(s[i+1:])
+			if n == 0 {
+				text.WriteByte(c)
+
+				continue
+			}
+
+			if !markdownURLChecker.safeURL(src) {
+				text.WriteString(alt)
+				i += n
+
+				continue
+			}
+
+			flush()
+
+			var b strings.Builder
+
+			writeStartTag(&b, "img", "src", src, "alt", alt)
+
+			res = append(res, &InlineHTML{HTML: b.String()})
+			i += n
+		case c == '[':
+			label, href, n := scanMarkdownLink(s[i:])
+			if n == 0 {
+				text.WriteByte(c)
+
+				continue
+			}
+
+			if !markdownURLChecker.safeURL(href) {
+				flush()
+
+				res = append(res, parseMarkdownInlines(label)...)
+				i += n - 1
+
+				continue
+			}
+
+			flush()
+
+			res = append(res, &Link{
+				Href:    href,
+				Content: parseMarkdownInlines(label),
+			})
+			i += n - 1
+		case c == '*' || c == '_':
+			delim := string(c)
+			if strings.HasPrefix(s[i:], delim+delim) {
+				delim += delim
+			}
+
+			// Intraword underscores aren't emphasis.
+			opening := i+len(delim) < len(s) && s[i+len(delim)] != ' '
+			if c == '_' && i > 0 && isWordRune(rune(s[i-1])) {
+				opening = false
+			}
+
+			end := -1
+			if opening {
+				end = findClosingDelimiter(s[i+len(delim):], delim)
+			}
+
+			if end == -1 {
+				text.WriteString(delim)
+				i += len(delim) - 1
+
+				continue
+			}
+
+			flush()
+
+			inner := parseMarkdownInlines(s[i+len(delim) : i+len(delim)+end])
+
+			if len(delim) == 2 {
+				res = append(res, &Strong{Content: inner})
+			} else {
+				res = append(res, &Emphasis{Content: inner})
+			}
+
+			i += 2*len(delim) + end - 1
+		default:
+			text.WriteByte(c)
+		}
+	}
+
+	flush()
+
+	return res
+}
+
+// findClosingDelimiter finds the closing emphasis delimiter in s, skipping
+// escaped characters and code spans.
+func findClosingDelimiter(s, delim string) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '`':
+			end := strings.IndexByte(s[i+1:], '`')
+			if end != -1 {
+				i += end + 1
+			}
+		case strings.HasPrefix(s[i:], delim) && i > 0 && s[i-1] != ' ':
+			// A single delimiter can't close on the start of a
+			// double one.
+			if len(delim) == 1 && strings.HasPrefix(s[i+1:], delim) {
+				next := findClosingDelimiter(s[i+2:], delim+delim)
+				if next != -1 {
+					i += next + 3
+
+					continue
+				}
+			}
+
+			return i
+		}
+	}
+
+	return -1
+}
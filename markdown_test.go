@@ -0,0 +1,82 @@
+package ttninjs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFromMarkdownUnsafeURLs(t *testing.T) {
+	cases := []struct {
+		md   string
+		want string
+	}{
+		{
+			md:   "[x](javascript:alert(1))",
+			want: "<body><p>x</p></body>",
+		},
+		{
+			md:   "Se [här](JavaScript:alert(1)) och ![bild](data:image/png;base64,AAAA).",
+			want: "<body><p>Se här och bild.</p></body>",
+		},
+		{
+			md:   "[TT](https://tt.se/) och [mejl](mailto:info@tt.se)",
+			want: `<body><p><a href="https://tt.se/">TT</a> och <a href="mailto:info@tt.se">mejl</a></p></body>`,
+		},
+		{
+			md:   "[relativ](/nyheter?a=b:c)",
+			want: `<body><p><a href="/nyheter?a=b:c">relativ</a></p></body>`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.md, func(t *testing.T) {
+			doc, err := FromMarkdown(c.md)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if doc.BodyHtml5 != c.want {
+				t.Errorf("got %s, expected %s", doc.BodyHtml5, c.want)
+			}
+		})
+	}
+}
+
+func TestFromMarkdownUnsafeImageBlock(t *testing.T) {
+	doc, err := FromMarkdown("![bild](javascript:alert(1))")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(doc.BodyHtml5, "javascript") || strings.Contains(doc.BodyHtml5, "<figure") {
+		t.Errorf("unsafe image was kept: %s", doc.BodyHtml5)
+	}
+}
+
+func TestScanMarkdownLink(t *testing.T) {
+	cases := []struct {
+		s    string
+		text string
+		href string
+		n    int
+	}{
+		{"[a](b) c", "a", "b", 6},
+		{"[Paris](https://sv.wikipedia.org/wiki/Paris_(olika_betydelser)) x",
+			"Paris", "https://sv.wikipedia.org/wiki/Paris_(olika_betydelser)", 63},
+		{`[a](b\)c)`, "a", "b)c", 9},
+		{"[a](<b c)>)", "a", "b c)", 11},
+		{"[a [b]](c)", "a [b]", "c", 10},
+		{"[a](b(c)", "", "", 0},
+		{"[a] (b)", "", "", 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.s, func(t *testing.T) {
+			text, href, n := scanMarkdownLink(c.s)
+			if text != c.text || href != c.href || n != c.n {
+				t.Errorf("got (%q, %q, %d), expected (%q, %q, %d)",
+					text, href, n, c.text, c.href, c.n)
+			}
+		})
+	}
+}
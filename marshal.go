@@ -0,0 +1,56 @@
+package ttninjs
+
+import "time"
+
+// plainDocument is used to marshal documents with the default struct encoding.
+type plainDocument Document
+
+// marshalDocument shadows the value typed fields of a document with pointers,
+// so that omitempty has an effect on them.
+type marshalDocument struct {
+	*plainDocument
+
+	Standard       *Standard  `json:"$standard,omitempty"`
+	Signals        *Signals   `json:"signals,omitempty"`
+	Versioncreated *time.Time `json:"versioncreated,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. Empty "$standard" and "signals"
+// objects and a zero versioncreated are left out, as they are in the documents
+// that TT publishes.
+func (j Document) MarshalJSON() ([]byte, error) {
+	d := marshalDocument{
+		plainDocument: (*plainDocument)(&j),
+	}
+
+	if !j.Standard.IsZero() {
+		d.Standard = &j.Standard
+	}
+
+	if !j.Signals.IsZero() {
+		d.Signals = &j.Signals
+	}
+
+	if !j.Versioncreated.IsZero() {
+		d.Versioncreated = &j.Versioncreated
+	}
+
+	return json.Marshal(d)
+}
+
+// IsZero returns true if no standard information has been set.
+func (s Standard) IsZero() bool {
+	return s == Standard{}
+}
+
+// IsZero returns true if no signals have been set.
+func (s Signals) IsZero() bool {
+	return len(s.Deliverytags) == 0 &&
+		s.Multipagecount == nil &&
+		s.Pagecode == "" &&
+		s.Pageproduct == "" &&
+		s.Pagevariant == "" &&
+		len(s.Paginae) == 0 &&
+		s.Retransmission == nil &&
+		s.Updatetype == nil
+}
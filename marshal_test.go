@@ -0,0 +1,113 @@
+package ttninjs
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// TestMarshalGolden round-trips the documents in testdata/marshal through
+// UnmarshalJSON and MarshalJSON, and compares the output with the golden
+// files.
+func TestMarshalGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "marshal", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inputs) == 0 {
+		t.Fatal("no test documents found")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".json")
+
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var doc Document
+
+			err = json.Unmarshal(data, &doc)
+			if err != nil {
+				t.Fatalf("unmarshal input: %v", err)
+			}
+
+			out, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+
+			var indented bytes.Buffer
+
+			err = stdjson.Indent(&indented, out, "", "  ")
+			if err != nil {
+				t.Fatalf("indent output: %v", err)
+			}
+
+			indented.WriteByte('\n')
+
+			golden := strings.TrimSuffix(input, ".json") + ".golden"
+
+			if *update {
+				err := os.WriteFile(golden, indented.Bytes(), 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file, run with -update to create it: %v", err)
+			}
+
+			if !bytes.Equal(indented.Bytes(), want) {
+				t.Errorf("output doesn't match %s:\n%s", golden, indented.String())
+			}
+
+			var again Document
+
+			err = json.Unmarshal(out, &again)
+			if err != nil {
+				t.Fatalf("unmarshal output: %v", err)
+			}
+
+			if !reflect.DeepEqual(doc, again) {
+				t.Errorf("document changed in round-trip:\n got: %#v\nwant: %#v", again, doc)
+			}
+		})
+	}
+}
+
+func TestMarshalOmitsEmpty(t *testing.T) {
+	out, err := json.Marshal(Document{Uri: "http://tt.se/text/1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != `{"uri":"http://tt.se/text/1"}` {
+		t.Errorf("unexpected output %s", out)
+	}
+
+	var fields map[string]stdjson.RawMessage
+
+	err = json.Unmarshal(out, &fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"$standard", "signals", "versioncreated"} {
+		if _, ok := fields[name]; ok {
+			t.Errorf("%s should be left out", name)
+		}
+	}
+}
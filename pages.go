@@ -0,0 +1,345 @@
+package ttninjs
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DocumentPage is a page from BodyPages tied to its entry in Signals.Paginae.
+type DocumentPage struct {
+	Page
+
+	// Key is the key of the page in BodyPages.
+	Key string
+	// Index is the index of the pagina in Signals.Paginae, or -1 if the
+	// page has no entry there.
+	Index int
+}
+
+// Pages returns the pages of a delivery of ready-made pages in the order of
+// Signals.Paginae, followed by any pages without an entry in Paginae, ordered
+// by key. A page matches a pagina entry if its Pagina or its key in BodyPages
+// is equal to it.
+func (j Document) Pages() []DocumentPage {
+	var pages []DocumentPage
+
+	used := make(map[string]bool, len(j.BodyPages))
+
+	for i, pagina := range j.Signals.Paginae {
+		key, ok := j.pageKey(pagina, used)
+		if !ok {
+			continue
+		}
+
+		used[key] = true
+
+		page := j.BodyPages[key]
+		if page.Pagina == "" {
+			page.Pagina = pagina
+		}
+
+		pages = append(pages, DocumentPage{Page: page, Key: key, Index: i})
+	}
+
+	var rest []string
+
+	for key := range j.BodyPages {
+		if !used[key] {
+			rest = append(rest, key)
+		}
+	}
+
+	slices.Sort(rest)
+
+	for _, key := range rest {
+		pages = append(pages, DocumentPage{
+			Page:  j.BodyPages[key],
+			Key:   key,
+			Index: -1,
+		})
+	}
+
+	return pages
+}
+
+func (j Document) pageKey(pagina string, used map[string]bool) (string, bool) {
+	var keys []string
+
+	for key, p := range j.BodyPages {
+		if p.Pagina == pagina && !used[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) > 0 {
+		slices.Sort(keys)
+
+		return keys[0], true
+	}
+
+	if _, ok := j.BodyPages[pagina]; ok && !used[pagina] {
+		return pagina, true
+	}
+
+	return "", false
+}
+
+// PDF returns the PDF rendition of the page.
+func (p Page) PDF() (Rendition, bool) {
+	for _, name := range sortedRenditionNames(p.Renditions) {
+		r := p.Renditions[name]
+		if r.Mimetype == "application/pdf" {
+			return r, true
+		}
+	}
+
+	return Rendition{}, false
+}
+
+// Image returns an image rendition of the page, preferring one with the given
+// usage, f.ex. "Preview" or "Thumbnail".
+func (p Page) Image(usage string) (Rendition, bool) {
+	var (
+		found bool
+		res   Rendition
+	)
+
+	for _, name := range sortedRenditionNames(p.Renditions) {
+		r := p.Renditions[name]
+		if !strings.HasPrefix(r.Mimetype, "image/") {
+			continue
+		}
+
+		if strings.EqualFold(r.Usage, usage) {
+			return r, true
+		}
+
+		if !found {
+			found = true
+			res = r
+		}
+	}
+
+	return res, found
+}
+
+func sortedRenditionNames(renditions Renditions) []string {
+	names := make([]string, 0, len(renditions))
+
+	for name := range renditions {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// pageFields has the fields of Page without its methods.
+type pageFields Page
+
+// pageMembers are the members of a page that have fields.
+var pageMembers = []string{"pagina", "width", "height", "unit", "renditions"}
+
+// MarshalJSON implements json.Marshaler, adding the members in Extra. The
+// fields take precedence over Extra members with the same name.
+func (p Page) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(pageFields(p))
+	if err != nil || len(p.Extra) == 0 {
+		return data, err
+	}
+
+	members := make(map[string]any, len(p.Extra)+len(pageMembers))
+
+	for name, v := range p.Extra {
+		members[name] = v
+	}
+
+	var fields map[string]stdjson.RawMessage
+
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, v := range fields {
+		members[name] = v
+	}
+
+	return json.Marshal(members)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping the members that don't
+// have a field in Extra.
+func (p *Page) UnmarshalJSON(data []byte) error {
+	var fields pageFields
+
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	var members map[string]any
+
+	err = json.Unmarshal(data, &members)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range pageMembers {
+		delete(members, name)
+	}
+
+	*p = Page(fields)
+
+	if len(members) > 0 {
+		p.Extra = members
+	}
+
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface of gopkg.in/yaml.v2 and
+// v3, so that pages have the same members in YAML as in JSON.
+func (p Page) MarshalYAML() (any, error) {
+	if len(p.Extra) == 0 {
+		return pageFields(p), nil
+	}
+
+	data, err := p.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var members map[string]any
+
+	err = json.Unmarshal(data, &members)
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface of gopkg.in/yaml.v2,
+// which v3 also supports. The members are decoded like in UnmarshalJSON.
+func (p *Page) UnmarshalYAML(unmarshal func(any) error) error {
+	var members map[string]any
+
+	err := unmarshal(&members)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(yamlToJSON(members))
+	if err != nil {
+		return fmt.Errorf("unable to convert page to JSON: %w", err)
+	}
+
+	return p.UnmarshalJSON(data)
+}
+
+// yamlToJSON converts the map[any]any values that gopkg.in/yaml.v2 decodes
+// mappings as to map[string]any, so that they can be marshalled as JSON.
+func yamlToJSON(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+
+		for k, e := range v {
+			m[fmt.Sprint(k)] = yamlToJSON(e)
+		}
+
+		return m
+	case map[string]any:
+		for k, e := range v {
+			v[k] = yamlToJSON(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = yamlToJSON(e)
+		}
+	}
+
+	return v
+}
+
+// PageProblem describes an inconsistency between BodyPages and the page
+// signals of a document.
+type PageProblem struct {
+	// Key is the key of the page in BodyPages, if the problem concerns a
+	// page.
+	Key string
+	// Pagina is the pagina entry that the problem concerns, if any.
+	Pagina string
+	Msg    string
+}
+
+func (p PageProblem) String() string {
+	switch {
+	case p.Key != "":
+		return fmt.Sprintf("page %q: %s", p.Key, p.Msg)
+	case p.Pagina != "":
+		return fmt.Sprintf("pagina %q: %s", p.Pagina, p.Msg)
+	}
+
+	return p.Msg
+}
+
+// CheckPages checks that Signals.Multipagecount matches the number of pages in
+// BodyPages, and that every page has exactly one entry in Signals.Paginae.
+func CheckPages(doc *Document) []PageProblem {
+	var problems []PageProblem
+
+	if mc := doc.Signals.Multipagecount; mc != nil && int(*mc) != len(doc.BodyPages) {
+		problems = append(problems, PageProblem{
+			Msg: fmt.Sprintf("multipagecount is %d, but there are %d pages",
+				int(*mc), len(doc.BodyPages)),
+		})
+	}
+
+	if len(doc.Signals.Paginae) == 0 {
+		return problems
+	}
+
+	seen := make(map[string]bool, len(doc.Signals.Paginae))
+
+	for _, pagina := range doc.Signals.Paginae {
+		if seen[pagina] {
+			problems = append(problems, PageProblem{
+				Pagina: pagina,
+				Msg:    "listed more than once in paginae",
+			})
+		}
+
+		seen[pagina] = true
+	}
+
+	for _, p := range doc.Pages() {
+		if p.Index == -1 {
+			problems = append(problems, PageProblem{
+				Key: p.Key,
+				Msg: "has no entry in paginae",
+			})
+
+			continue
+		}
+
+		delete(seen, doc.Signals.Paginae[p.Index])
+	}
+
+	for _, pagina := range doc.Signals.Paginae {
+		if seen[pagina] {
+			problems = append(problems, PageProblem{
+				Pagina: pagina,
+				Msg:    "has no page in body_pages",
+			})
+
+			delete(seen, pagina)
+		}
+	}
+
+	return problems
+}
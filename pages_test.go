@@ -0,0 +1,284 @@
+package ttninjs
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDocumentPages(t *testing.T) {
+	doc := Document{
+		Uri: "a",
+		BodyPages: BodyPages{
+			"p1":  {Pagina: "1"},
+			"p2":  {Pagina: "2"},
+			"A":   {},
+			"x":   {Pagina: "9"},
+			"dup": {Pagina: "2"},
+			"B":   {},
+		},
+		Signals: Signals{Paginae: []string{"A", "2", "1", "3", "2"}},
+	}
+
+	type page struct {
+		key, pagina string
+		index       int
+	}
+
+	var got []page
+
+	for _, p := range doc.Pages() {
+		got = append(got, page{p.Key, p.Pagina, p.Index})
+	}
+
+	// Pages are matched by pagina before key, each page is used once,
+	// and pages without a pagina entry come last ordered by key.
+	want := []page{
+		{"A", "A", 0},
+		{"dup", "2", 1},
+		{"p1", "1", 2},
+		{"p2", "2", 4},
+		{"B", "", -1},
+		{"x", "9", -1},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if pages := (Document{Uri: "a"}).Pages(); pages != nil {
+		t.Errorf("got %v for a document without pages", pages)
+	}
+}
+
+func TestPageRenditions(t *testing.T) {
+	p := Page{Renditions: Renditions{
+		"thumbnail": {Href: "t.jpg", Mimetype: "image/jpeg", Usage: "Thumbnail"},
+		"preview":   {Href: "p.jpg", Mimetype: "image/jpeg", Usage: "Preview"},
+		"print":     {Href: "p.pdf", Mimetype: "application/pdf"},
+	}}
+
+	if r, ok := p.PDF(); !ok || r.Href != "p.pdf" {
+		t.Errorf("PDF: got %+v %v", r, ok)
+	}
+
+	cases := map[string]string{
+		"Thumbnail": "t.jpg",
+		"preview":   "p.jpg",
+		"Other":     "p.jpg",
+	}
+
+	for usage, href := range cases {
+		if r, ok := p.Image(usage); !ok || r.Href != href {
+			t.Errorf("Image(%q): got %+v %v, want %s", usage, r, ok, href)
+		}
+	}
+
+	var empty Page
+
+	if _, ok := empty.PDF(); ok {
+		t.Error("didn't expect a PDF")
+	}
+
+	if _, ok := empty.Image("Preview"); ok {
+		t.Error("didn't expect an image")
+	}
+}
+
+func TestCheckPages(t *testing.T) {
+	count := func(n float64) *float64 { return &n }
+
+	cases := []struct {
+		name    string
+		pages   BodyPages
+		signals Signals
+		want    []string
+	}{
+		{name: "no pages"},
+		{
+			name:    "consistent",
+			pages:   BodyPages{"1": {}, "p2": {Pagina: "2"}},
+			signals: Signals{Paginae: []string{"1", "2"}, Multipagecount: count(2)},
+		},
+		{
+			name:    "without paginae",
+			pages:   BodyPages{"1": {}, "2": {}},
+			signals: Signals{Multipagecount: count(3)},
+			want:    []string{"multipagecount is 3, but there are 2 pages"},
+		},
+		{
+			name:    "mismatches",
+			pages:   BodyPages{"1": {}, "2": {}, "extra": {}},
+			signals: Signals{Paginae: []string{"1", "1", "2", "4"}},
+			want: []string{
+				`pagina "1": listed more than once in paginae`,
+				`page "extra": has no entry in paginae`,
+				`pagina "4": has no page in body_pages`,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			doc := Document{Uri: "a", BodyPages: c.pages, Signals: c.signals}
+
+			var got []string
+
+			for _, p := range CheckPages(&doc) {
+				got = append(got, p.String())
+			}
+
+			if !slices.Equal(got, c.want) {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPageJSON(t *testing.T) {
+	input := `{
+		"uri": "a",
+		"body_pages": {
+			"1": {
+				"pagina": "1",
+				"width": 260,
+				"height": 370,
+				"unit": "mm",
+				"pagecolor": "cmyk",
+				"section": {"name": "Sport", "order": 2},
+				"renditions": {"print": {"href": "1.pdf", "mimetype": "application/pdf"}}
+			},
+			"2": {"pagina": "2"}
+		}
+	}`
+
+	var doc Document
+
+	err := json.Unmarshal([]byte(input), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Page{
+		Pagina: "1",
+		Width:  260,
+		Height: 370,
+		Unit:   "mm",
+		Renditions: Renditions{
+			"print": {Href: "1.pdf", Mimetype: "application/pdf"},
+		},
+		Extra: map[string]any{
+			"pagecolor": "cmyk",
+			"section":   map[string]any{"name": "Sport", "order": float64(2)},
+		},
+	}
+
+	if !reflect.DeepEqual(doc.BodyPages["1"], want) {
+		t.Errorf("got %+v, want %+v", doc.BodyPages["1"], want)
+	}
+
+	if doc.BodyPages["2"].Extra != nil {
+		t.Errorf("got extra members %v", doc.BodyPages["2"].Extra)
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantJSON := `{"body_pages":{` +
+		`"1":{"height":370,"pagecolor":"cmyk","pagina":"1",` +
+		`"renditions":{"print":{"href":"1.pdf","mimetype":"application/pdf"}},` +
+		`"section":{"name":"Sport","order":2},"unit":"mm","width":260},` +
+		`"2":{"pagina":"2"}},"uri":"a"}`
+
+	if string(out) != wantJSON {
+		t.Errorf("got\n%s\nwant\n%s", out, wantJSON)
+	}
+
+	// Fields take precedence over extra members with the same name.
+	data, err := json.Marshal(Page{
+		Pagina: "3",
+		Extra:  map[string]any{"pagina": "4", "note": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"note":true,"pagina":"3"}` {
+		t.Errorf("got %s", data)
+	}
+
+	err = json.Unmarshal([]byte(`{"pagina": 1}`), &Page{})
+	if err == nil {
+		t.Error("expected an error for a numeric pagina")
+	}
+}
+
+func TestPageYAML(t *testing.T) {
+	p := Page{
+		Pagina: "1",
+		Extra:  map[string]any{"pagecolor": "cmyk"},
+	}
+
+	v, err := p.MarshalYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"pagina": "1", "pagecolor": "cmyk"}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+
+	v, err = Page{Pagina: "2"}.MarshalYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := v.(pageFields); !ok {
+		t.Errorf("got %T for a page without extra members", v)
+	}
+
+	// gopkg.in/yaml.v2 decodes mappings as map[any]any.
+	decoded := map[string]any{
+		"pagina": "1",
+		"section": map[any]any{
+			"name": "Sport",
+		},
+		"tags": []any{map[any]any{"a": 1}},
+	}
+
+	var got Page
+
+	err = got.UnmarshalYAML(func(v any) error {
+		*(v.(*map[string]any)) = decoded
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantPage := Page{
+		Pagina: "1",
+		Extra: map[string]any{
+			"section": map[string]any{"name": "Sport"},
+			"tags":    []any{map[string]any{"a": float64(1)}},
+		},
+	}
+
+	if !reflect.DeepEqual(got, wantPage) {
+		t.Errorf("got %+v, want %+v", got, wantPage)
+	}
+
+	err = got.UnmarshalYAML(func(v any) error {
+		*(v.(*map[string]any)) = map[string]any{"pagina": []any{"1"}}
+
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "pagina") {
+		t.Errorf("got %v, want an error for the pagina", err)
+	}
+}
@@ -0,0 +1,33 @@
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ttab/ttninjs"
+)
+
+// RunNDJSON runs the pipeline over documents read from r, writing the processed
+// documents to w as NDJSON. The input format is detected as described for
+// ttninjs.Decoder, so it can also be a JSON array or an object envelope.
+func (p *Pipeline) RunNDJSON(
+	ctx context.Context, r io.Reader, w io.Writer, opts ...ttninjs.DecoderOption,
+) error {
+	dec := ttninjs.NewDecoder(r, opts...)
+	bw := bufio.NewWriter(w)
+	enc := ttninjs.NewEncoder(bw)
+
+	err := p.Run(ctx, FromDecoder(dec), enc.Encode)
+	if err != nil {
+		return err
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush output: %w", err)
+	}
+
+	return nil
+}
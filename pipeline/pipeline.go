@@ -0,0 +1,356 @@
+// Package pipeline runs chains of processing stages over streams of documents
+// using a bounded pool of workers.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ttab/ttninjs"
+)
+
+// ErrDrop can be returned by a stage to remove a document from the pipeline
+// without treating it as an error.
+var ErrDrop = errors.New("drop document")
+
+// Stage processes a document in place.
+type Stage func(ctx context.Context, doc *ttninjs.Document) error
+
+// Source produces the documents to process, Next returns io.EOF when there are
+// no more documents.
+type Source interface {
+	Next() (*ttninjs.Document, error)
+}
+
+// SourceFunc is a function that implements Source.
+type SourceFunc func() (*ttninjs.Document, error)
+
+func (fn SourceFunc) Next() (*ttninjs.Document, error) {
+	return fn()
+}
+
+// FromDecoder creates a source that reads documents from a decoder.
+func FromDecoder(dec *ttninjs.Decoder) Source {
+	return SourceFunc(func() (*ttninjs.Document, error) {
+		var doc ttninjs.Document
+
+		err := dec.Decode(&doc)
+		if err != nil {
+			return nil, err
+		}
+
+		return &doc, nil
+	})
+}
+
+// Sink receives the processed documents. It's always called from a single
+// goroutine.
+type Sink func(doc *ttninjs.Document) error
+
+// StageError is the error for a document that failed in a stage.
+type StageError struct {
+	Stage string
+	// Index is the position of the document in the source.
+	Index int
+	URI   string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %q failed for item %d (%s): %v",
+		e.Stage, e.Index, e.URI, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Options for a pipeline.
+type Options struct {
+	// Workers is the number of documents that are processed
+	// concurrently. Defaults to 1.
+	Workers int
+	// Ordered makes the pipeline deliver documents to the sink in the same
+	// order as they were read from the source.
+	Ordered bool
+	// OnError is called when a document fails in a stage. If OnError
+	// returns nil the document is dropped and processing continues,
+	// otherwise the pipeline is stopped with the returned error. If OnError
+	// is nil the pipeline stops at the first error.
+	OnError func(err *StageError) error
+}
+
+// StageStats are the counters for a stage.
+type StageStats struct {
+	Name string
+	// Processed is the number of documents the stage has been run for.
+	Processed int64
+	Errors    int64
+	Dropped   int64
+	// Latency is the total time spent in the stage.
+	Latency time.Duration
+}
+
+// AverageLatency returns the average time spent per document.
+func (s StageStats) AverageLatency() time.Duration {
+	if s.Processed == 0 {
+		return 0
+	}
+
+	return s.Latency / time.Duration(s.Processed)
+}
+
+type stage struct {
+	name string
+	fn   Stage
+
+	processed atomic.Int64
+	errors    atomic.Int64
+	dropped   atomic.Int64
+	latency   atomic.Int64
+}
+
+// Pipeline is a chain of named stages.
+type Pipeline struct {
+	opts   Options
+	stages []*stage
+}
+
+// New creates a new pipeline.
+func New(opts Options) *Pipeline {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+
+	return &Pipeline{opts: opts}
+}
+
+// Stage adds a named stage to the end of the pipeline.
+func (p *Pipeline) Stage(name string, fn Stage) *Pipeline {
+	p.stages = append(p.stages, &stage{name: name, fn: fn})
+
+	return p
+}
+
+// Stats returns the counters for all stages.
+func (p *Pipeline) Stats() []StageStats {
+	stats := make([]StageStats, len(p.stages))
+
+	for i, s := range p.stages {
+		stats[i] = StageStats{
+			Name:      s.name,
+			Processed: s.processed.Load(),
+			Errors:    s.errors.Load(),
+			Dropped:   s.dropped.Load(),
+			Latency:   time.Duration(s.latency.Load()),
+		}
+	}
+
+	return stats
+}
+
+type job struct {
+	index int
+	doc   *ttninjs.Document
+}
+
+type result struct {
+	index int
+	// doc is nil if the document was dropped.
+	doc *ttninjs.Document
+	err *StageError
+}
+
+// process runs all stages for a document.
+func (p *Pipeline) process(ctx context.Context, j job) result {
+	for _, s := range p.stages {
+		start := time.Now()
+		err := s.fn(ctx, j.doc)
+
+		s.latency.Add(int64(time.Since(start)))
+		s.processed.Add(1)
+
+		switch {
+		case errors.Is(err, ErrDrop):
+			s.dropped.Add(1)
+
+			return result{index: j.index}
+		case err != nil:
+			s.errors.Add(1)
+
+			return result{
+				index: j.index,
+				err: &StageError{
+					Stage: s.name,
+					Index: j.index,
+					URI:   j.doc.Uri,
+					Err:   err,
+				},
+			}
+		}
+	}
+
+	return result{index: j.index, doc: j.doc}
+}
+
+// Run reads documents from the source, processes them and writes them to the
+// sink until the source is exhausted, the context is cancelled or an error
+// stops the pipeline.
+func (p *Pipeline) Run(ctx context.Context, src Source, sink Sink) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	workers := p.opts.Workers
+
+	// The slots limit the number of documents in flight, including the
+	// ones that are waiting to be delivered in order.
+	slots := make(chan struct{}, workers*2)
+	jobs := make(chan job)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+
+		for i := 0; ; i++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			doc, err := src.Next()
+			if errors.Is(err, io.EOF) {
+				return
+			} else if err != nil {
+				cancel(fmt.Errorf("read from source: %w", err))
+
+				return
+			}
+
+			select {
+			case jobs <- job{index: i, doc: doc}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var workersWg sync.WaitGroup
+
+	for range workers {
+		workersWg.Add(1)
+
+		go func() {
+			defer workersWg.Done()
+
+			for j := range jobs {
+				res := p.process(ctx, j)
+
+				select {
+				case results <- res:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		workersWg.Wait()
+		close(results)
+	}()
+
+	err := p.collect(ctx, results, slots, sink)
+	if err != nil {
+		cancel(err)
+	}
+
+	// Drain the results so that the workers can exit.
+	for range results {
+	}
+
+	wg.Wait()
+
+	if cause := context.Cause(ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+
+	return ctx.Err()
+}
+
+// collect delivers results to the sink, in order if requested.
+func (p *Pipeline) collect(
+	ctx context.Context, results <-chan result,
+	slots <-chan struct{}, sink Sink,
+) error {
+	pending := make(map[int]result)
+	next := 0
+
+	deliver := func(res result) error {
+		defer func() { <-slots }()
+
+		if res.err != nil {
+			if p.opts.OnError == nil {
+				return res.err
+			}
+
+			return p.opts.OnError(res.err)
+		}
+
+		if res.doc == nil {
+			return nil
+		}
+
+		err := sink(res.doc)
+		if err != nil {
+			return fmt.Errorf("write item %d to sink: %w", res.index, err)
+		}
+
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case res, ok := <-results:
+			if !ok {
+				return nil
+			}
+
+			if !p.opts.Ordered {
+				if err := deliver(res); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			pending[res.index] = res
+
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+
+				delete(pending, next)
+				next++
+
+				if err := deliver(r); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
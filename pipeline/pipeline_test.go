@@ -0,0 +1,347 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ttab/ttninjs"
+)
+
+// numbered is a source of n documents with the URIs "0", "1"...
+func numbered(n int) Source {
+	i := 0
+
+	return SourceFunc(func() (*ttninjs.Document, error) {
+		if i == n {
+			return nil, io.EOF
+		}
+
+		doc := &ttninjs.Document{Uri: fmt.Sprint(i)}
+		i++
+
+		return doc, nil
+	})
+}
+
+// jitter sleeps for a short random time, so that workers finish out of order.
+func jitter(ctx context.Context, _ *ttninjs.Document) error {
+	time.Sleep(time.Duration(rand.IntN(500)) * time.Microsecond)
+
+	return nil
+}
+
+func collectURIs(uris *[]string) Sink {
+	return func(doc *ttninjs.Document) error {
+		*uris = append(*uris, doc.Uri)
+
+		return nil
+	}
+}
+
+func expectedURIs(n int) []string {
+	uris := make([]string, n)
+	for i := range uris {
+		uris[i] = fmt.Sprint(i)
+	}
+
+	return uris
+}
+
+func TestRunOrdered(t *testing.T) {
+	p := New(Options{Workers: 8, Ordered: true}).
+		Stage("jitter", jitter).
+		Stage("headline", func(_ context.Context, doc *ttninjs.Document) error {
+			doc.Headline = "Dokument " + doc.Uri
+
+			return nil
+		})
+
+	var got []string
+
+	err := p.Run(context.Background(), numbered(200), func(doc *ttninjs.Document) error {
+		if doc.Headline != "Dokument "+doc.Uri {
+			t.Errorf("document %s wasn't processed by all stages", doc.Uri)
+		}
+
+		got = append(got, doc.Uri)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(got, expectedURIs(200)) {
+		t.Errorf("documents were delivered out of order: %v", got)
+	}
+
+	for _, s := range p.Stats() {
+		if s.Processed != 200 || s.Errors != 0 || s.Dropped != 0 {
+			t.Errorf("unexpected stats %+v", s)
+		}
+	}
+}
+
+func TestRunUnordered(t *testing.T) {
+	var running, maxRunning atomic.Int64
+
+	p := New(Options{Workers: 4}).Stage("jitter", func(ctx context.Context, doc *ttninjs.Document) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		return jitter(ctx, doc)
+	})
+
+	var got []string
+
+	err := p.Run(context.Background(), numbered(100), collectURIs(&got))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := expectedURIs(100)
+
+	slices.Sort(got)
+	slices.Sort(want)
+
+	if !slices.Equal(got, want) {
+		t.Errorf("got documents %v", got)
+	}
+
+	if m := maxRunning.Load(); m > 4 {
+		t.Errorf("%d documents were processed concurrently with 4 workers", m)
+	}
+}
+
+func TestRunDrop(t *testing.T) {
+	p := New(Options{Workers: 3, Ordered: true}).
+		Stage("odd", func(_ context.Context, doc *ttninjs.Document) error {
+			if strings.ContainsAny(doc.Uri[len(doc.Uri)-1:], "13579") {
+				return ErrDrop
+			}
+
+			return nil
+		}).
+		Stage("noop", func(context.Context, *ttninjs.Document) error { return nil })
+
+	var got []string
+
+	err := p.Run(context.Background(), numbered(10), collectURIs(&got))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(got, []string{"0", "2", "4", "6", "8"}) {
+		t.Errorf("got %v", got)
+	}
+
+	stats := p.Stats()
+
+	if stats[0].Processed != 10 || stats[0].Dropped != 5 {
+		t.Errorf("unexpected stats for the first stage %+v", stats[0])
+	}
+
+	if stats[1].Processed != 5 || stats[1].Dropped != 0 {
+		t.Errorf("unexpected stats for the second stage %+v", stats[1])
+	}
+
+	if stats[0].Name != "odd" || stats[1].Name != "noop" {
+		t.Errorf("unexpected stage names %q, %q", stats[0].Name, stats[1].Name)
+	}
+}
+
+var errStage = errors.New("failed")
+
+func failOn(uri string) Stage {
+	return func(_ context.Context, doc *ttninjs.Document) error {
+		if doc.Uri == uri {
+			return errStage
+		}
+
+		return nil
+	}
+}
+
+func TestRunStageError(t *testing.T) {
+	p := New(Options{Workers: 4, Ordered: true}).Stage("fail", failOn("5"))
+
+	var got []string
+
+	err := p.Run(context.Background(), numbered(1000), collectURIs(&got))
+
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) {
+		t.Fatalf("expected a *StageError, got %v", err)
+	}
+
+	if stageErr.Stage != "fail" || stageErr.Index != 5 || stageErr.URI != "5" ||
+		!errors.Is(err, errStage) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// Ordered delivery stops at the failed document.
+	if !slices.Equal(got, expectedURIs(5)) {
+		t.Errorf("got %v, expected the documents before the failed one", got)
+	}
+
+	if s := p.Stats()[0]; s.Errors != 1 || s.Processed >= 1000 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestRunOnError(t *testing.T) {
+	var failed []int
+
+	p := New(Options{
+		Workers: 4,
+		Ordered: true,
+		OnError: func(err *StageError) error {
+			failed = append(failed, err.Index)
+
+			return nil
+		},
+	}).Stage("fail", failOn("5"))
+
+	var got []string
+
+	err := p.Run(context.Background(), numbered(10), collectURIs(&got))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := slices.Delete(expectedURIs(10), 5, 6)
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, expected %v", got, want)
+	}
+
+	if !slices.Equal(failed, []int{5}) {
+		t.Errorf("OnError was called for %v", failed)
+	}
+
+	// An error from OnError stops the pipeline.
+	errStop := errors.New("stop")
+
+	p = New(Options{OnError: func(*StageError) error { return errStop }}).
+		Stage("fail", failOn("5"))
+
+	err = p.Run(context.Background(), numbered(10), func(*ttninjs.Document) error { return nil })
+	if !errors.Is(err, errStop) {
+		t.Errorf("expected the error from OnError, got %v", err)
+	}
+}
+
+func TestRunSourceAndSinkErrors(t *testing.T) {
+	errSource := errors.New("source")
+
+	src := SourceFunc(func() (*ttninjs.Document, error) {
+		return nil, errSource
+	})
+
+	err := New(Options{Workers: 2}).Run(context.Background(), src,
+		func(*ttninjs.Document) error { return nil })
+	if !errors.Is(err, errSource) {
+		t.Errorf("expected the source error, got %v", err)
+	}
+
+	errSink := errors.New("sink")
+
+	err = New(Options{Workers: 2}).Run(context.Background(), numbered(10),
+		func(*ttninjs.Document) error { return errSink })
+	if !errors.Is(err, errSink) {
+		t.Errorf("expected the sink error, got %v", err)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		read int
+	)
+
+	// An endless source.
+	src := SourceFunc(func() (*ttninjs.Document, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		read++
+
+		return &ttninjs.Document{Uri: fmt.Sprint(read)}, nil
+	})
+
+	p := New(Options{Workers: 4, Ordered: true}).Stage("jitter", jitter)
+
+	delivered := 0
+
+	done := make(chan error)
+
+	go func() {
+		done <- p.Run(ctx, src, func(*ttninjs.Document) error {
+			delivered++
+			if delivered == 10 {
+				cancel()
+			}
+
+			return nil
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the pipeline didn't stop when cancelled")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// The documents in flight are bounded by the workers.
+	if read > delivered+4*2+1 {
+		t.Errorf("read %d documents after cancelling at %d", read, delivered)
+	}
+}
+
+func TestRunNDJSON(t *testing.T) {
+	in := `[{"uri":"a","headline":"A"},{"uri":"b","headline":"B"}]`
+
+	p := New(Options{Workers: 2, Ordered: true}).
+		Stage("lower", func(_ context.Context, doc *ttninjs.Document) error {
+			doc.Headline = strings.ToLower(doc.Headline)
+
+			return nil
+		})
+
+	var out bytes.Buffer
+
+	err := p.RunNDJSON(context.Background(), strings.NewReader(in), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "{\"headline\":\"a\",\"uri\":\"a\"}\n{\"headline\":\"b\",\"uri\":\"b\"}\n"
+	if out.String() != want {
+		t.Errorf("got %q, expected %q", out.String(), want)
+	}
+}
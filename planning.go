@@ -0,0 +1,306 @@
+package ttninjs
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrNotPlanning is returned when assignments are managed on a
+	// document that isn't a planning item.
+	ErrNotPlanning = errors.New("document is not a planning item")
+	// ErrAssignmentExists is returned by AddAssignment if there already is
+	// an assignment with the key.
+	ErrAssignmentExists = errors.New("assignment already exists")
+	// ErrNoAssignment is returned for unknown assignment keys.
+	ErrNoAssignment = errors.New("no such assignment")
+	// ErrAssignmentType is returned for assignments that don't have a
+	// content type.
+	ErrAssignmentType = errors.New("invalid assignment type")
+	// ErrAssignmentKey is returned for empty assignment keys.
+	ErrAssignmentKey = errors.New("assignment key is empty")
+)
+
+// AssignmentURI returns the URI of an assignment of a planning item. Content
+// produced for the assignment references it in Replacing, see LinkContent.
+// ErrMissingURI is returned if the planning item has no URI.
+func AssignmentURI(planningURI, key string) (string, error) {
+	switch {
+	case planningURI == "":
+		return "", ErrMissingURI
+	case key == "":
+		return "", ErrAssignmentKey
+	}
+
+	return planningURI + "#" + key, nil
+}
+
+// AddAssignment adds an assignment to a planning item. The type of the
+// assignment must be one of the content types, f.ex. text, picture or video.
+// The pubstatus of the assignment defaults to commissioned and the URI to
+// AssignmentURI(planning.Uri, key).
+func AddAssignment(planning *Document, key string, a Document) error {
+	if planning.Type != TypePlanning {
+		return ErrNotPlanning
+	}
+
+	if key == "" {
+		return ErrAssignmentKey
+	}
+
+	if _, exists := planning.Assignments[key]; exists {
+		return fmt.Errorf("%w: %q", ErrAssignmentExists, key)
+	}
+
+	switch a.Type {
+	case TypeText, TypePicture, TypeVideo, TypeGraphic, TypeAudio:
+	default:
+		return fmt.Errorf("%w: %q", ErrAssignmentType, a.Type)
+	}
+
+	if a.Pubstatus == "" {
+		a.Pubstatus = PubstatusCommissioned
+	}
+
+	if a.Uri == "" {
+		uri, err := AssignmentURI(planning.Uri, key)
+		if err != nil {
+			return err
+		}
+
+		a.Uri = uri
+	}
+
+	if planning.Assignments == nil {
+		planning.Assignments = make(Assignments)
+	}
+
+	planning.Assignments[key] = a
+
+	return nil
+}
+
+// UpdateAssignment updates an assignment of a planning item with fn.
+func UpdateAssignment(planning *Document, key string, fn func(a *Document)) error {
+	if planning.Type != TypePlanning {
+		return ErrNotPlanning
+	}
+
+	a, ok := planning.Assignments[key]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNoAssignment, key)
+	}
+
+	fn(&a)
+
+	planning.Assignments[key] = a
+
+	return nil
+}
+
+// CancelAssignment sets the pubstatus of an assignment to canceled.
+func CancelAssignment(planning *Document, key string) error {
+	return UpdateAssignment(planning, key, func(a *Document) {
+		a.Pubstatus = PubstatusCanceled
+	})
+}
+
+// LinkContent links content produced for an assignment to it by adding the URI
+// of the assignment to the Replacing of the content, and marks the assignment
+// as usable.
+func LinkContent(planning *Document, key string, content *Document) error {
+	var uri string
+
+	err := UpdateAssignment(planning, key, func(a *Document) {
+		uri = a.Uri
+		a.Pubstatus = PubstatusUsable
+	})
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(content.Replacing, uri) {
+		content.Replacing = append(content.Replacing, uri)
+	}
+
+	return nil
+}
+
+// UnlinkContent removes the link from content to an assignment, see
+// LinkContent. The assignment is marked as commissioned again unless it has
+// been canceled, also if other content is still linked to it.
+func UnlinkContent(planning *Document, key string, content *Document) error {
+	var uri string
+
+	err := UpdateAssignment(planning, key, func(a *Document) {
+		uri = a.Uri
+
+		if a.Pubstatus != PubstatusCanceled {
+			a.Pubstatus = PubstatusCommissioned
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	content.Replacing = slices.DeleteFunc(content.Replacing, func(s string) bool {
+		return s == uri
+	})
+
+	if len(content.Replacing) == 0 {
+		content.Replacing = nil
+	}
+
+	return nil
+}
+
+// AssignmentRef is an assignment of a planning item.
+type AssignmentRef struct {
+	Planning   *Document
+	Key        string
+	Assignment Document
+	// Due is the Datetime or Date of the assignment, or of the planning
+	// item if the assignment has neither.
+	Due time.Time
+}
+
+// IsOpen returns true if the assignment has been commissioned but hasn't been
+// delivered or canceled.
+func (r AssignmentRef) IsOpen() bool {
+	return r.Assignment.Pubstatus == PubstatusCommissioned ||
+		r.Assignment.Pubstatus == ""
+}
+
+// PlanningAssignments returns the assignments of the planning items, ordered
+// by due time and key.
+func PlanningAssignments(plannings []Document) []AssignmentRef {
+	var refs []AssignmentRef
+
+	for i := range plannings {
+		p := &plannings[i]
+
+		for key, a := range p.Assignments {
+			due, ok := documentTime(&a)
+			if !ok {
+				due, _ = documentTime(p)
+			}
+
+			refs = append(refs, AssignmentRef{
+				Planning:   p,
+				Key:        key,
+				Assignment: a,
+				Due:        due,
+			})
+		}
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		if !refs[i].Due.Equal(refs[j].Due) {
+			return refs[i].Due.Before(refs[j].Due)
+		}
+
+		if refs[i].Planning.Uri != refs[j].Planning.Uri {
+			return refs[i].Planning.Uri < refs[j].Planning.Uri
+		}
+
+		return refs[i].Key < refs[j].Key
+	})
+
+	return refs
+}
+
+func documentTime(doc *Document) (time.Time, bool) {
+	switch {
+	case doc.Datetime != nil:
+		return *doc.Datetime, true
+	case doc.Date != nil:
+		return doc.Date.Time, true
+	}
+
+	return time.Time{}, false
+}
+
+// OpenAssignments returns the open assignments of the planning items that are
+// due before the given time, ordered by due time. Assignments without a due time are left
+// out.
+func OpenAssignments(plannings []Document, before time.Time) []AssignmentRef {
+	var open []AssignmentRef
+
+	for _, r := range PlanningAssignments(plannings) {
+		if r.IsOpen() && !r.Due.IsZero() && r.Due.Before(before) {
+			open = append(open, r)
+		}
+	}
+
+	return open
+}
+
+// Coverage describes how many of the assignments of a set of planning items
+// have been covered by content.
+type Coverage struct {
+	// Assignments is the number of assignments that haven't been
+	// canceled.
+	Assignments int
+	// Covered is the number of assignments with linked content.
+	Covered int
+	// Missing are the assignments without linked content.
+	Missing []AssignmentRef
+	// Content maps assignment URIs to the URIs of the content linked to
+	// them.
+	Content map[string][]string
+}
+
+// Ratio returns the share of covered assignments, or 1 if there are no
+// assignments.
+func (c Coverage) Ratio() float64 {
+	if c.Assignments == 0 {
+		return 1
+	}
+
+	return float64(c.Covered) / float64(c.Assignments)
+}
+
+// PlanningCoverage matches content to the assignments of planning items
+// through Replacing, see LinkContent, and reports which assignments have been
+// covered. Canceled assignments aren't counted.
+func PlanningCoverage(plannings []Document, content []Document) Coverage {
+	c := Coverage{
+		Content: make(map[string][]string),
+	}
+
+	for _, doc := range content {
+		for _, uri := range doc.Replacing {
+			c.Content[uri] = append(c.Content[uri], doc.Uri)
+		}
+	}
+
+	assignments := make(map[string]bool)
+
+	for _, r := range PlanningAssignments(plannings) {
+		assignments[r.Assignment.Uri] = true
+
+		if r.Assignment.Pubstatus == PubstatusCanceled {
+			continue
+		}
+
+		c.Assignments++
+
+		if len(c.Content[r.Assignment.Uri]) > 0 {
+			c.Covered++
+		} else {
+			c.Missing = append(c.Missing, r)
+		}
+	}
+
+	// Only keep content that was linked to one of the assignments.
+	for uri := range c.Content {
+		if !assignments[uri] {
+			delete(c.Content, uri)
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,290 @@
+package ttninjs
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+const planningURI = "http://tt.se/planning/1"
+
+func testPlanning(t *testing.T) Document {
+	t.Helper()
+
+	due := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	p := Document{
+		Uri:  planningURI,
+		Type: TypePlanning,
+		Date: &SerializableDate{Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for key, a := range map[string]Document{
+		"text":    {Type: TypeText, Datetime: &due},
+		"picture": {Type: TypePicture},
+	} {
+		err := AddAssignment(&p, key, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return p
+}
+
+func TestAssignmentURI(t *testing.T) {
+	uri, err := AssignmentURI(planningURI, "text")
+	if err != nil || uri != planningURI+"#text" {
+		t.Errorf("got %q %v", uri, err)
+	}
+
+	_, err = AssignmentURI("", "text")
+	if !errors.Is(err, ErrMissingURI) {
+		t.Errorf("got %v, want ErrMissingURI", err)
+	}
+
+	_, err = AssignmentURI(planningURI, "")
+	if !errors.Is(err, ErrAssignmentKey) {
+		t.Errorf("got %v, want ErrAssignmentKey", err)
+	}
+}
+
+func TestAddAssignment(t *testing.T) {
+	p := testPlanning(t)
+
+	a := p.Assignments["text"]
+	if a.Uri != planningURI+"#text" || a.Pubstatus != PubstatusCommissioned {
+		t.Errorf("got uri %q and pubstatus %q", a.Uri, a.Pubstatus)
+	}
+
+	err := AddAssignment(&p, "own", Document{
+		Type: TypeVideo, Uri: "http://tt.se/assignment/1", Pubstatus: PubstatusWithheld,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a := p.Assignments["own"]; a.Uri != "http://tt.se/assignment/1" || a.Pubstatus != PubstatusWithheld {
+		t.Errorf("got uri %q and pubstatus %q", a.Uri, a.Pubstatus)
+	}
+
+	cases := []struct {
+		name     string
+		planning Document
+		key      string
+		a        Document
+		want     error
+	}{
+		{"not planning", Document{Uri: "a", Type: TypeText}, "k", Document{Type: TypeText}, ErrNotPlanning},
+		{"empty key", p, "", Document{Type: TypeText}, ErrAssignmentKey},
+		{"exists", p, "text", Document{Type: TypeText}, ErrAssignmentExists},
+		{"event", p, "k", Document{Type: TypeEvent}, ErrAssignmentType},
+		{"no type", p, "k", Document{}, ErrAssignmentType},
+		{"no planning uri", Document{Type: TypePlanning}, "k", Document{Type: TypeText}, ErrMissingURI},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := AddAssignment(&c.planning, c.key, c.a)
+			if !errors.Is(err, c.want) {
+				t.Errorf("got %v, want %v", err, c.want)
+			}
+		})
+	}
+
+	// An assignment with its own URI doesn't need the planning URI.
+	np := Document{Type: TypePlanning}
+
+	err = AddAssignment(&np, "k", Document{Type: TypeText, Uri: "http://tt.se/assignment/2"})
+	if err != nil {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestUpdateAssignment(t *testing.T) {
+	p := testPlanning(t)
+
+	err := CancelAssignment(&p, "picture")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := p.Assignments["picture"].Pubstatus; got != PubstatusCanceled {
+		t.Errorf("got pubstatus %q", got)
+	}
+
+	err = UpdateAssignment(&p, "missing", func(_ *Document) {})
+	if !errors.Is(err, ErrNoAssignment) {
+		t.Errorf("got %v, want ErrNoAssignment", err)
+	}
+
+	notPlanning := Document{Uri: "a", Type: TypeText}
+
+	err = CancelAssignment(&notPlanning, "text")
+	if !errors.Is(err, ErrNotPlanning) {
+		t.Errorf("got %v, want ErrNotPlanning", err)
+	}
+}
+
+func TestLinkContent(t *testing.T) {
+	p := testPlanning(t)
+	assignment := planningURI + "#text"
+
+	content := Document{Uri: "http://tt.se/text/1", Replacing: []string{"http://tt.se/text/0"}}
+
+	for range 2 {
+		err := LinkContent(&p, "text", &content)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []string{"http://tt.se/text/0", assignment}; !slices.Equal(content.Replacing, want) {
+		t.Errorf("got replacing %v, want %v", content.Replacing, want)
+	}
+
+	if got := p.Assignments["text"].Pubstatus; got != PubstatusUsable {
+		t.Errorf("got pubstatus %q after linking", got)
+	}
+
+	err := UnlinkContent(&p, "text", &content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"http://tt.se/text/0"}; !slices.Equal(content.Replacing, want) {
+		t.Errorf("got replacing %v, want %v", content.Replacing, want)
+	}
+
+	if got := p.Assignments["text"].Pubstatus; got != PubstatusCommissioned {
+		t.Errorf("got pubstatus %q after unlinking", got)
+	}
+
+	// Unlinking content from a canceled assignment keeps it canceled.
+	picture := Document{Uri: "http://tt.se/picture/1"}
+
+	err = LinkContent(&p, "picture", &picture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = CancelAssignment(&p, "picture")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = UnlinkContent(&p, "picture", &picture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if picture.Replacing != nil {
+		t.Errorf("got replacing %v", picture.Replacing)
+	}
+
+	if got := p.Assignments["picture"].Pubstatus; got != PubstatusCanceled {
+		t.Errorf("got pubstatus %q", got)
+	}
+
+	for _, fn := range []func(*Document, string, *Document) error{LinkContent, UnlinkContent} {
+		err := fn(&p, "missing", &content)
+		if !errors.Is(err, ErrNoAssignment) {
+			t.Errorf("got %v, want ErrNoAssignment", err)
+		}
+	}
+}
+
+func TestPlanningAssignments(t *testing.T) {
+	early := time.Date(2024, 5, 31, 9, 0, 0, 0, time.UTC)
+
+	first := testPlanning(t)
+	second := Document{
+		Uri:  "http://tt.se/planning/2",
+		Type: TypePlanning,
+		Assignments: Assignments{
+			"b": {Uri: "http://tt.se/planning/2#b", Type: TypeText, Datetime: &early},
+			"a": {Uri: "http://tt.se/planning/2#a", Type: TypeText, Datetime: &early,
+				Pubstatus: PubstatusUsable},
+			"undated": {Uri: "http://tt.se/planning/2#undated", Type: TypeText},
+		},
+	}
+
+	plannings := []Document{first, second}
+
+	type ref struct {
+		planning, key string
+		due           time.Time
+	}
+
+	var got []ref
+
+	for _, r := range PlanningAssignments(plannings) {
+		got = append(got, ref{r.Planning.Uri, r.Key, r.Due})
+	}
+
+	// The picture assignment gets the date of the planning item.
+	want := []ref{
+		{"http://tt.se/planning/2", "undated", time.Time{}},
+		{"http://tt.se/planning/2", "a", early},
+		{"http://tt.se/planning/2", "b", early},
+		{planningURI, "picture", first.Date.Time},
+		{planningURI, "text", *first.Assignments["text"].Datetime},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	var open []string
+
+	for _, r := range OpenAssignments(plannings, first.Date.Time.Add(time.Hour)) {
+		open = append(open, r.Assignment.Uri)
+	}
+
+	if want := []string{"http://tt.se/planning/2#b", planningURI + "#picture"}; !slices.Equal(open, want) {
+		t.Errorf("got open %v, want %v", open, want)
+	}
+}
+
+func TestPlanningCoverage(t *testing.T) {
+	p := testPlanning(t)
+
+	err := AddAssignment(&p, "video", Document{Type: TypeVideo})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = CancelAssignment(&p, "video")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := Document{Uri: "http://tt.se/text/1"}
+	other := Document{Uri: "http://tt.se/text/2", Replacing: []string{"http://tt.se/text/0"}}
+
+	err = LinkContent(&p, "text", &text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := PlanningCoverage([]Document{p}, []Document{text, other})
+
+	if c.Assignments != 2 || c.Covered != 1 || c.Ratio() != 0.5 {
+		t.Errorf("got %d of %d covered, ratio %v", c.Covered, c.Assignments, c.Ratio())
+	}
+
+	if len(c.Missing) != 1 || c.Missing[0].Key != "picture" {
+		t.Errorf("got missing %+v", c.Missing)
+	}
+
+	want := map[string][]string{planningURI + "#text": {"http://tt.se/text/1"}}
+	if !reflect.DeepEqual(c.Content, want) {
+		t.Errorf("got content %v, want %v", c.Content, want)
+	}
+
+	if r := PlanningCoverage(nil, nil).Ratio(); r != 1 {
+		t.Errorf("got ratio %v without assignments", r)
+	}
+}
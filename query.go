@@ -0,0 +1,249 @@
+package ttninjs
+
+import (
+	"strings"
+)
+
+// Subjects is a filterable list of subjects.
+type Subjects []SubjectElem
+
+// Places is a filterable list of places.
+type Places []PlaceElem
+
+// Persons is a filterable list of persons.
+type Persons []PersonElem
+
+// Organisations is a filterable list of organisations.
+type Organisations []OrganisationElem
+
+// Subjects returns the subjects of the document. The method has a value
+// receiver so that it can be used directly on associations,
+// f.ex. doc.Associations["image1"].Subjects().
+func (j Document) Subjects() Subjects {
+	return Subjects(j.Subject)
+}
+
+// Places returns the places of the document.
+func (j Document) Places() Places {
+	return Places(j.Place)
+}
+
+// Persons returns the persons of the document.
+func (j Document) Persons() Persons {
+	return Persons(j.Person)
+}
+
+// Organisations returns the organisations of the document.
+func (j Document) Organisations() Organisations {
+	return Organisations(j.Organisation)
+}
+
+// sameScheme compares scheme URIs, ignoring any trailing slash.
+func sameScheme(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+func filterElems[S ~[]E, E any](s S, fn func(E) bool) S {
+	var res S
+
+	for _, e := range s {
+		if fn(e) {
+			res = append(res, e)
+		}
+	}
+
+	return res
+}
+
+func firstElem[S ~[]E, E any](s S) (E, bool) {
+	if len(s) == 0 {
+		var zero E
+
+		return zero, false
+	}
+
+	return s[0], true
+}
+
+func elemStrings[S ~[]E, E any](s S, fn func(E) string) []string {
+	res := make([]string, 0, len(s))
+
+	for _, e := range s {
+		res = append(res, fn(e))
+	}
+
+	return res
+}
+
+// Where returns the subjects that match fn.
+func (s Subjects) Where(fn func(SubjectElem) bool) Subjects {
+	return filterElems(s, fn)
+}
+
+// ByScheme returns the subjects in the given scheme.
+func (s Subjects) ByScheme(scheme string) Subjects {
+	return s.Where(func(e SubjectElem) bool {
+		return sameScheme(e.Scheme, scheme)
+	})
+}
+
+// ByRel returns the subjects with the given relationship.
+func (s Subjects) ByRel(rel string) Subjects {
+	return s.Where(func(e SubjectElem) bool {
+		return e.Rel == rel
+	})
+}
+
+// MinRelevance returns the subjects with a relevance of at least n. Subjects
+// without relevance are excluded.
+func (s Subjects) MinRelevance(n int) Subjects {
+	return s.Where(func(e SubjectElem) bool {
+		return e.Relevance != nil && *e.Relevance >= n
+	})
+}
+
+// MinConfidence returns the subjects with a confidence of at least
+// n. Subjects without confidence are excluded.
+func (s Subjects) MinConfidence(n int) Subjects {
+	return s.Where(func(e SubjectElem) bool {
+		return e.Confidence != nil && *e.Confidence >= n
+	})
+}
+
+// First returns the first subject in the list.
+func (s Subjects) First() (SubjectElem, bool) {
+	return firstElem(s)
+}
+
+// Codes returns the codes of the subjects.
+func (s Subjects) Codes() []string {
+	return elemStrings(s, func(e SubjectElem) string { return e.Code })
+}
+
+// Names returns the names of the subjects.
+func (s Subjects) Names() []string {
+	return elemStrings(s, func(e SubjectElem) string { return e.Name })
+}
+
+// Where returns the places that match fn.
+func (s Places) Where(fn func(PlaceElem) bool) Places {
+	return filterElems(s, fn)
+}
+
+// ByScheme returns the places in the given scheme.
+func (s Places) ByScheme(scheme string) Places {
+	return s.Where(func(e PlaceElem) bool {
+		return sameScheme(e.Scheme, scheme)
+	})
+}
+
+// ByRel returns the places with the given relationship, f.ex. "kommun".
+func (s Places) ByRel(rel string) Places {
+	return s.Where(func(e PlaceElem) bool {
+		return e.Rel == rel
+	})
+}
+
+// WithGeometry returns the places that have a GeoJSON geometry.
+func (s Places) WithGeometry() Places {
+	return s.Where(func(e PlaceElem) bool {
+		return e.GeometryGeojson != nil
+	})
+}
+
+// First returns the first place in the list.
+func (s Places) First() (PlaceElem, bool) {
+	return firstElem(s)
+}
+
+// Codes returns the codes of the places.
+func (s Places) Codes() []string {
+	return elemStrings(s, func(e PlaceElem) string { return e.Code })
+}
+
+// Names returns the names of the places.
+func (s Places) Names() []string {
+	return elemStrings(s, func(e PlaceElem) string { return e.Name })
+}
+
+// Where returns the persons that match fn.
+func (s Persons) Where(fn func(PersonElem) bool) Persons {
+	return filterElems(s, fn)
+}
+
+// ByScheme returns the persons in the given scheme.
+func (s Persons) ByScheme(scheme string) Persons {
+	return s.Where(func(e PersonElem) bool {
+		return sameScheme(e.Scheme, scheme)
+	})
+}
+
+// ByRel returns the persons with the given relationship.
+func (s Persons) ByRel(rel string) Persons {
+	return s.Where(func(e PersonElem) bool {
+		return e.Rel == rel
+	})
+}
+
+// First returns the first person in the list.
+func (s Persons) First() (PersonElem, bool) {
+	return firstElem(s)
+}
+
+// Codes returns the codes of the persons.
+func (s Persons) Codes() []string {
+	return elemStrings(s, func(e PersonElem) string { return e.Code })
+}
+
+// Names returns the names of the persons.
+func (s Persons) Names() []string {
+	return elemStrings(s, func(e PersonElem) string { return e.Name })
+}
+
+// Where returns the organisations that match fn.
+func (s Organisations) Where(fn func(OrganisationElem) bool) Organisations {
+	return filterElems(s, fn)
+}
+
+// ByScheme returns the organisations in the given scheme.
+func (s Organisations) ByScheme(scheme string) Organisations {
+	return s.Where(func(e OrganisationElem) bool {
+		return sameScheme(e.Scheme, scheme)
+	})
+}
+
+// ByRel returns the organisations with the given relationship.
+func (s Organisations) ByRel(rel string) Organisations {
+	return s.Where(func(e OrganisationElem) bool {
+		return e.Rel == rel
+	})
+}
+
+// WithTicker returns the organisations that have a financial instrument with
+// the given ticker symbol, f.ex. "ERIC B".
+func (s Organisations) WithTicker(ticker string) Organisations {
+	return s.Where(func(e OrganisationElem) bool {
+		for _, sym := range e.Symbols {
+			if sym.Ticker == ticker {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// First returns the first organisation in the list.
+func (s Organisations) First() (OrganisationElem, bool) {
+	return firstElem(s)
+}
+
+// Codes returns the codes of the organisations.
+func (s Organisations) Codes() []string {
+	return elemStrings(s, func(e OrganisationElem) string { return e.Code })
+}
+
+// Names returns the names of the organisations.
+func (s Organisations) Names() []string {
+	return elemStrings(s, func(e OrganisationElem) string { return e.Name })
+}
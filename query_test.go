@@ -0,0 +1,156 @@
+package ttninjs
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSubjectsQuery(t *testing.T) {
+	n := func(v int) *int { return &v }
+
+	doc := Document{
+		Uri: "a",
+		Subject: []SubjectElem{
+			{Scheme: "http://cv.iptc.org/newscodes/mediatopic/", Code: "01000000",
+				Name: "kultur", Rel: "about", Relevance: n(90), Confidence: n(40)},
+			{Scheme: "http://cv.iptc.org/newscodes/mediatopic", Code: "20000002",
+				Name: "film", Relevance: n(50), Confidence: n(80)},
+			{Scheme: "http://tt.se/spec/keyword/", Code: "bio", Name: "bio", Rel: "about"},
+		},
+	}
+
+	cases := []struct {
+		name string
+		got  Subjects
+		want []string
+	}{
+		{"scheme", doc.Subjects().ByScheme("http://cv.iptc.org/newscodes/mediatopic/"),
+			[]string{"01000000", "20000002"}},
+		{"scheme without slash", doc.Subjects().ByScheme("http://tt.se/spec/keyword"),
+			[]string{"bio"}},
+		{"rel", doc.Subjects().ByRel("about"), []string{"01000000", "bio"}},
+		{"relevance", doc.Subjects().MinRelevance(50), []string{"01000000", "20000002"}},
+		{"high relevance", doc.Subjects().MinRelevance(91), []string{}},
+		{"confidence", doc.Subjects().MinConfidence(50), []string{"20000002"}},
+		{"chained", doc.Subjects().ByRel("about").MinRelevance(0), []string{"01000000"}},
+		{"where", doc.Subjects().Where(func(e SubjectElem) bool {
+			return e.Name == "film"
+		}), []string{"20000002"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.got.Codes(); !slices.Equal(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	if got := doc.Subjects().Names(); !slices.Equal(got, []string{"kultur", "film", "bio"}) {
+		t.Errorf("got names %v", got)
+	}
+
+	if s, ok := doc.Subjects().ByRel("about").First(); !ok || s.Code != "01000000" {
+		t.Errorf("got first %+v %v", s, ok)
+	}
+
+	if _, ok := doc.Subjects().ByRel("missing").First(); ok {
+		t.Error("didn't expect a first subject")
+	}
+}
+
+func TestPlacesQuery(t *testing.T) {
+	doc := Document{
+		Uri: "a",
+		Place: []PlaceElem{
+			{Scheme: "http://tt.se/spec/place/", Code: "0180", Name: "Stockholm",
+				Rel: "kommun", GeometryGeojson: NewPoint(18.07, 59.33)},
+			{Scheme: "http://tt.se/spec/place/", Code: "01", Name: "Stockholms län", Rel: "lan"},
+			{Name: "Slussen"},
+		},
+	}
+
+	places := doc.Places()
+
+	if got := places.ByRel("kommun").Names(); !slices.Equal(got, []string{"Stockholm"}) {
+		t.Errorf("got %v", got)
+	}
+
+	if got := places.ByScheme("http://tt.se/spec/place").Codes(); !slices.Equal(got, []string{"0180", "01"}) {
+		t.Errorf("got %v", got)
+	}
+
+	if got := places.WithGeometry().Names(); !slices.Equal(got, []string{"Stockholm"}) {
+		t.Errorf("got %v", got)
+	}
+
+	if p, ok := places.First(); !ok || p.Code != "0180" {
+		t.Errorf("got first %+v %v", p, ok)
+	}
+
+	// Associations can be queried without taking their address.
+	doc.Associations = Associations{"image1": {Uri: "b", Place: doc.Place[2:]}}
+
+	if got := doc.Associations["image1"].Places().Names(); !slices.Equal(got, []string{"Slussen"}) {
+		t.Errorf("got %v", got)
+	}
+
+	if got := (Document{}).Places().Codes(); got == nil || len(got) != 0 {
+		t.Errorf("got %#v for a document without places", got)
+	}
+}
+
+func TestPersonsQuery(t *testing.T) {
+	doc := Document{
+		Uri: "a",
+		Person: []PersonElem{
+			{Scheme: "http://tt.se/spec/person/", Code: "p1", Name: "Anna Andersson", Rel: "about"},
+			{Scheme: "urn:example:person", Code: "p2", Name: "Bo Ek"},
+		},
+	}
+
+	if got := doc.Persons().ByRel("about").Names(); !slices.Equal(got, []string{"Anna Andersson"}) {
+		t.Errorf("got %v", got)
+	}
+
+	if got := doc.Persons().ByScheme("urn:example:person").Codes(); !slices.Equal(got, []string{"p2"}) {
+		t.Errorf("got %v", got)
+	}
+
+	if _, ok := (Document{}).Persons().First(); ok {
+		t.Error("didn't expect a first person")
+	}
+}
+
+func TestOrganisationsQuery(t *testing.T) {
+	doc := Document{
+		Uri: "a",
+		Organisation: []OrganisationElem{
+			{Scheme: "http://tt.se/spec/organisation/", Code: "o1", Name: "Ericsson", Rel: "about",
+				Symbols: []OrganisationElemSymbolsElem{
+					{Ticker: "ERIC A"}, {Ticker: "ERIC B"},
+				}},
+			{Scheme: "http://tt.se/spec/organisation/", Code: "o2", Name: "Volvo",
+				Symbols: []OrganisationElemSymbolsElem{{Ticker: "VOLV B"}}},
+			{Code: "o3", Name: "Riksdagen"},
+		},
+	}
+
+	orgs := doc.Organisations()
+
+	if got := orgs.WithTicker("ERIC B").Names(); !slices.Equal(got, []string{"Ericsson"}) {
+		t.Errorf("got %v", got)
+	}
+
+	if got := orgs.WithTicker("ERIC").Names(); len(got) != 0 {
+		t.Errorf("got %v for a partial ticker", got)
+	}
+
+	if got := orgs.ByScheme("http://tt.se/spec/organisation").Codes(); !slices.Equal(got, []string{"o1", "o2"}) {
+		t.Errorf("got %v", got)
+	}
+
+	if o, ok := orgs.ByRel("about").First(); !ok || o.Code != "o1" {
+		t.Errorf("got first %+v %v", o, ok)
+	}
+}
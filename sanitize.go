@@ -0,0 +1,395 @@
+package ttninjs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SanitizePolicy is an allowlist of the elements and attributes that may
+// appear in a body.
+type SanitizePolicy struct {
+	// Elements maps allowed tag names to the attributes allowed for them,
+	// in addition to GlobalAttributes.
+	Elements map[string][]string
+	// GlobalAttributes are allowed on all elements.
+	GlobalAttributes []string
+	// DropContent lists disallowed elements that are removed together with
+	// their content. Other disallowed elements are replaced by their
+	// content.
+	DropContent []string
+	// URLSchemes are the schemes allowed in URL attributes. Relative URLs
+	// are always allowed.
+	URLSchemes []string
+	// RenditionUsage is the preferred rendition usage, f.ex. "Preview",
+	// when an embedded media element is rewritten to point to an
+	// association.
+	RenditionUsage string
+}
+
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "cite": true, "poster": true,
+}
+
+// mediaElements are the elements that embed renditions of associations.
+var mediaElements = map[string]bool{
+	"img": true, "video": true, "audio": true, "source": true,
+}
+
+// BodyHTML5Policy returns a policy matching TT's body_html5 profile.
+func BodyHTML5Policy() *SanitizePolicy {
+	return &SanitizePolicy{
+		Elements: map[string][]string{
+			"body": nil, "section": nil, "div": nil, "span": nil,
+			"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+			"p": nil, "br": nil, "hr": nil, "pre": nil,
+			"strong": nil, "b": nil, "em": nil, "i": nil, "u": nil, "s": nil,
+			"sub": nil, "sup": nil, "small": nil, "mark": nil, "code": nil,
+			"abbr":       nil,
+			"time":       {"datetime"},
+			"q":          {"cite"},
+			"blockquote": {"cite"},
+			"ul":         nil,
+			"ol":         {"start", "type"},
+			"li":         nil,
+			"dl":         nil, "dt": nil, "dd": nil,
+			"a":          {"href", "rel", "target"},
+			"figure":     nil,
+			"figcaption": nil,
+			"img":        {"src", "alt", "width", "height"},
+			"table":      nil, "caption": nil, "thead": nil, "tbody": nil,
+			"tfoot": nil, "tr": nil,
+			"th": {"colspan", "rowspan", "scope"},
+			"td": {"colspan", "rowspan"},
+		},
+		GlobalAttributes: []string{"class", "id", "lang", "dir", "title"},
+		DropContent: []string{
+			"script", "style", "iframe", "object", "embed", "form",
+			"template", "noscript", "head", "title", "meta", "link",
+		},
+		URLSchemes:     []string{"http", "https", "mailto"},
+		RenditionUsage: "Preview",
+	}
+}
+
+// BodyRichHTML5Policy returns a policy matching TT's body_richhtml5 profile,
+// which also allows video and audio.
+func BodyRichHTML5Policy() *SanitizePolicy {
+	p := BodyHTML5Policy()
+
+	p.Elements["aside"] = nil
+	p.Elements["details"] = nil
+	p.Elements["summary"] = nil
+	p.Elements["video"] = []string{"src", "poster", "controls", "width", "height"}
+	p.Elements["audio"] = []string{"src", "controls"}
+	p.Elements["source"] = []string{"src", "type"}
+
+	return p
+}
+
+// SanitizeAction describes a change made by the sanitizer.
+type SanitizeAction struct {
+	// Field is the body that was changed, "body_html5" or
+	// "body_richhtml5".
+	Field string
+	// Action is one of "remove-element", "unwrap-element",
+	// "remove-attribute", "remove-rendition" and "rewrite-rendition".
+	Action string
+	// Tag is the name of the element, or "!--" for comments.
+	Tag   string
+	Attr  string
+	Value string
+}
+
+func (a SanitizeAction) String() string {
+	switch a.Action {
+	case "remove-attribute":
+		return fmt.Sprintf("%s: removed attribute %s=%q from <%s>",
+			a.Field, a.Attr, a.Value, a.Tag)
+	case "remove-rendition":
+		return fmt.Sprintf("%s: removed <%s> with unresolved %s %q",
+			a.Field, a.Tag, a.Attr, a.Value)
+	case "rewrite-rendition":
+		return fmt.Sprintf("%s: rewrote <%s> %s to %q",
+			a.Field, a.Tag, a.Attr, a.Value)
+	case "unwrap-element":
+		return fmt.Sprintf("%s: replaced <%s> with its content", a.Field, a.Tag)
+	}
+
+	if a.Tag == "!--" {
+		return a.Field + ": removed comment"
+	}
+
+	return fmt.Sprintf("%s: removed <%s>", a.Field, a.Tag)
+}
+
+// SanitizeBody removes disallowed elements and attributes, including scripts,
+// event handlers and unsafe URLs, from BodyHtml5 and BodyRichhtml5.
+//
+// Embedded media (img, video, audio and source) must point to a rendition of
+// one of the associations of the document. If the src doesn't match any
+// rendition, but the element or its enclosing figure has an id or a
+// data-association attribute naming an association, the src is rewritten to
+// the preferred rendition of that association, provided that its URL is
+// allowed by the policy. Otherwise the element is removed, together with its
+// figure.
+func SanitizeBody(doc *Document, policy *SanitizePolicy) []SanitizeAction {
+	s := sanitizer{
+		policy:     policy,
+		doc:        doc,
+		renditions: renditionHrefs(doc.Associations),
+	}
+
+	if doc.BodyHtml5 != "" {
+		doc.BodyHtml5 = s.sanitize("body_html5", doc.BodyHtml5)
+	}
+
+	if doc.BodyRichhtml5 != "" {
+		doc.BodyRichhtml5 = s.sanitize("body_richhtml5", doc.BodyRichhtml5)
+	}
+
+	return s.actions
+}
+
+func renditionHrefs(assoc Associations) map[string]bool {
+	hrefs := make(map[string]bool)
+
+	for _, a := range assoc {
+		for _, r := range a.Renditions {
+			if r.Href != "" {
+				hrefs[r.Href] = true
+			}
+		}
+	}
+
+	return hrefs
+}
+
+type sanitizer struct {
+	policy     *SanitizePolicy
+	doc        *Document
+	renditions map[string]bool
+
+	field   string
+	actions []SanitizeAction
+}
+
+func (s *sanitizer) record(a SanitizeAction) {
+	a.Field = s.field
+	s.actions = append(s.actions, a)
+}
+
+func (s *sanitizer) sanitize(field string, body string) string {
+	s.field = field
+
+	root := parseHTML(body)
+
+	s.children(root)
+
+	return renderHTML(root)
+}
+
+// children sanitises the children of a node, replacing the list of children.
+func (s *sanitizer) children(n *htmlNode) {
+	var kept []*htmlNode
+
+	for _, c := range n.Children {
+		kept = append(kept, s.node(c)...)
+	}
+
+	for _, c := range kept {
+		c.Parent = n
+	}
+
+	n.Children = kept
+}
+
+// node sanitises a node and returns the nodes that should replace it.
+func (s *sanitizer) node(n *htmlNode) []*htmlNode {
+	switch n.Type {
+	case htmlTextNode:
+		return []*htmlNode{n}
+	case htmlCommentNode:
+		s.record(SanitizeAction{Action: "remove-element", Tag: "!--"})
+
+		return nil
+	}
+
+	allowedAttrs, allowed := s.policy.Elements[n.Tag]
+
+	if !allowed {
+		if slices.Contains(s.policy.DropContent, n.Tag) {
+			s.record(SanitizeAction{Action: "remove-element", Tag: n.Tag})
+
+			return nil
+		}
+
+		s.record(SanitizeAction{Action: "unwrap-element", Tag: n.Tag})
+
+		var res []*htmlNode
+
+		for _, c := range n.Children {
+			res = append(res, s.node(c)...)
+		}
+
+		return res
+	}
+
+	s.attributes(n, allowedAttrs)
+
+	if mediaElements[n.Tag] && !s.resolveMedia(n) {
+		return nil
+	}
+
+	hadMedia := containsMedia(n)
+
+	s.children(n)
+
+	// Remove figures that lost their media.
+	if n.Tag == "figure" && hadMedia && !containsMedia(n) {
+		s.record(SanitizeAction{Action: "remove-element", Tag: n.Tag})
+
+		return nil
+	}
+
+	return []*htmlNode{n}
+}
+
+func containsMedia(n *htmlNode) bool {
+	for _, c := range n.Children {
+		if mediaElements[c.Tag] || containsMedia(c) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *sanitizer) attributes(n *htmlNode, allowed []string) {
+	var kept []htmlAttr
+
+	for _, a := range n.Attrs {
+		ok := slices.Contains(allowed, a.Key) ||
+			slices.Contains(s.policy.GlobalAttributes, a.Key) ||
+			((mediaElements[n.Tag] || n.Tag == "figure") && a.Key == "data-association")
+
+		if ok && urlAttributes[a.Key] && !s.safeURL(a.Val) {
+			ok = false
+		}
+
+		if !ok {
+			s.record(SanitizeAction{
+				Action: "remove-attribute",
+				Tag:    n.Tag,
+				Attr:   a.Key,
+				Value:  a.Val,
+			})
+
+			continue
+		}
+
+		kept = append(kept, a)
+	}
+
+	n.Attrs = kept
+}
+
+func (s *sanitizer) safeURL(u string) bool {
+	u = strings.TrimSpace(u)
+
+	colon := strings.IndexByte(u, ':')
+	if colon == -1 {
+		return true
+	}
+
+	// A colon after a path, query or fragment delimiter isn't a scheme.
+	if slash := strings.IndexAny(u, "/?#"); slash != -1 && slash < colon {
+		return true
+	}
+
+	scheme := strings.ToLower(u[:colon])
+
+	return slices.Contains(s.policy.URLSchemes, scheme)
+}
+
+// resolveMedia checks that a media element points to an association rendition,
+// rewriting it if possible. Returns false if the element should be removed.
+func (s *sanitizer) resolveMedia(n *htmlNode) bool {
+	src, hasSrc := n.attr("src")
+
+	// Video and audio elements can use source children instead of src.
+	if !hasSrc && (n.Tag == "video" || n.Tag == "audio") {
+		return true
+	}
+
+	if hasSrc && s.renditions[src] {
+		return true
+	}
+
+	key := associationReference(n)
+	if assoc, ok := s.doc.Associations[key]; ok && key != "" {
+		href := preferredRendition(assoc.Renditions, s.policy.RenditionUsage)
+		if href != "" && s.safeURL(href) {
+			n.setAttr("src", href)
+
+			s.record(SanitizeAction{
+				Action: "rewrite-rendition",
+				Tag:    n.Tag,
+				Attr:   "src",
+				Value:  href,
+			})
+
+			return true
+		}
+	}
+
+	s.record(SanitizeAction{
+		Action: "remove-rendition",
+		Tag:    n.Tag,
+		Attr:   "src",
+		Value:  src,
+	})
+
+	return false
+}
+
+// associationReference finds the association key referenced by a media element
+// or its enclosing figure.
+func associationReference(n *htmlNode) string {
+	for e := n; e != nil && e.Type == htmlElementNode; e = e.Parent {
+		if v, ok := e.attr("data-association"); ok {
+			return v
+		}
+
+		if v, ok := e.attr("id"); ok && (e == n || e.Tag == "figure") {
+			return v
+		}
+
+		if e.Tag == "figure" {
+			break
+		}
+	}
+
+	return ""
+}
+
+// preferredRendition returns the href of the rendition with the given usage,
+// or of the first rendition by name if there's none with that usage.
+func preferredRendition(renditions Renditions, usage string) string {
+	names := sortedRenditionNames(renditions)
+
+	for _, name := range names {
+		r := renditions[name]
+		if r.Href != "" && strings.EqualFold(r.Usage, usage) {
+			return r.Href
+		}
+	}
+
+	for _, name := range names {
+		if href := renditions[name].Href; href != "" {
+			return href
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,74 @@
+package ttninjs
+
+import (
+	"strings"
+	"testing"
+)
+
+func sanitizeTestDocument(body string, href string) *Document {
+	return &Document{
+		Uri:       "http://tt.se/text/1",
+		BodyHtml5: body,
+		Associations: Associations{
+			"img1": Document{
+				Uri:  "http://tt.se/media/image/1",
+				Type: TypePicture,
+				Renditions: Renditions{
+					"preview": {Href: href, Usage: "Preview"},
+				},
+			},
+		},
+	}
+}
+
+func TestSanitizeBodyFigureAssociation(t *testing.T) {
+	doc := sanitizeTestDocument(
+		`<figure data-association="img1"><img src="http://example.com/a.jpg" alt="Bild"></figure>`,
+		"https://tt.se/media/1-preview.jpg")
+
+	actions := SanitizeBody(doc, BodyHTML5Policy())
+
+	want := `<figure data-association="img1"><img src="https://tt.se/media/1-preview.jpg" alt="Bild"></figure>`
+	if doc.BodyHtml5 != want {
+		t.Errorf("got %s, expected %s", doc.BodyHtml5, want)
+	}
+
+	if len(actions) != 1 || actions[0].Action != "rewrite-rendition" {
+		t.Errorf("unexpected actions %v", actions)
+	}
+}
+
+func TestSanitizeBodyUnsafeRendition(t *testing.T) {
+	doc := sanitizeTestDocument(
+		`<p>Text</p><figure id="img1"><img src="http://example.com/a.jpg"></figure>`,
+		"javascript:alert(1)")
+
+	actions := SanitizeBody(doc, BodyHTML5Policy())
+
+	if doc.BodyHtml5 != "<p>Text</p>" {
+		t.Errorf("unsafe rendition was kept: %s", doc.BodyHtml5)
+	}
+
+	for _, a := range actions {
+		if a.Action == "rewrite-rendition" {
+			t.Errorf("src was rewritten to %q", a.Value)
+		}
+	}
+}
+
+func TestSanitizeBodyUnsafeAttributes(t *testing.T) {
+	doc := sanitizeTestDocument(
+		`<p onclick="x()">A <a href="javascript:alert(1)">länk</a><script>x()</script></p>`,
+		"https://tt.se/media/1-preview.jpg")
+
+	SanitizeBody(doc, BodyHTML5Policy())
+
+	want := "<p>A <a>länk</a></p>"
+	if doc.BodyHtml5 != want {
+		t.Errorf("got %s, expected %s", doc.BodyHtml5, want)
+	}
+
+	if strings.Contains(doc.BodyHtml5, "javascript") {
+		t.Errorf("unsafe URL was kept")
+	}
+}
@@ -0,0 +1,261 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// Supported JWS algorithms.
+const (
+	AlgEdDSA = "EdDSA"
+	AlgES256 = "ES256"
+	AlgES384 = "ES384"
+)
+
+// Key is a signing or verification key loaded from a JWK.
+type Key struct {
+	ID  string
+	Alg string
+
+	// Public is an ed25519.PublicKey or an *ecdsa.PublicKey.
+	Public crypto.PublicKey
+	// Private is nil for public keys.
+	Private crypto.Signer
+}
+
+// KeySet is a set of keys, typically the public keys of a publisher.
+type KeySet struct {
+	Keys []Key
+}
+
+// Key returns the key with the given ID.
+func (ks *KeySet) Key(id string) (Key, bool) {
+	if ks == nil {
+		return Key{}, false
+	}
+
+	for _, k := range ks.Keys {
+		if k.ID == id {
+			return k, true
+		}
+	}
+
+	return Key{}, false
+}
+
+// Public returns a key set with the private parts of the keys removed, suitable
+// for publishing.
+func (ks *KeySet) Public() *KeySet {
+	if ks == nil {
+		return &KeySet{}
+	}
+
+	pub := KeySet{Keys: make([]Key, len(ks.Keys))}
+
+	for i, k := range ks.Keys {
+		k.Private = nil
+		pub.Keys[i] = k
+	}
+
+	return &pub
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	D   string `json:"d,omitempty"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// LoadKeySet reads a JWK set ({"keys": [...]}) or a single JWK from a file.
+func LoadKeySet(name string) (*KeySet, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	ks, err := ParseKeySet(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", name, err)
+	}
+
+	return ks, nil
+}
+
+// ParseKeySet parses a JWK set or a single JWK.
+func ParseKeySet(data []byte) (*KeySet, error) {
+	var set jwkSet
+
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if set.Keys == nil {
+		var single jwk
+
+		err := json.Unmarshal(data, &single)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+
+		set.Keys = []jwk{single}
+	}
+
+	var ks KeySet
+
+	for i, j := range set.Keys {
+		k, err := parseJWK(j)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+
+		ks.Keys = append(ks.Keys, k)
+	}
+
+	return &ks, nil
+}
+
+func parseJWK(j jwk) (Key, error) {
+	switch {
+	case j.Kty == "OKP" && j.Crv == "Ed25519":
+		return parseEd25519(j)
+	case j.Kty == "EC" && j.Crv == "P-256":
+		return parseECDSA(j, elliptic.P256(), AlgES256)
+	case j.Kty == "EC" && j.Crv == "P-384":
+		return parseECDSA(j, elliptic.P384(), AlgES384)
+	}
+
+	return Key{}, fmt.Errorf("unsupported key type %q with curve %q", j.Kty, j.Crv)
+}
+
+func decodeParam(name, value string) ([]byte, error) {
+	if value == "" {
+		return nil, fmt.Errorf("missing parameter %q", name)
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameter %q: %w", name, err)
+	}
+
+	return b, nil
+}
+
+func parseEd25519(j jwk) (Key, error) {
+	x, err := decodeParam("x", j.X)
+	if err != nil {
+		return Key{}, err
+	}
+
+	if len(x) != ed25519.PublicKeySize {
+		return Key{}, errors.New("invalid Ed25519 public key size")
+	}
+
+	k := Key{
+		ID:     j.Kid,
+		Alg:    AlgEdDSA,
+		Public: ed25519.PublicKey(x),
+	}
+
+	if j.D != "" {
+		d, err := decodeParam("d", j.D)
+		if err != nil {
+			return Key{}, err
+		}
+
+		if len(d) != ed25519.SeedSize {
+			return Key{}, errors.New("invalid Ed25519 private key size")
+		}
+
+		priv := ed25519.NewKeyFromSeed(d)
+
+		if !k.Public.(ed25519.PublicKey).Equal(priv.Public()) {
+			return Key{}, errors.New("private key doesn't match the public key")
+		}
+
+		k.Private = priv
+	}
+
+	return k, nil
+}
+
+func parseECDSA(j jwk, curve elliptic.Curve, alg string) (Key, error) {
+	x, err := decodeParam("x", j.X)
+	if err != nil {
+		return Key{}, err
+	}
+
+	y, err := decodeParam("y", j.Y)
+	if err != nil {
+		return Key{}, err
+	}
+
+	pub := ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+
+	// The conversion to a crypto/ecdh key checks that the point is on the
+	// curve.
+	public, err := pub.ECDH()
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	k := Key{
+		ID:     j.Kid,
+		Alg:    alg,
+		Public: &pub,
+	}
+
+	if j.D != "" {
+		d, err := decodeParam("d", j.D)
+		if err != nil {
+			return Key{}, err
+		}
+
+		if len(d) > (curve.Params().BitSize+7)/8 {
+			return Key{}, errors.New("invalid private key size")
+		}
+
+		priv := &ecdsa.PrivateKey{
+			PublicKey: pub,
+			D:         new(big.Int).SetBytes(d),
+		}
+
+		// Derive the public key from d through crypto/ecdh, which also
+		// checks that d is in range.
+		derived, err := priv.ECDH()
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid private key: %w", err)
+		}
+
+		if !derived.PublicKey().Equal(public) {
+			return Key{}, errors.New("private key doesn't match the public key")
+		}
+
+		k.Private = priv
+	}
+
+	return k, nil
+}
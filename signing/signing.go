@@ -0,0 +1,258 @@
+// Package signing creates and verifies detached JWS signatures (RFC 7515
+// appendix F) over the canonical JSON of documents.
+package signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ttab/ttninjs"
+)
+
+// TrustindicatorScheme is the scheme of the trust indicator that advertises
+// the verification endpoint of a signed document.
+const TrustindicatorScheme = "http://tt.se/spec/trustindicator/1.0/"
+
+// TrustindicatorCodeSigned is the trust indicator code for signed documents.
+const TrustindicatorCodeSigned = "signed"
+
+var (
+	ErrNoPrivateKey     = errors.New("key has no private part")
+	ErrMalformed        = errors.New("malformed detached JWS")
+	ErrUnknownKey       = errors.New("no matching key in key set")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrNotSigned        = errors.New("document has no signature")
+	ErrNoKeySet         = errors.New("no key set")
+)
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Options for SignDocument.
+type Options struct {
+	// VerificationURL, if set, is added to the document as a trust
+	// indicator before signing, so that receivers know where to find the
+	// keys needed to verify the signature.
+	VerificationURL string
+}
+
+// Sign creates a detached JWS for the document with the compact serialisation
+// "header..signature". The signature property of the document is not included
+// in the signed payload.
+func Sign(doc *ttninjs.Document, key Key) (string, error) {
+	if key.Private == nil {
+		return "", ErrNoPrivateKey
+	}
+
+	h, err := json.Marshal(header{Alg: key.Alg, Kid: key.ID})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWS header: %w", err)
+	}
+
+	encHeader := base64.RawURLEncoding.EncodeToString(h)
+
+	input, err := signingInput(doc, encHeader)
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := sign(key, input)
+	if err != nil {
+		return "", err
+	}
+
+	return encHeader + ".." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// SignDocument signs the document and stores the detached JWS in its
+// signature property.
+func SignDocument(doc *ttninjs.Document, key Key, opts Options) error {
+	if opts.VerificationURL != "" {
+		setTrustindicator(doc, opts.VerificationURL)
+	}
+
+	sig, err := Sign(doc, key)
+	if err != nil {
+		return err
+	}
+
+	doc.Signature = sig
+
+	return nil
+}
+
+func setTrustindicator(doc *ttninjs.Document, url string) {
+	ti := ttninjs.TrustindicatorElem{
+		Scheme: TrustindicatorScheme,
+		Code:   TrustindicatorCodeSigned,
+		Href:   url,
+		Title:  "Signature verification keys",
+	}
+
+	for i, existing := range doc.Trustindicator {
+		if existing.Scheme == TrustindicatorScheme &&
+			existing.Code == TrustindicatorCodeSigned {
+			doc.Trustindicator[i] = ti
+
+			return
+		}
+	}
+
+	doc.Trustindicator = append(doc.Trustindicator, ti)
+}
+
+// VerificationURL returns the verification endpoint advertised by a signed
+// document.
+func VerificationURL(doc *ttninjs.Document) (string, bool) {
+	for _, ti := range doc.Trustindicator {
+		if ti.Scheme == TrustindicatorScheme && ti.Code == TrustindicatorCodeSigned {
+			return ti.Href, ti.Href != ""
+		}
+	}
+
+	return "", false
+}
+
+// Verify checks a detached JWS against the document using the keys in the key
+// set.
+func Verify(doc *ttninjs.Document, sig string, keys *KeySet) error {
+	if keys == nil {
+		return ErrNoKeySet
+	}
+
+	encHeader, encSig, ok := strings.Cut(sig, "..")
+	if !ok || encHeader == "" || encSig == "" {
+		return ErrMalformed
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(encHeader)
+	if err != nil {
+		return fmt.Errorf("%w: invalid header encoding: %w", ErrMalformed, err)
+	}
+
+	var h header
+
+	err = json.Unmarshal(rawHeader, &h)
+	if err != nil {
+		return fmt.Errorf("%w: invalid header: %w", ErrMalformed, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil {
+		return fmt.Errorf("%w: invalid signature encoding: %w", ErrMalformed, err)
+	}
+
+	input, err := signingInput(doc, encHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys.Keys {
+		if k.Alg != h.Alg || (h.Kid != "" && k.ID != h.Kid) {
+			continue
+		}
+
+		if verify(k, input, signature) {
+			return nil
+		}
+
+		if h.Kid != "" {
+			return ErrInvalidSignature
+		}
+	}
+
+	if h.Kid != "" {
+		if _, ok := keys.Key(h.Kid); !ok {
+			return fmt.Errorf("%w: kid %q", ErrUnknownKey, h.Kid)
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// VerifyDocument verifies the signature stored in the document.
+func VerifyDocument(doc *ttninjs.Document, keys *KeySet) error {
+	if doc.Signature == "" {
+		return ErrNotSigned
+	}
+
+	return Verify(doc, doc.Signature, keys)
+}
+
+func signingInput(doc *ttninjs.Document, encHeader string) ([]byte, error) {
+	unsigned := *doc
+	unsigned.Signature = ""
+
+	payload, err := ttninjs.Canonicalize(&unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to canonicalize document: %w", err)
+	}
+
+	return []byte(encHeader + "." + base64.RawURLEncoding.EncodeToString(payload)), nil
+}
+
+func sign(key Key, input []byte) ([]byte, error) {
+	switch priv := key.Private.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(priv, input), nil
+	case *ecdsa.PrivateKey:
+		digest, size := ecdsaDigest(key.Alg, input)
+
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign: %w", err)
+		}
+
+		// JWS uses the fixed size R || S encoding rather than ASN.1.
+		sig := make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+
+		return sig, nil
+	}
+
+	return nil, fmt.Errorf("unsupported private key type %T", key.Private)
+}
+
+func verify(key Key, input []byte, sig []byte) bool {
+	switch pub := key.Public.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, input, sig)
+	case *ecdsa.PublicKey:
+		digest, size := ecdsaDigest(key.Alg, input)
+		if len(sig) != 2*size {
+			return false
+		}
+
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+
+		return ecdsa.Verify(pub, digest, r, s)
+	}
+
+	return false
+}
+
+// ecdsaDigest hashes the input for the algorithm and returns the digest and
+// the size of the signature components.
+func ecdsaDigest(alg string, input []byte) ([]byte, int) {
+	if alg == AlgES384 {
+		sum := sha512.Sum384(input)
+
+		return sum[:], 48
+	}
+
+	sum := sha256.Sum256(input)
+
+	return sum[:], 32
+}
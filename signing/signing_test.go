@@ -0,0 +1,148 @@
+package signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ttab/ttninjs"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func ed25519JWK(t *testing.T) (string, ed25519.PrivateKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fmt.Sprintf(`{"kty":"OKP","crv":"Ed25519","kid":"ed","x":%q,"d":%q}`,
+		b64(pub), b64(priv.Seed())), priv
+}
+
+func ecdsaJWK(t *testing.T) (string, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fmt.Sprintf(`{"kty":"EC","crv":"P-256","kid":"ec","x":%q,"y":%q,"d":%q}`,
+		b64(priv.X.FillBytes(make([]byte, 32))),
+		b64(priv.Y.FillBytes(make([]byte, 32))),
+		b64(priv.D.FillBytes(make([]byte, 32)))), priv
+}
+
+func TestSignAndVerify(t *testing.T) {
+	edKey, _ := ed25519JWK(t)
+	ecKey, _ := ecdsaJWK(t)
+
+	ks, err := ParseKeySet([]byte(`{"keys":[` + edKey + `,` + ecKey + `]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range ks.Keys {
+		t.Run(k.Alg, func(t *testing.T) {
+			doc := ttninjs.Document{Uri: "http://tt.se/text/1", Headline: "Rubrik"}
+
+			err := SignDocument(&doc, k, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = VerifyDocument(&doc, ks.Public())
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+
+			doc.Headline = "Ändrad rubrik"
+
+			err = VerifyDocument(&doc, ks.Public())
+			if !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature for a changed document, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyNilKeySet(t *testing.T) {
+	doc := ttninjs.Document{Uri: "http://tt.se/text/1", Signature: "e30..AAAA"}
+
+	err := VerifyDocument(&doc, nil)
+	if !errors.Is(err, ErrNoKeySet) {
+		t.Errorf("expected ErrNoKeySet, got %v", err)
+	}
+}
+
+func TestParseKeySetMismatchedPrivateKey(t *testing.T) {
+	edKey, _ := ed25519JWK(t)
+	_, otherEd := ed25519JWK(t)
+
+	ecKey, _ := ecdsaJWK(t)
+	_, otherEC := ecdsaJWK(t)
+
+	cases := map[string]string{
+		"Ed25519": strings.Replace(edKey, `"d":"`,
+			`"d":"`+b64(otherEd.Seed())+`","unused":"`, 1),
+		"ECDSA": strings.Replace(ecKey, `"d":"`,
+			`"d":"`+b64(otherEC.D.FillBytes(make([]byte, 32)))+`","unused":"`, 1),
+		"ECDSA zero": strings.Replace(ecKey, `"d":"`,
+			`"d":"`+b64(make([]byte, 32))+`","unused":"`, 1),
+	}
+
+	for name, key := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseKeySet([]byte(key))
+			if err == nil {
+				t.Fatal("expected an error for a private key that doesn't match")
+			}
+		})
+	}
+}
+
+func TestParseKeySetInvalidPublicKey(t *testing.T) {
+	_, priv := ecdsaJWK(t)
+
+	x := priv.X.FillBytes(make([]byte, 32))
+	y := priv.Y.FillBytes(make([]byte, 32))
+
+	offCurve := append([]byte(nil), y...)
+	offCurve[31] ^= 1
+
+	key := func(x, y []byte) string {
+		return fmt.Sprintf(`{"keys":[{"kty":"EC","crv":"P-256","x":%q,"y":%q}]}`,
+			b64(x), b64(y))
+	}
+
+	_, err := ParseKeySet([]byte(key(x, y)))
+	if err != nil {
+		t.Fatalf("public key: %v", err)
+	}
+
+	cases := map[string]string{
+		"off the curve": key(x, offCurve),
+		"zero":          key(make([]byte, 32), make([]byte, 32)),
+		"too long":      key(append([]byte{1}, x...), y),
+	}
+
+	for name, key := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseKeySet([]byte(key))
+			if err == nil {
+				t.Fatal("expected an error for an invalid public key")
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package ttninjs
+
+import (
+	"cmp"
+	"sort"
+)
+
+// SortPolicy determines the order of the priority fields when sorting
+// documents editorially.
+type SortPolicy int
+
+const (
+	// SortWebprio sorts on webprio, newsvalue and urgency, in that
+	// order. This follows the sort logic defined in
+	// http://tt.se/spec/webprio/1.0.
+	SortWebprio SortPolicy = iota
+	// SortNewsvalue sorts on newsvalue, urgency and webprio.
+	SortNewsvalue
+	// SortUrgency sorts on urgency, webprio and newsvalue.
+	SortUrgency
+)
+
+// CompareUrgency compares documents by urgency, most urgent first. Urgency is
+// 1-9 where 1 is most urgent, documents without urgency are sorted last.
+func CompareUrgency(a, b *Document) int {
+	return compareAscending(a.Urgency, b.Urgency, 1, 9)
+}
+
+// CompareWebprio compares documents by webprio, most important first. Webprio
+// is 1-3 where 1 is most important. A webprio of 0 means that the item needs
+// manual attention before publishing and is sorted last, see
+// SplitManualAttention.
+func CompareWebprio(a, b *Document) int {
+	return compareAscending(a.Webprio, b.Webprio, 1, 3)
+}
+
+// CompareNewsvalue compares documents by newsvalue, most important
+// first. Newsvalue is 6 (most important) to 1, documents without newsvalue are
+// sorted last.
+func CompareNewsvalue(a, b *Document) int {
+	av, bv := 0, 0
+
+	if a.Newsvalue != nil {
+		av = *a.Newsvalue
+	}
+
+	if b.Newsvalue != nil {
+		bv = *b.Newsvalue
+	}
+
+	// Flip the scale so that the most important value is the lowest.
+	return compareAscending(invertNewsvalue(av), invertNewsvalue(bv), 1, 6)
+}
+
+func invertNewsvalue(v int) int {
+	if v < 1 || v > 6 {
+		return 0
+	}
+
+	return 7 - v
+}
+
+// compareAscending compares priority values where lower values come first, and
+// values outside of the range [lo, hi] are sorted last.
+func compareAscending(a, b int, lo, hi int) int {
+	aValid := a >= lo && a <= hi
+	bValid := b >= lo && b <= hi
+
+	switch {
+	case aValid && !bValid:
+		return -1
+	case !aValid && bValid:
+		return 1
+	case !aValid && !bValid:
+		return 0
+	}
+
+	return cmp.Compare(a, b)
+}
+
+// CompareVersioncreated compares documents by versioncreated, newest first.
+func CompareVersioncreated(a, b *Document) int {
+	return b.Versioncreated.Compare(a.Versioncreated)
+}
+
+// LessUrgency reports whether a is more urgent than b.
+func LessUrgency(a, b *Document) bool {
+	return CompareUrgency(a, b) < 0
+}
+
+// LessWebprio reports whether a has a higher webprio than b.
+func LessWebprio(a, b *Document) bool {
+	return CompareWebprio(a, b) < 0
+}
+
+// LessNewsvalue reports whether a has a higher newsvalue than b.
+func LessNewsvalue(a, b *Document) bool {
+	return CompareNewsvalue(a, b) < 0
+}
+
+// Compare compares two documents according to the policy. Documents with equal
+// priority are ordered by versioncreated, newest first, and then by URI to
+// make the order deterministic.
+func (p SortPolicy) Compare(a, b *Document) int {
+	var order []func(a, b *Document) int
+
+	switch p {
+	case SortNewsvalue:
+		order = []func(a, b *Document) int{
+			CompareNewsvalue, CompareUrgency, CompareWebprio,
+		}
+	case SortUrgency:
+		order = []func(a, b *Document) int{
+			CompareUrgency, CompareWebprio, CompareNewsvalue,
+		}
+	default:
+		order = []func(a, b *Document) int{
+			CompareWebprio, CompareNewsvalue, CompareUrgency,
+		}
+	}
+
+	for _, fn := range order {
+		if c := fn(a, b); c != 0 {
+			return c
+		}
+	}
+
+	if c := CompareVersioncreated(a, b); c != 0 {
+		return c
+	}
+
+	return cmp.Compare(a.Uri, b.Uri)
+}
+
+// Less reports whether a should be sorted before b according to the policy.
+func (p SortPolicy) Less(a, b *Document) bool {
+	return p.Compare(a, b) < 0
+}
+
+// SortEditorial sorts the documents in place according to the policy.
+func SortEditorial(docs []Document, policy SortPolicy) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		return policy.Less(&docs[i], &docs[j])
+	})
+}
+
+// NeedsManualAttention returns true if the document has a webprio of 0, which
+// means that it needs manual attention before publishing.
+func (j Document) NeedsManualAttention() bool {
+	return j.Webprio == 0
+}
+
+// SplitManualAttention separates the documents that need manual attention
+// from the ones that are ready for publishing, see NeedsManualAttention. The
+// relative order of the documents is kept.
+func SplitManualAttention(docs []Document) (ready []Document, manual []Document) {
+	for _, doc := range docs {
+		if doc.NeedsManualAttention() {
+			manual = append(manual, doc)
+		} else {
+			ready = append(ready, doc)
+		}
+	}
+
+	return ready, manual
+}
@@ -0,0 +1,134 @@
+package ttninjs
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func docURIs(docs []Document) []string {
+	var res []string
+
+	for _, d := range docs {
+		res = append(res, d.Uri)
+	}
+
+	return res
+}
+
+func TestComparePriorities(t *testing.T) {
+	n := func(v int) *int { return &v }
+
+	cases := []struct {
+		name string
+		fn   func(a, b *Document) int
+		a, b Document
+		want int
+	}{
+		{"urgency", CompareUrgency, Document{Urgency: 1}, Document{Urgency: 4}, -1},
+		{"same urgency", CompareUrgency, Document{Urgency: 4}, Document{Urgency: 4}, 0},
+		{"missing urgency", CompareUrgency, Document{}, Document{Urgency: 9}, 1},
+		{"urgency out of range", CompareUrgency, Document{Urgency: 10}, Document{}, 0},
+		{"webprio", CompareWebprio, Document{Webprio: 3}, Document{Webprio: 1}, 1},
+		{"webprio 0", CompareWebprio, Document{Webprio: 3}, Document{}, -1},
+		{"newsvalue", CompareNewsvalue, Document{Newsvalue: n(6)}, Document{Newsvalue: n(1)}, -1},
+		{"missing newsvalue", CompareNewsvalue, Document{}, Document{Newsvalue: n(1)}, 1},
+		{"newsvalue out of range", CompareNewsvalue, Document{Newsvalue: n(7)}, Document{Newsvalue: n(0)}, 0},
+		{"versioncreated", CompareVersioncreated,
+			Document{Versioncreated: time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)},
+			Document{Versioncreated: time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)}, -1},
+		{"versioncreated zones", CompareVersioncreated,
+			Document{Versioncreated: time.Date(2024, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 7200))},
+			Document{Versioncreated: time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)}, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.fn(&c.a, &c.b); got != c.want {
+				t.Errorf("got %d, expected %d", got, c.want)
+			}
+
+			if got := c.fn(&c.b, &c.a); got != -c.want {
+				t.Errorf("reversed: got %d, expected %d", got, -c.want)
+			}
+		})
+	}
+
+	a, b := Document{Urgency: 1, Webprio: 2}, Document{Urgency: 2, Webprio: 1}
+
+	if !LessUrgency(&a, &b) || LessWebprio(&a, &b) || LessNewsvalue(&a, &b) {
+		t.Error("unexpected result from the Less functions")
+	}
+}
+
+func TestSortEditorial(t *testing.T) {
+	n := func(v int) *int { return &v }
+	older := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	docs := []Document{
+		{Uri: "manual", Urgency: 1, Newsvalue: n(6)},
+		{Uri: "w2-u1", Webprio: 2, Urgency: 1, Versioncreated: older},
+		{Uri: "w1-n3", Webprio: 1, Newsvalue: n(3), Urgency: 5, Versioncreated: older},
+		{Uri: "w1-n5", Webprio: 1, Newsvalue: n(5), Urgency: 8, Versioncreated: older},
+		{Uri: "w1-n5-newer", Webprio: 1, Newsvalue: n(5), Urgency: 8, Versioncreated: newer},
+		{Uri: "w1-n5-b", Webprio: 1, Newsvalue: n(5), Urgency: 8, Versioncreated: older},
+		{Uri: "w3-n6-u4", Webprio: 3, Newsvalue: n(6), Urgency: 4, Versioncreated: older},
+	}
+
+	cases := []struct {
+		policy SortPolicy
+		want   []string
+	}{
+		{SortWebprio, []string{
+			"w1-n5-newer", "w1-n5", "w1-n5-b", "w1-n3", "w2-u1", "w3-n6-u4", "manual",
+		}},
+		{SortNewsvalue, []string{
+			"manual", "w3-n6-u4", "w1-n5-newer", "w1-n5", "w1-n5-b", "w1-n3", "w2-u1",
+		}},
+		{SortUrgency, []string{
+			"w2-u1", "manual", "w3-n6-u4", "w1-n3", "w1-n5-newer", "w1-n5", "w1-n5-b",
+		}},
+	}
+
+	for _, c := range cases {
+		// Sort a reversed copy to check that the order doesn't depend
+		// on the input.
+		sorted := slices.Clone(docs)
+		slices.Reverse(sorted)
+
+		SortEditorial(sorted, c.policy)
+
+		if got := docURIs(sorted); !slices.Equal(got, c.want) {
+			t.Errorf("policy %d: got %v, expected %v", c.policy, got, c.want)
+		}
+
+		if !c.policy.Less(&sorted[0], &sorted[1]) || c.policy.Less(&sorted[1], &sorted[0]) {
+			t.Errorf("policy %d: Less disagrees with the sort order", c.policy)
+		}
+	}
+}
+
+func TestSplitManualAttention(t *testing.T) {
+	docs := []Document{
+		{Uri: "text-1", Type: TypeText, Webprio: 1},
+		{Uri: "text-0", Type: TypeText},
+		{Uri: "picture", Type: TypePicture},
+		{Uri: "event", Type: TypeEvent},
+		{Uri: "planning", Type: TypePlanning},
+		{Uri: "text-3", Type: TypeText, Webprio: 3},
+		{Uri: "picture-2", Type: TypePicture, Webprio: 2},
+	}
+
+	ready, manual := SplitManualAttention(docs)
+
+	wantReady := []string{"text-1", "text-3", "picture-2"}
+	if got := docURIs(ready); !slices.Equal(got, wantReady) {
+		t.Errorf("ready: got %v, expected %v", got, wantReady)
+	}
+
+	wantManual := []string{"text-0", "picture", "event", "planning"}
+	if got := docURIs(manual); !slices.Equal(got, wantManual) {
+		t.Errorf("manual: got %v, expected %v", got, wantManual)
+	}
+}
@@ -0,0 +1,196 @@
+package sportsml
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ttab/ttninjs"
+)
+
+// Result formats the result of an event in TT style, f.ex. "AIK–Djurgården
+// 3–1". Events without a home and away team are formatted as the names of the
+// participants in order of rank.
+func Result(e Event) string {
+	home, okHome := e.Home()
+	away, okAway := e.Away()
+
+	if !okHome || !okAway {
+		if len(e.Teams) != 2 {
+			return participants(e)
+		}
+
+		home, away = e.Teams[0], e.Teams[1]
+	}
+
+	res := home.Metadata.Name.String() + "–" + away.Metadata.Name.String()
+
+	switch e.Metadata.EventStatus {
+	case StatusPostponed:
+		return res + " (uppskjuten)"
+	case StatusCancelled:
+		return res + " (inställd)"
+	case StatusPreEvent:
+		return res
+	}
+
+	if home.Stats.Score != "" && away.Stats.Score != "" {
+		res += " " + home.Stats.Score + "–" + away.Stats.Score
+	}
+
+	return res
+}
+
+func participants(e Event) string {
+	var names []string
+
+	for _, p := range sortedPlayers(e.Players) {
+		name := p.Metadata.Name.String()
+
+		if p.Stats.Rank.Value != "" {
+			name = p.Stats.Rank.Value + ") " + name
+		}
+
+		if p.Stats.Score != "" {
+			name += " " + p.Stats.Score
+		}
+
+		names = append(names, name)
+	}
+
+	for _, t := range e.Teams {
+		names = append(names, t.Metadata.Name.String())
+	}
+
+	if len(names) == 0 {
+		return e.Metadata.Name.String()
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// ResultsList returns the results of the events as a list that can be
+// inserted in a ttninjs.Body.
+func ResultsList(events []Event) *ttninjs.List {
+	var l ttninjs.List
+
+	for _, e := range events {
+		l.Items = append(l.Items, textContent(Result(e)))
+	}
+
+	return &l
+}
+
+// StandingsTable returns the standing as a table that can be inserted in a
+// ttninjs.Body. League tables have the columns rank, team, played, won,
+// drawn, lost, goals and points. Standings of individual events have the
+// columns rank, name and result.
+func StandingsTable(s Standing) *ttninjs.Table {
+	t := ttninjs.Table{}
+
+	if s.ContentLabel != "" {
+		t.Caption = textContent(s.ContentLabel)
+	}
+
+	if len(s.Teams) > 0 {
+		t.Head = []ttninjs.TableRow{headerRow("", "Lag", "S", "V", "O", "F", "Mål", "P")}
+
+		for _, team := range sortedTeams(s.Teams) {
+			st := team.Stats
+			goals := strconv.Itoa(st.Outcome.PointsFor) + "–" +
+				strconv.Itoa(st.Outcome.PointsAgainst)
+
+			t.Rows = append(t.Rows, dataRow(
+				st.Rank.Value,
+				team.Metadata.Name.String(),
+				st.EventsPlayed,
+				strconv.Itoa(st.Outcome.Wins),
+				strconv.Itoa(st.Outcome.Ties),
+				strconv.Itoa(st.Outcome.Losses),
+				goals,
+				st.StandingPoints,
+			))
+		}
+
+		return &t
+	}
+
+	t.Head = []ttninjs.TableRow{headerRow("", "Namn", "Resultat")}
+
+	for _, p := range sortedPlayers(s.Players) {
+		t.Rows = append(t.Rows, dataRow(
+			p.Stats.Rank.Value,
+			p.Metadata.Name.String(),
+			p.Stats.Score,
+		))
+	}
+
+	return &t
+}
+
+func textContent(s string) []ttninjs.Inline {
+	if s == "" {
+		return nil
+	}
+
+	return []ttninjs.Inline{&ttninjs.Text{Text: s}}
+}
+
+func headerRow(cells ...string) ttninjs.TableRow {
+	row := dataRow(cells...)
+
+	for i := range row.Cells {
+		row.Cells[i].Header = true
+	}
+
+	return row
+}
+
+func dataRow(cells ...string) ttninjs.TableRow {
+	var row ttninjs.TableRow
+
+	for _, c := range cells {
+		row.Cells = append(row.Cells, ttninjs.TableCell{
+			Content: textContent(c),
+		})
+	}
+
+	return row
+}
+
+// rankOrder orders numeric ranks before non-numeric and missing ranks.
+func rankOrder(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+
+	switch {
+	case errA == nil && errB == nil:
+		return na - nb
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+
+	return 0
+}
+
+func sortedTeams(teams []Team) []Team {
+	sorted := slices.Clone(teams)
+
+	slices.SortStableFunc(sorted, func(a, b Team) int {
+		return rankOrder(a.Stats.Rank.Value, b.Stats.Rank.Value)
+	})
+
+	return sorted
+}
+
+func sortedPlayers(players []Player) []Player {
+	sorted := slices.Clone(players)
+
+	slices.SortStableFunc(sorted, func(a, b Player) int {
+		return rankOrder(a.Stats.Rank.Value, b.Stats.Rank.Value)
+	})
+
+	return sorted
+}
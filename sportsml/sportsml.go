@@ -0,0 +1,323 @@
+// Package sportsml parses the SportsML-G2 results, fixtures and standings that
+// TT delivers in body_sportsml.
+//
+// Only the subset of SportsML used by TT is modelled: sports metadata, events
+// with their teams and players, and standings. Elements and attributes that
+// aren't modelled are ignored.
+package sportsml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ttab/ttninjs"
+)
+
+// ErrNoSportsML is returned by ParseSportsML for documents without a
+// SportsML body.
+var ErrNoSportsML = errors.New("document has no sportsml body")
+
+// Event statuses used in EventMetadata.EventStatus.
+const (
+	StatusPreEvent  = "pre-event"
+	StatusMidEvent  = "mid-event"
+	StatusPostEvent = "post-event"
+	StatusPostponed = "postponed"
+	StatusCancelled = "cancelled"
+)
+
+// Team alignments used in TeamMetadata.Alignment.
+const (
+	AlignmentHome = "home"
+	AlignmentAway = "away"
+)
+
+// SportsContent is the root element of a SportsML document.
+type SportsContent struct {
+	XMLName     xml.Name     `xml:"sports-content"`
+	Metadata    Metadata     `xml:"sports-metadata"`
+	Events      []Event      `xml:"sports-event"`
+	Standings   []Standing   `xml:"standing"`
+	Tournaments []Tournament `xml:"tournament"`
+}
+
+// Metadata describes a SportsML document.
+type Metadata struct {
+	DocID    string `xml:"doc-id,attr"`
+	DateTime string `xml:"date-time,attr"`
+	Language string `xml:"language,attr"`
+	Title    string `xml:"sports-title"`
+}
+
+// Tournament groups events and standings, f.ex. the rounds of a league.
+type Tournament struct {
+	Metadata  TournamentMetadata `xml:"tournament-metadata"`
+	Divisions []Tournament       `xml:"tournament-division"`
+	Rounds    []Tournament       `xml:"tournament-round"`
+	Events    []Event            `xml:"sports-event"`
+	Standings []Standing         `xml:"standing"`
+}
+
+// TournamentMetadata describes a tournament, division or round.
+type TournamentMetadata struct {
+	Key  string `xml:"key,attr"`
+	Name Name   `xml:"name"`
+}
+
+// Event is a match, race or other sports event.
+type Event struct {
+	Metadata EventMetadata `xml:"event-metadata"`
+	Teams    []Team        `xml:"team"`
+	Players  []Player      `xml:"player"`
+}
+
+// EventMetadata describes an event.
+type EventMetadata struct {
+	Key           string `xml:"key,attr"`
+	StartDateTime string `xml:"start-date-time,attr"`
+	EventStatus   string `xml:"event-status,attr"`
+	Name          Name   `xml:"name"`
+	Site          Name   `xml:"site>site-metadata>name"`
+}
+
+// Name is the name of a team, player, site or tournament.
+type Name struct {
+	Full         string `xml:"full,attr"`
+	First        string `xml:"first,attr"`
+	Last         string `xml:"last,attr"`
+	Abbreviation string `xml:"abbreviation,attr"`
+	Text         string `xml:",chardata"`
+}
+
+// String returns the full name, or the first and last names if there is no
+// full name.
+func (n Name) String() string {
+	switch {
+	case n.Full != "":
+		return n.Full
+	case n.First != "" || n.Last != "":
+		return strings.TrimSpace(n.First + " " + n.Last)
+	}
+
+	return strings.TrimSpace(n.Text)
+}
+
+// Team is a team taking part in an event or a standing.
+type Team struct {
+	Metadata TeamMetadata `xml:"team-metadata"`
+	Stats    Stats        `xml:"team-stats"`
+	Players  []Player     `xml:"player"`
+}
+
+// TeamMetadata describes a team.
+type TeamMetadata struct {
+	Key       string `xml:"key,attr"`
+	Alignment string `xml:"alignment,attr"`
+	Name      Name   `xml:"name"`
+}
+
+// Player is an individual athlete taking part in an event or a standing.
+type Player struct {
+	Metadata PlayerMetadata `xml:"player-metadata"`
+	Stats    Stats          `xml:"player-stats"`
+}
+
+// PlayerMetadata describes a player.
+type PlayerMetadata struct {
+	Key         string `xml:"key,attr"`
+	Nationality string `xml:"nationality,attr"`
+	Name        Name   `xml:"name"`
+}
+
+// Stats are the results of a team or player. Score is kept as a string as
+// it's a time or distance in many sports.
+type Stats struct {
+	Score          string        `xml:"score,attr"`
+	EventOutcome   string        `xml:"event-outcome,attr"`
+	StandingPoints string        `xml:"standing-points,attr"`
+	EventsPlayed   string        `xml:"events-played,attr"`
+	Outcome        OutcomeTotals `xml:"outcome-totals"`
+	Rank           Rank          `xml:"rank"`
+}
+
+// OutcomeTotals are the accumulated results of a team in a standing.
+type OutcomeTotals struct {
+	Wins          int `xml:"wins,attr"`
+	Losses        int `xml:"losses,attr"`
+	Ties          int `xml:"ties,attr"`
+	PointsFor     int `xml:"points-scored-for,attr"`
+	PointsAgainst int `xml:"points-scored-against,attr"`
+}
+
+// Rank is the position of a team or player.
+type Rank struct {
+	Value string `xml:"value,attr"`
+}
+
+// Standing is a league table or the result list of an individual event.
+type Standing struct {
+	ContentLabel string   `xml:"content-label,attr"`
+	Teams        []Team   `xml:"team"`
+	Players      []Player `xml:"player"`
+}
+
+// Home returns the home team of an event.
+func (e Event) Home() (Team, bool) {
+	return e.team(AlignmentHome)
+}
+
+// Away returns the away team of an event.
+func (e Event) Away() (Team, bool) {
+	return e.team(AlignmentAway)
+}
+
+func (e Event) team(alignment string) (Team, bool) {
+	for _, t := range e.Teams {
+		if t.Metadata.Alignment == alignment {
+			return t, true
+		}
+	}
+
+	return Team{}, false
+}
+
+// AllEvents returns the events of the document, including the events of its
+// tournaments.
+func (sc *SportsContent) AllEvents() []Event {
+	events := append([]Event(nil), sc.Events...)
+
+	for _, t := range sc.Tournaments {
+		events = t.appendEvents(events)
+	}
+
+	return events
+}
+
+// AllStandings returns the standings of the document, including the standings
+// of its tournaments.
+func (sc *SportsContent) AllStandings() []Standing {
+	standings := append([]Standing(nil), sc.Standings...)
+
+	for _, t := range sc.Tournaments {
+		standings = t.appendStandings(standings)
+	}
+
+	return standings
+}
+
+func (t Tournament) appendEvents(events []Event) []Event {
+	events = append(events, t.Events...)
+
+	for _, c := range t.Divisions {
+		events = c.appendEvents(events)
+	}
+
+	for _, c := range t.Rounds {
+		events = c.appendEvents(events)
+	}
+
+	return events
+}
+
+func (t Tournament) appendStandings(standings []Standing) []Standing {
+	standings = append(standings, t.Standings...)
+
+	for _, c := range t.Divisions {
+		standings = c.appendStandings(standings)
+	}
+
+	for _, c := range t.Rounds {
+		standings = c.appendStandings(standings)
+	}
+
+	return standings
+}
+
+// ParseSportsML parses the BodySportsml of a document. The body has already
+// been decoded from JSON to UTF-8, so the encoding in its XML declaration is
+// ignored.
+func ParseSportsML(doc *ttninjs.Document) (*SportsContent, error) {
+	if doc.BodySportsml == "" {
+		return nil, ErrNoSportsML
+	}
+
+	return decode(strings.NewReader(doc.BodySportsml), utf8CharsetReader)
+}
+
+// Parse parses a SportsML document from raw bytes, transcoding it to UTF-8
+// if it's declared as ISO-8859-1.
+func Parse(r io.Reader) (*SportsContent, error) {
+	// TT delivers SportsML files as UTF-8 or ISO-8859-1.
+	return decode(r, charsetReader)
+}
+
+func decode(
+	r io.Reader, cr func(charset string, input io.Reader) (io.Reader, error),
+) (*SportsContent, error) {
+	var sc SportsContent
+
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = cr
+
+	err := dec.Decode(&sc)
+	if err != nil {
+		return nil, fmt.Errorf("decode sportsml: %w", err)
+	}
+
+	return &sc, nil
+}
+
+// utf8CharsetReader is used for input that is known to be UTF-8 regardless of
+// the declared charset.
+func utf8CharsetReader(_ string, input io.Reader) (io.Reader, error) {
+	return input, nil
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii":
+		return input, nil
+	case "iso-8859-1", "latin1", "latin-1":
+		return &latin1Reader{r: input}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
+
+// latin1Reader converts ISO-8859-1 to UTF-8.
+type latin1Reader struct {
+	r   io.Reader
+	buf []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	if len(p) < 2 {
+		return 0, io.ErrShortBuffer
+	}
+
+	if cap(l.buf) < len(p)/2 {
+		l.buf = make([]byte, len(p)/2)
+	}
+
+	n, err := l.r.Read(l.buf[:len(p)/2])
+
+	out := 0
+
+	for _, c := range l.buf[:n] {
+		if c < 0x80 {
+			p[out] = c
+			out++
+
+			continue
+		}
+
+		p[out] = 0xc0 | c>>6
+		p[out+1] = 0x80 | c&0x3f
+		out += 2
+	}
+
+	return out, err
+}
@@ -0,0 +1,187 @@
+package sportsml
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ttab/ttninjs"
+)
+
+func parseFile(t *testing.T, name string) *SportsContent {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	sc, err := Parse(f)
+	if err != nil {
+		t.Fatalf("parse %s: %v", name, err)
+	}
+
+	return sc
+}
+
+func TestResult(t *testing.T) {
+	sc := parseFile(t, "allsvenskan.xml")
+
+	var got []string
+
+	for _, e := range sc.AllEvents() {
+		got = append(got, Result(e))
+	}
+
+	want := []string{
+		"AIK–Djurgården 3–1",
+		"Malmö FF–Halmstad 2–0",
+		"IFK Göteborg–Häcken (uppskjuten)",
+		"Hammarby–Mjällby",
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("got results\n%q\nexpected\n%q", got, want)
+	}
+
+	list := ResultsList(sc.AllEvents())
+	if len(list.Items) != len(want) {
+		t.Errorf("results list has %d items, expected %d", len(list.Items), len(want))
+	}
+}
+
+func TestResultIndividual(t *testing.T) {
+	sc := parseFile(t, "vasaloppet-latin1.xml")
+
+	events := sc.AllEvents()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, expected 1", len(events))
+	}
+
+	got := Result(events[0])
+	want := "1) Torleif Syrstad 3.55.21, 2) Emil Persson 3.55.23, " +
+		"3) Oskar Kårdin 3.55.40, Björn Öhman Bröt"
+
+	if got != want {
+		t.Errorf("got %q, expected %q", got, want)
+	}
+
+	if site := events[0].Metadata.Site.String(); site != "Sälen-Mora" {
+		t.Errorf("site is %q, ISO-8859-1 wasn't transcoded", site)
+	}
+}
+
+// tableText returns the text of the cells of a table, row by row, with the
+// header first.
+func tableText(t *ttninjs.Table) [][]string {
+	var rows [][]string
+
+	for _, r := range append(slices.Clone(t.Head), t.Rows...) {
+		var cells []string
+
+		for _, c := range r.Cells {
+			var text string
+
+			for _, in := range c.Content {
+				if txt, ok := in.(*ttninjs.Text); ok {
+					text += txt.Text
+				}
+			}
+
+			cells = append(cells, text)
+		}
+
+		rows = append(rows, cells)
+	}
+
+	return rows
+}
+
+func TestStandingsTable(t *testing.T) {
+	cases := []struct {
+		file    string
+		caption string
+		want    [][]string
+	}{
+		{
+			file:    "allsvenskan.xml",
+			caption: "Allsvenskan",
+			want: [][]string{
+				{"", "Lag", "S", "V", "O", "F", "Mål", "P"},
+				{"1", "Malmö FF", "7", "5", "2", "0", "16–5", "17"},
+				{"2", "AIK", "7", "4", "1", "2", "11–7", "13"},
+				{"3", "Djurgården", "7", "3", "1", "3", "9–10", "10"},
+			},
+		},
+		{
+			file:    "vasaloppet-latin1.xml",
+			caption: "Vasaloppet, herrar",
+			want: [][]string{
+				{"", "Namn", "Resultat"},
+				{"1", "Torleif Syrstad", "3.55.21"},
+				{"2", "Emil Persson", "3.55.23"},
+				{"3", "Oskar Kårdin", "3.55.40"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.file, func(t *testing.T) {
+			standings := parseFile(t, c.file).AllStandings()
+			if len(standings) != 1 {
+				t.Fatalf("got %d standings, expected 1", len(standings))
+			}
+
+			table := StandingsTable(standings[0])
+
+			got := tableText(table)
+			if !slices.EqualFunc(got, c.want, slices.Equal) {
+				t.Errorf("got table\n%q\nexpected\n%q", got, c.want)
+			}
+
+			if len(table.Caption) != 1 || table.Caption[0].(*ttninjs.Text).Text != c.caption {
+				t.Errorf("unexpected caption %v", table.Caption)
+			}
+
+			for _, cell := range table.Head[0].Cells {
+				if !cell.Header {
+					t.Errorf("head cells should be header cells")
+				}
+			}
+		})
+	}
+}
+
+// TestParseSportsML checks that a body_sportsml that declares ISO-8859-1 isn't
+// transcoded again after JSON decoding.
+func TestParseSportsML(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "document.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc ttninjs.Document
+
+	err = json.Unmarshal(data, &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc, err := ParseSportsML(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Result(sc.AllEvents()[1]); got != "Malmö FF–Halmstad 2–0" {
+		t.Errorf("got %q, expected %q", got, "Malmö FF–Halmstad 2–0")
+	}
+
+	_, err = ParseSportsML(&ttninjs.Document{Uri: "x"})
+	if err != ErrNoSportsML {
+		t.Errorf("expected ErrNoSportsML, got %v", err)
+	}
+}
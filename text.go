@@ -0,0 +1,167 @@
+package ttninjs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ExtractText returns the body of the document as plain text, with one
+// paragraph per line. The text is extracted from BodyHtml5, or BodyRichhtml5
+// if there is no BodyHtml5. Figure captions, scripts and styles are left out.
+func ExtractText(doc *Document) string {
+	body := doc.BodyHtml5
+	if body == "" {
+		body = doc.BodyRichhtml5
+	}
+
+	if body == "" {
+		return ""
+	}
+
+	return htmlText(parseHTML(body))
+}
+
+// htmlText extracts the text of an HTML tree with block elements separated by
+// newlines and whitespace collapsed.
+func htmlText(root *htmlNode) string {
+	var (
+		lines []string
+		line  strings.Builder
+	)
+
+	flush := func() {
+		text := strings.Join(strings.Fields(line.String()), " ")
+		if text != "" {
+			lines = append(lines, text)
+		}
+
+		line.Reset()
+	}
+
+	var walk func(n *htmlNode)
+
+	walk = func(n *htmlNode) {
+		switch n.Type {
+		case htmlTextNode:
+			line.WriteString(n.Text)
+
+			return
+		case htmlCommentNode:
+			return
+		}
+
+		switch n.Tag {
+		case "figcaption", "script", "style", "template":
+			return
+		case "br":
+			flush()
+
+			return
+		}
+
+		block := blockElements[n.Tag] || n.Tag == "td" || n.Tag == "th"
+		if block {
+			flush()
+		}
+
+		for _, c := range n.Children {
+			walk(c)
+		}
+
+		if block {
+			flush()
+		}
+	}
+
+	walk(root)
+	flush()
+
+	return strings.Join(lines, "\n")
+}
+
+// Counts are the character and word counts of a body text.
+type Counts struct {
+	Characters int
+	Words      int
+}
+
+// CountText counts the characters and words of a text as TT does. All
+// characters, including spaces, are counted, except line breaks between
+// paragraphs. Words are sequences of characters separated by whitespace that
+// contain at least one letter or digit.
+func CountText(text string) Counts {
+	var c Counts
+
+	for _, line := range strings.Split(text, "\n") {
+		c.Characters += utf8.RuneCountInString(line)
+
+		for _, w := range strings.Fields(line) {
+			if strings.IndexFunc(w, isWordRune) != -1 {
+				c.Words++
+			}
+		}
+	}
+
+	return c
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// ComputeCounts sets Charcount and Wordcount of the document from the text of
+// its body, see ExtractText and CountText.
+func ComputeCounts(doc *Document) Counts {
+	c := CountText(ExtractText(doc))
+
+	chars := float64(c.Characters)
+
+	doc.Charcount = &chars
+	doc.Wordcount = c.Words
+
+	return c
+}
+
+// CountMismatch describes a stored count that doesn't agree with the body.
+type CountMismatch struct {
+	// Field is "charcount" or "wordcount".
+	Field    string
+	Stored   int
+	Computed int
+}
+
+func (m CountMismatch) String() string {
+	return fmt.Sprintf("%s is %d, but the body has %d", m.Field, m.Stored, m.Computed)
+}
+
+// CheckCounts compares the stored Charcount and Wordcount of the document with
+// the counts computed from the body. Counts that aren't set aren't checked.
+func CheckCounts(doc *Document) []CountMismatch {
+	if doc.Charcount == nil && doc.Wordcount == 0 {
+		return nil
+	}
+
+	c := CountText(ExtractText(doc))
+
+	var mismatches []CountMismatch
+
+	if doc.Charcount != nil && int(*doc.Charcount) != c.Characters {
+		mismatches = append(mismatches, CountMismatch{
+			Field:    "charcount",
+			Stored:   int(*doc.Charcount),
+			Computed: c.Characters,
+		})
+	}
+
+	if doc.Wordcount != 0 && doc.Wordcount != c.Words {
+		mismatches = append(mismatches, CountMismatch{
+			Field:    "wordcount",
+			Stored:   doc.Wordcount,
+			Computed: c.Words,
+		})
+	}
+
+	return mismatches
+}
@@ -0,0 +1,202 @@
+package ttninjs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestExtractTextFixtures extracts the text of the bodies in testdata/text and
+// compares it with the .txt files next to them.
+func TestExtractTextFixtures(t *testing.T) {
+	counts := map[string]Counts{
+		"article": {Characters: 279, Words: 41},
+	}
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "text", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inputs) != len(counts) {
+		t.Fatalf("found %d fixtures, want %d", len(inputs), len(counts))
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".html")
+
+		t.Run(name, func(t *testing.T) {
+			body, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := os.ReadFile(strings.TrimSuffix(input, ".html") + ".txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			doc := Document{Uri: "a", BodyHtml5: string(body)}
+
+			text := ExtractText(&doc)
+			if text != string(want) {
+				t.Fatalf("got text:\n%s", text)
+			}
+
+			c := ComputeCounts(&doc)
+			if c != counts[name] {
+				t.Errorf("got counts %+v, want %+v", c, counts[name])
+			}
+
+			if doc.Charcount == nil || int(*doc.Charcount) != c.Characters ||
+				doc.Wordcount != c.Words {
+				t.Errorf("counts weren't set on the document: %v %d",
+					doc.Charcount, doc.Wordcount)
+			}
+
+			if m := CheckCounts(&doc); m != nil {
+				t.Errorf("got mismatches %v after ComputeCounts", m)
+			}
+		})
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	cases := []struct {
+		name string
+		doc  Document
+		want string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "richhtml5 fallback",
+			doc:  Document{BodyRichhtml5: "<p>Rik text</p>"},
+			want: "Rik text",
+		},
+		{
+			name: "html5 first",
+			doc:  Document{BodyHtml5: "<p>Text</p>", BodyRichhtml5: "<p>Rik text</p>"},
+			want: "Text",
+		},
+		{
+			name: "whitespace",
+			doc:  Document{BodyHtml5: "<p>  en\n\ttvå  \r\n tre </p>\n\n<p> fyra&nbsp;&nbsp;fem </p>"},
+			want: "en två tre\nfyra fem",
+		},
+		{
+			name: "inline elements",
+			doc:  Document{BodyHtml5: "<p>ett <em>två</em><strong>tre</strong> <a href=\"#\">fyra</a></p>"},
+			want: "ett tvåtre fyra",
+		},
+		{
+			name: "entities",
+			doc:  Document{BodyHtml5: "<p>&lt;b&gt; &amp; &quot;citat&quot; &#229;&#xE4;&ouml; &hellip;</p>"},
+			want: "<b> & \"citat\" åäö …",
+		},
+		{
+			name: "text outside blocks",
+			doc:  Document{BodyHtml5: "före<p>inne</p>efter<br>sist"},
+			want: "före\ninne\nefter\nsist",
+		},
+		{
+			name: "left out",
+			doc: Document{BodyHtml5: "<p>kvar</p><!-- bort --><figure><img src=\"a.jpg\">" +
+				"<figcaption>bort</figcaption></figure><script>bort()</script>" +
+				"<template><p>bort</p></template><style>bort</style>"},
+			want: "kvar",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.doc.Uri = "a"
+
+			if got := ExtractText(&c.doc); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCountText(t *testing.T) {
+	cases := []struct {
+		text string
+		want Counts
+	}{
+		{"", Counts{}},
+		{"ett", Counts{Characters: 3, Words: 1}},
+		{"ett två", Counts{Characters: 7, Words: 2}},
+		{"ett\ntvå", Counts{Characters: 6, Words: 2}},
+		{"åäö ÅÄÖ", Counts{Characters: 7, Words: 2}},
+		{"Pris: 12 % – inte 15 %!", Counts{Characters: 23, Words: 4}},
+		{"– & - ...", Counts{Characters: 9, Words: 0}},
+		{"e-post: a@b.se", Counts{Characters: 14, Words: 2}},
+		{"”Citat”, sade hon.", Counts{Characters: 18, Words: 3}},
+		{"日本語 テキスト", Counts{Characters: 8, Words: 2}},
+		{"emoji 👍 ok", Counts{Characters: 10, Words: 2}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.text, func(t *testing.T) {
+			if got := CountText(c.text); got != c.want {
+				t.Errorf("got %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCheckCounts(t *testing.T) {
+	chars := func(n float64) *float64 { return &n }
+
+	body := "<p>Ett två tre.</p><p>Fyra fem.</p>"
+
+	cases := []struct {
+		name      string
+		charcount *float64
+		wordcount int
+		want      []CountMismatch
+	}{
+		{name: "not set"},
+		{name: "matching", charcount: chars(21), wordcount: 5},
+		{name: "only charcount", charcount: chars(21)},
+		{name: "only wordcount", wordcount: 5},
+		{
+			name:      "charcount",
+			charcount: chars(25), wordcount: 5,
+			want: []CountMismatch{{Field: "charcount", Stored: 25, Computed: 21}},
+		},
+		{
+			name:      "both",
+			charcount: chars(0), wordcount: 7,
+			want: []CountMismatch{
+				{Field: "charcount", Stored: 0, Computed: 21},
+				{Field: "wordcount", Stored: 7, Computed: 5},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			doc := Document{
+				Uri:       "a",
+				BodyHtml5: body,
+				Charcount: c.charcount,
+				Wordcount: c.wordcount,
+			}
+
+			got := CheckCounts(&doc)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	m := CountMismatch{Field: "wordcount", Stored: 7, Computed: 5}
+	if got := m.String(); got != "wordcount is 7, but the body has 5" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -236,6 +236,12 @@ type Document struct {
 	// it will probably hold a large number of properties.
 	Signals Signals `json:"signals,omitempty" yaml:"signals,omitempty" mapstructure:"signals,omitempty"`
 
+	// $$TT: Detached JWS over the canonical JSON of the item, excluding this
+	// property. Used to verify that the item hasn't been altered, see the
+	// trustindicator for the verification endpoint. Not yet in the published
+	// schema, see README.md.
+	Signature string `json:"signature,omitempty" yaml:"signature,omitempty" mapstructure:"signature,omitempty"`
+
 	// $$TT: Short name given to article while in production. (DEPRECTED, use slugline
 	// instead.)
 	Slug string `json:"slug,omitempty" yaml:"slug,omitempty" mapstructure:"slug,omitempty"`
@@ -470,7 +476,32 @@ type BodyEvent struct {
 }
 
 // $$TT: One or more objects describing the pages in this delivery.
-type BodyPages map[string]interface{}
+type BodyPages map[string]Page
+
+// Page is a page in a delivery of ready-made pages. The schema doesn't
+// describe the members of pages, members that don't have a field are kept in
+// Extra.
+type Page struct {
+	// Pagina is the page number of the page, matching an entry in
+	// signals.paginae. A page number can also be a letter.
+	Pagina string `json:"pagina,omitempty" yaml:"pagina,omitempty" mapstructure:"pagina,omitempty"`
+
+	// Width of the page, measured in unit.
+	Width float64 `json:"width,omitempty" yaml:"width,omitempty" mapstructure:"width,omitempty"`
+
+	// Height of the page, measured in unit.
+	Height float64 `json:"height,omitempty" yaml:"height,omitempty" mapstructure:"height,omitempty"`
+
+	// Unit for width and height, defaults to mm.
+	Unit string `json:"unit,omitempty" yaml:"unit,omitempty" mapstructure:"unit,omitempty"`
+
+	// Renditions of the page, typically a PDF and preview images.
+	Renditions Renditions `json:"renditions,omitempty" yaml:"renditions,omitempty" mapstructure:"renditions,omitempty"`
+
+	// Extra holds the members of the page that don't have a field, so that
+	// they're kept when the document is marshalled again.
+	Extra map[string]any `json:"-" yaml:"-" mapstructure:"-"`
+}
 
 type BylinesElem struct {
 	// The affiliation of the person. Example: SvD/TT
@@ -699,17 +730,42 @@ type PlaceElem struct {
 }
 
 // $$TT: An optional GeoJSON description of the place.
+//
+// The JSON and YAML "coordinates" member is mapped to Coordinates, Positions,
+// Lines or Polygons depending on the type of the geometry, see geojson.go.
 type PlaceElemGeometryGeojson struct {
-	// Array of coordinate pairs, but in our case on pair.
+	// The position of a Point as longitude, latitude and optional
+	// altitude.
 	Coordinates []float64 `json:"coordinates,omitempty" yaml:"coordinates,omitempty" mapstructure:"coordinates,omitempty"`
 
+	// The positions of a MultiPoint or LineString.
+	Positions []Position `json:"-" yaml:"-" mapstructure:"-"`
+
+	// The lines of a MultiLineString, or the linear rings of a Polygon
+	// with the exterior ring first.
+	Lines [][]Position `json:"-" yaml:"-" mapstructure:"-"`
+
+	// The polygons of a MultiPolygon.
+	Polygons [][][]Position `json:"-" yaml:"-" mapstructure:"-"`
+
+	// The geometries of a GeometryCollection.
+	Geometries []PlaceElemGeometryGeojson `json:"geometries,omitempty" yaml:"geometries,omitempty" mapstructure:"geometries,omitempty"`
+
 	// What type of coordinates is given. Normally Point.
 	Type PlaceElemGeometryGeojsonType `json:"type,omitempty" yaml:"type,omitempty" mapstructure:"type,omitempty"`
 }
 
 type PlaceElemGeometryGeojsonType string
 
-const PlaceElemGeometryGeojsonTypePoint PlaceElemGeometryGeojsonType = "Point"
+const (
+	PlaceElemGeometryGeojsonTypePoint              PlaceElemGeometryGeojsonType = "Point"
+	PlaceElemGeometryGeojsonTypeMultiPoint         PlaceElemGeometryGeojsonType = "MultiPoint"
+	PlaceElemGeometryGeojsonTypeLineString         PlaceElemGeometryGeojsonType = "LineString"
+	PlaceElemGeometryGeojsonTypeMultiLineString    PlaceElemGeometryGeojsonType = "MultiLineString"
+	PlaceElemGeometryGeojsonTypePolygon            PlaceElemGeometryGeojsonType = "Polygon"
+	PlaceElemGeometryGeojsonTypeMultiPolygon       PlaceElemGeometryGeojsonType = "MultiPolygon"
+	PlaceElemGeometryGeojsonTypeGeometryCollection PlaceElemGeometryGeojsonType = "GeometryCollection"
+)
 
 type ProductElem struct {
 	// The code for the subject in a scheme (= controlled vocabulary) which is
@@ -948,6 +1004,12 @@ var enumValues_AdviceElemRole = []interface{}{
 
 var enumValues_PlaceElemGeometryGeojsonType = []interface{}{
 	"Point",
+	"MultiPoint",
+	"LineString",
+	"MultiLineString",
+	"Polygon",
+	"MultiPolygon",
+	"GeometryCollection",
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
@@ -0,0 +1,569 @@
+package ttninjs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrMissingName is returned by ToVCard for elements without a name, as a
+// vCard must have a formatted name.
+var ErrMissingName = errors.New("element has no name")
+
+// ContactElem is an element with contact information that can be exported as a
+// vCard: InfosourceElem, OrganisationElem, PersonElem, PlaceElem or BodyEvent,
+// which is exported as its organizer.
+type ContactElem interface {
+	vcardContact() vcardContact
+}
+
+type vcardContact struct {
+	kind string
+	name string
+	info []ContactinfoType
+	geo  *PlaceElemGeometryGeojson
+}
+
+func (e InfosourceElem) vcardContact() vcardContact {
+	return vcardContact{kind: "org", name: e.Name, info: e.Contactinfo}
+}
+
+func (e OrganisationElem) vcardContact() vcardContact {
+	return vcardContact{kind: "org", name: e.Name, info: e.Contactinfo}
+}
+
+func (e PersonElem) vcardContact() vcardContact {
+	return vcardContact{kind: "individual", name: e.Name, info: e.Contactinfo}
+}
+
+func (e PlaceElem) vcardContact() vcardContact {
+	return vcardContact{
+		kind: "location",
+		name: e.Name,
+		info: e.Contactinfo,
+		geo:  e.GeometryGeojson,
+	}
+}
+
+// vcardContact uses the organizer of the event, with the mail address, phone
+// number, web page and address of the organizer as contact information.
+func (e BodyEvent) vcardContact() vcardContact {
+	c := vcardContact{kind: "org", name: e.Organizer}
+
+	if e.Organizerphone != "" {
+		c.info = append(c.info, ContactinfoType{Type: ContactTypePhone, Value: e.Organizerphone})
+	}
+
+	if e.Organizermail != "" {
+		c.info = append(c.info, ContactinfoType{Type: ContactTypeEmail, Value: e.Organizermail})
+	}
+
+	if e.Organizerurl != "" {
+		c.info = append(c.info, ContactinfoType{Type: ContactTypeWeb, Value: e.Organizerurl})
+	}
+
+	a := Address{
+		Locality: e.Organizercity,
+		Country:  e.Organizercountry,
+	}
+
+	for _, l := range strings.Split(e.Organizeraddress, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			a.Lines = append(a.Lines, l)
+		}
+	}
+
+	if !a.isZero() {
+		c.info = append(c.info, ContactinfoType{Type: ContactTypeAddress, Address: a})
+	}
+
+	return c
+}
+
+// Contact methods, the Type of ContactinfoType, as created by FromVCard.
+const (
+	ContactTypePhone   = "phone"
+	ContactTypeMobile  = "mobile"
+	ContactTypeFax     = "fax"
+	ContactTypeEmail   = "email"
+	ContactTypeWeb     = "web"
+	ContactTypeAddress = "address"
+)
+
+// Contact roles, the Role of ContactinfoType, as created by FromVCard.
+const (
+	ContactRoleOffice  = "office"
+	ContactRolePrivate = "private"
+)
+
+// contactTypes maps the types of contact information to the contact methods
+// used in vCards.
+var contactTypes = map[string]string{
+	"phone":     ContactTypePhone,
+	"telephone": ContactTypePhone,
+	"tel":       ContactTypePhone,
+	"telefon":   ContactTypePhone,
+	"voice":     ContactTypePhone,
+	"mobile":    ContactTypeMobile,
+	"mobil":     ContactTypeMobile,
+	"cell":      ContactTypeMobile,
+	"fax":       ContactTypeFax,
+	"email":     ContactTypeEmail,
+	"e-mail":    ContactTypeEmail,
+	"mail":      ContactTypeEmail,
+	"epost":     ContactTypeEmail,
+	"e-post":    ContactTypeEmail,
+	"web":       ContactTypeWeb,
+	"webb":      ContactTypeWeb,
+	"url":       ContactTypeWeb,
+	"website":   ContactTypeWeb,
+	"homepage":  ContactTypeWeb,
+	"address":   ContactTypeAddress,
+	"adress":    ContactTypeAddress,
+	"postal":    ContactTypeAddress,
+	"visit":     ContactTypeAddress,
+}
+
+// contactType returns the contact method of contact information, guessing it
+// from the value or address if the type isn't known.
+func contactType(c ContactinfoType) string {
+	if t, ok := contactTypes[strings.ToLower(strings.TrimSpace(c.Type))]; ok {
+		return t
+	}
+
+	v := strings.TrimSpace(c.Value)
+
+	switch {
+	case !c.Address.isZero():
+		return ContactTypeAddress
+	case strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://"):
+		return ContactTypeWeb
+	case isEmailAddress(v):
+		return ContactTypeEmail
+	}
+
+	return ""
+}
+
+func isEmailAddress(s string) bool {
+	local, domain, ok := strings.Cut(s, "@")
+
+	return ok && local != "" && strings.Contains(domain, ".") &&
+		!strings.ContainsAny(s, " \t")
+}
+
+func (a Address) isZero() bool {
+	return len(a.Lines) == 0 && a.Locality == "" && a.Area == "" &&
+		a.Postalcode == "" && a.Country == ""
+}
+
+// ToVCard renders an element as an RFC 6350 vCard 4.0. Phone numbers become
+// TEL, email addresses EMAIL, web addresses URL and addresses ADR with a
+// formatted LABEL. The role of contact information is mapped to a work or home
+// TYPE, its language to LANGUAGE, and its name to an X-NAME parameter. Places
+// with a geometry get a GEO with their centroid. Contact information of other
+// types, like social media accounts, is left out.
+//
+// Phone numbers are written as tel: URIs, where spaces become hyphens and other
+// characters that aren't allowed in a URI are dropped, so "+46 8 123 45" is
+// read back by FromVCard as "+46-8-123-45".
+func ToVCard(elem ContactElem) ([]byte, error) {
+	c := elem.vcardContact()
+
+	if strings.TrimSpace(c.name) == "" {
+		return nil, ErrMissingName
+	}
+
+	var buf bytes.Buffer
+
+	w := icsWriter{w: &buf}
+
+	w.line("BEGIN", nil, "VCARD")
+	w.line("VERSION", nil, "4.0")
+	w.line("KIND", nil, c.kind)
+	w.text("FN", nil, c.name)
+
+	switch c.kind {
+	case "individual":
+		first, last := splitName(c.name)
+		w.line("N", nil, strings.Join([]string{
+			icsTextEscaper.Replace(last), icsTextEscaper.Replace(first), "", "", "",
+		}, ";"))
+	case "org":
+		w.text("ORG", nil, c.name)
+	}
+
+	if c.geo != nil {
+		if p, ok := c.geo.Centroid(); ok {
+			w.line("GEO", nil, "geo:"+
+				strconv.FormatFloat(p.Lat(), 'f', -1, 64)+","+
+				strconv.FormatFloat(p.Lon(), 'f', -1, 64))
+		}
+	}
+
+	for _, info := range c.info {
+		vcardInfo(&w, info)
+	}
+
+	w.line("END", nil, "VCARD")
+
+	return buf.Bytes(), nil
+}
+
+func vcardInfo(w *icsWriter, c ContactinfoType) {
+	var params []string
+
+	switch strings.ToLower(c.Role) {
+	case ContactRoleOffice, "work", "arbete":
+		params = append(params, "TYPE", "work")
+	case ContactRolePrivate, "home", "hem":
+		params = append(params, "TYPE", "home")
+	}
+
+	if c.Lang != "" {
+		params = append(params, "LANGUAGE", c.Lang)
+	}
+
+	if c.Name != "" {
+		params = append(params, "X-NAME", vcardParamEscaper.Replace(c.Name))
+	}
+
+	value := strings.TrimSpace(c.Value)
+
+	switch contactType(c) {
+	case ContactTypePhone, ContactTypeMobile, ContactTypeFax:
+		if value == "" {
+			return
+		}
+
+		kind := map[string]string{
+			ContactTypePhone:  "voice",
+			ContactTypeMobile: "cell",
+			ContactTypeFax:    "fax",
+		}[contactType(c)]
+
+		params = append([]string{"VALUE", "uri"}, appendParamType(params, kind)...)
+
+		w.line("TEL", params, "tel:"+telURI(value))
+	case ContactTypeEmail:
+		w.text("EMAIL", params, strings.TrimPrefix(value, "mailto:"))
+	case ContactTypeWeb:
+		w.line("URL", params, value)
+	case ContactTypeAddress:
+		a := c.Address
+		if a.isZero() {
+			if value == "" {
+				return
+			}
+
+			a.Lines = strings.Split(value, "\n")
+		}
+
+		if label := a.Format(); label != "" {
+			params = append(params, "LABEL", vcardParamEscaper.Replace(label))
+		}
+
+		lines := make([]string, len(a.Lines))
+		for i, l := range a.Lines {
+			lines[i] = icsTextEscaper.Replace(l)
+		}
+
+		w.line("ADR", params, strings.Join([]string{
+			"", "",
+			strings.Join(lines, ","),
+			icsTextEscaper.Replace(a.Locality),
+			icsTextEscaper.Replace(a.Area),
+			icsTextEscaper.Replace(a.Postalcode),
+			icsTextEscaper.Replace(a.Country),
+		}, ";"))
+	}
+}
+
+// appendParamType adds a value to the TYPE parameter in a list of key value
+// pairs.
+func appendParamType(params []string, value string) []string {
+	for i := 0; i+1 < len(params); i += 2 {
+		if params[i] == "TYPE" {
+			params[i+1] += "," + value
+
+			return params
+		}
+	}
+
+	return append(params, "TYPE", value)
+}
+
+// telURI removes the characters that aren't allowed in an RFC 3966 telephone
+// number.
+func telURI(number string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '+', r == '-', r == '.', r == '(', r == ')':
+			return r
+		case r == ' ':
+			return '-'
+		}
+
+		return -1
+	}, strings.TrimPrefix(number, "tel:"))
+}
+
+// vcardParamEscaper escapes parameter values as described in RFC 6868.
+var vcardParamEscaper = strings.NewReplacer(
+	"^", "^^", "\r\n", "^n", "\n", "^n", `"`, "^'",
+)
+
+var vcardParamUnescaper = strings.NewReplacer(
+	"^^", "^", "^n", "\n", "^N", "\n", "^'", `"`,
+)
+
+// VCardError is returned by FromVCard for invalid vCards.
+type VCardError struct {
+	Line int
+	Msg  string
+}
+
+func (e *VCardError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// FromVCard reads the first vCard from r and returns its formatted name and
+// its contact information. TEL, EMAIL, URL and ADR properties are converted to
+// contact information with the types used by ToVCard. Phone numbers are
+// returned as written in the vCard, without a "tel:" prefix.
+func FromVCard(r io.Reader) (string, []ContactinfoType, error) {
+	props, err := readICSProperties(r)
+	if err != nil {
+		var icsErr *ICSError
+		if errors.As(err, &icsErr) {
+			return "", nil, &VCardError{Line: icsErr.Line, Msg: icsErr.Msg}
+		}
+
+		return "", nil, err
+	}
+
+	var (
+		name    string
+		info    []ContactinfoType
+		inCard  bool
+		started bool
+	)
+
+	for _, p := range props {
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VCARD"):
+			if inCard {
+				return "", nil, &VCardError{Line: p.line, Msg: "nested vCard"}
+			}
+
+			inCard, started = true, true
+
+			continue
+		case p.name == "END" && strings.EqualFold(p.value, "VCARD"):
+			if !inCard {
+				return "", nil, &VCardError{Line: p.line, Msg: "unexpected END:VCARD"}
+			}
+
+			return name, info, nil
+		case !inCard:
+			continue
+		}
+
+		text := icsTextUnescaper.Replace(p.value)
+
+		c := ContactinfoType{
+			Lang: p.params["LANGUAGE"],
+			Name: vcardParamUnescaper.Replace(p.params["X-NAME"]),
+		}
+
+		types := strings.Split(strings.ToLower(p.params["TYPE"]), ",")
+
+		for _, t := range types {
+			switch t {
+			case "work":
+				c.Role = ContactRoleOffice
+			case "home":
+				c.Role = ContactRolePrivate
+			}
+		}
+
+		switch p.name {
+		case "FN":
+			name = text
+
+			continue
+		case "TEL":
+			c.Type = ContactTypePhone
+
+			for _, t := range types {
+				switch t {
+				case "cell":
+					c.Type = ContactTypeMobile
+				case "fax":
+					c.Type = ContactTypeFax
+				}
+			}
+
+			c.Value = strings.TrimPrefix(text, "tel:")
+		case "EMAIL":
+			c.Type = ContactTypeEmail
+			c.Value = text
+		case "URL":
+			c.Type = ContactTypeWeb
+			c.Value = p.value
+		case "ADR":
+			c.Type = ContactTypeAddress
+			c.Address = vcardAddress(p.value)
+		default:
+			continue
+		}
+
+		info = append(info, c)
+	}
+
+	if started {
+		return "", nil, &VCardError{
+			Line: props[len(props)-1].line,
+			Msg:  "unterminated vCard",
+		}
+	}
+
+	return "", nil, &VCardError{Msg: "no vCard found"}
+}
+
+// vcardAddress parses the value of an ADR property. The post office box and
+// extended address are added as address lines before the street address.
+func vcardAddress(value string) Address {
+	parts := splitVCardValue(value, ';')
+	for len(parts) < 7 {
+		parts = append(parts, "")
+	}
+
+	var a Address
+
+	for _, part := range parts[:3] {
+		for _, line := range splitVCardValue(part, ',') {
+			if line = strings.TrimSpace(icsTextUnescaper.Replace(line)); line != "" {
+				a.Lines = append(a.Lines, line)
+			}
+		}
+	}
+
+	a.Locality = icsTextUnescaper.Replace(parts[3])
+	a.Area = icsTextUnescaper.Replace(parts[4])
+	a.Postalcode = icsTextUnescaper.Replace(parts[5])
+	a.Country = icsTextUnescaper.Replace(parts[6])
+
+	return a
+}
+
+// splitVCardValue splits a structured value on unescaped separators, leaving
+// the escapes in the parts.
+func splitVCardValue(value string, sep byte) []string {
+	var (
+		parts []string
+		start int
+	)
+
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, value[start:])
+}
+
+type addressFormat int
+
+const (
+	// Postal code before the locality: "114 55 Stockholm".
+	addressPostalLocality addressFormat = iota
+	// Locality, area and postal code on one line: "Springfield, IL 62701".
+	addressLocalityAreaPostal
+	// Locality, area and postal code on separate lines.
+	addressSeparateLines
+)
+
+// countryAddressFormat returns the address format of a country given as an
+// ISO 3166 code or name in English or Swedish. Countries that aren't known use
+// addressPostalLocality.
+func countryAddressFormat(country string) addressFormat {
+	switch country {
+	case "us", "usa", "united states", "ca", "can", "canada", "kanada",
+		"au", "aus", "australia", "australien":
+		return addressLocalityAreaPostal
+	case "gb", "gbr", "uk", "united kingdom", "storbritannien",
+		"ie", "irl", "ireland", "irland":
+		return addressSeparateLines
+	}
+
+	return addressPostalLocality
+}
+
+// swedishCountry matches the ways Sweden is written in addresses.
+var swedishCountry = map[string]bool{
+	"se": true, "swe": true, "sverige": true, "sweden": true,
+}
+
+// Format renders the address as lines separated by newlines, following the
+// conventions of its Country: postal code before the locality for Sweden and
+// most of Europe, "Locality, Area Postalcode" for f.ex. the USA, and separate
+// lines for the United Kingdom and Ireland. Swedish postal codes are written
+// with a space, "114 55".
+func (a Address) Format() string {
+	country := strings.ToLower(strings.TrimSpace(a.Country))
+
+	postal := strings.TrimSpace(a.Postalcode)
+	if swedishCountry[country] && len(postal) == 5 && strings.Trim(postal, "0123456789") == "" {
+		postal = postal[:3] + " " + postal[3:]
+	}
+
+	var lines []string
+
+	add := func(parts ...string) {
+		var nonEmpty []string
+
+		for _, p := range parts {
+			if p = strings.TrimSpace(p); p != "" {
+				nonEmpty = append(nonEmpty, p)
+			}
+		}
+
+		if len(nonEmpty) > 0 {
+			lines = append(lines, strings.Join(nonEmpty, " "))
+		}
+	}
+
+	for _, l := range a.Lines {
+		add(l)
+	}
+
+	switch countryAddressFormat(country) {
+	case addressLocalityAreaPostal:
+		locality := strings.TrimSpace(a.Locality)
+		if locality != "" && (a.Area != "" || postal != "") {
+			locality += ","
+		}
+
+		add(locality, a.Area, postal)
+	case addressSeparateLines:
+		add(a.Locality)
+		add(a.Area)
+		add(postal)
+	default:
+		add(postal, a.Locality)
+		add(a.Area)
+	}
+
+	add(a.Country)
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,205 @@
+package ttninjs
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToVCardEventOrganizer(t *testing.T) {
+	ev := BodyEvent{
+		Organizer:        "Stockholms stad",
+		Organizermail:    "info@stockholm.se",
+		Organizerphone:   "08-508 290 00",
+		Organizerurl:     "https://start.stockholm/",
+		Organizeraddress: "Stadshuset\nHantverkargatan 1",
+		Organizercity:    "Stockholm",
+		Organizercountry: "Sverige",
+	}
+
+	out, err := ToVCard(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	card := strings.ReplaceAll(string(out), "\r\n ", "")
+
+	for _, want := range []string{
+		"KIND:org\r\n",
+		"FN:Stockholms stad\r\n",
+		"ORG:Stockholms stad\r\n",
+		"TEL;VALUE=uri;TYPE=voice:tel:08-508-290-00\r\n",
+		"EMAIL:info@stockholm.se\r\n",
+		"URL:https://start.stockholm/\r\n",
+		";;Stadshuset,Hantverkargatan 1;Stockholm;;;Sverige\r\n",
+	} {
+		if !strings.Contains(card, want) {
+			t.Errorf("vCard doesn't contain %q:\n%s", want, card)
+		}
+	}
+
+	name, info, err := FromVCard(strings.NewReader(string(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name != ev.Organizer || len(info) != 4 {
+		t.Errorf("got %q with %d contact methods, expected %q with 4", name, len(info), ev.Organizer)
+	}
+
+	_, err = ToVCard(BodyEvent{Organizermail: "info@tt.se"})
+	if err != ErrMissingName {
+		t.Errorf("expected ErrMissingName for an event without organizer, got %v", err)
+	}
+}
+
+func TestAddressFormat(t *testing.T) {
+	cases := []struct {
+		name    string
+		address Address
+		want    string
+	}{
+		{"empty", Address{}, ""},
+		{
+			name: "se",
+			address: Address{
+				Lines: []string{"Hantverkargatan 1"}, Postalcode: "11152",
+				Locality: "Stockholm", Country: "Sverige",
+			},
+			want: "Hantverkargatan 1\n111 52 Stockholm\nSverige",
+		},
+		{
+			name:    "se code",
+			address: Address{Postalcode: " 41103 ", Locality: "Göteborg", Country: "SE"},
+			want:    "411 03 Göteborg\nSE",
+		},
+		{
+			name:    "se spaced postal code",
+			address: Address{Postalcode: "411 03", Locality: "Göteborg", Country: "Sweden"},
+			want:    "411 03 Göteborg\nSweden",
+		},
+		{
+			name:    "se invalid postal code",
+			address: Address{Postalcode: "S-41103", Locality: "Göteborg", Country: "Sverige"},
+			want:    "S-41103 Göteborg\nSverige",
+		},
+		{
+			name: "us",
+			address: Address{
+				Lines:    []string{"1600 Pennsylvania Avenue NW"},
+				Locality: "Washington", Area: "DC", Postalcode: "20500", Country: "USA",
+			},
+			want: "1600 Pennsylvania Avenue NW\nWashington, DC 20500\nUSA",
+		},
+		{
+			name:    "us without area",
+			address: Address{Locality: "Springfield", Country: "US"},
+			want:    "Springfield\nUS",
+		},
+		{
+			name: "gb",
+			address: Address{
+				Lines:    []string{"10 Downing Street"},
+				Locality: "London", Postalcode: "SW1A 2AA", Country: "United Kingdom",
+			},
+			want: "10 Downing Street\nLondon\nSW1A 2AA\nUnited Kingdom",
+		},
+		{
+			name: "other",
+			address: Address{
+				Lines:    []string{"Unter den Linden 1", " "},
+				Locality: "Berlin", Postalcode: "10117", Area: "Berlin", Country: "Tyskland",
+			},
+			want: "Unter den Linden 1\n10117 Berlin\nBerlin\nTyskland",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.address.Format(); got != c.want {
+				t.Errorf("got %q, expected %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFromVCard(t *testing.T) {
+	card := strings.Join([]string{
+		"BEGIN:VCARD",
+		"VERSION:4.0",
+		"FN:Anna Andersson",
+		`TEL;VALUE=uri;TYPE="work,cell":tel:+46-70-123-45-67`,
+		"TEL;TYPE=home;X-NAME=Växel ^'hem^':+46 8 123 45",
+		"TEL;TYPE=FAX:tel:+46-8-123-46",
+		"EMAIL;LANGUAGE=sv;TYPE=work;X-NAME=Redaktionen:anna@tt.se",
+		"URL:https://tt.se/",
+		"ADR;LANGUAGE=en;LABEL=\"Box 1\\nStockholm\":Box 1;;Gata 1\\, 3 tr,Plan 2;Stockholm;;11152;Sverige",
+		"NOTE:ignored",
+		"END:VCARD",
+	}, "\r\n")
+
+	name, info, err := FromVCard(strings.NewReader(card))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name != "Anna Andersson" {
+		t.Errorf("got name %q", name)
+	}
+
+	want := []ContactinfoType{
+		{Type: ContactTypeMobile, Role: ContactRoleOffice, Value: "+46-70-123-45-67"},
+		{Type: ContactTypePhone, Role: ContactRolePrivate, Name: `Växel "hem"`, Value: "+46 8 123 45"},
+		{Type: ContactTypeFax, Value: "+46-8-123-46"},
+		{Type: ContactTypeEmail, Role: ContactRoleOffice, Lang: "sv", Name: "Redaktionen",
+			Value: "anna@tt.se"},
+		{Type: ContactTypeWeb, Value: "https://tt.se/"},
+		{Type: ContactTypeAddress, Lang: "en", Address: Address{
+			Lines:      []string{"Box 1", "Gata 1, 3 tr", "Plan 2"},
+			Locality:   "Stockholm",
+			Postalcode: "11152",
+			Country:    "Sverige",
+		}},
+	}
+
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("got\n%+v\nexpected\n%+v", info, want)
+	}
+
+	// Phone numbers lose their spaces in the tel: URI.
+	out, err := ToVCard(PersonElem{Name: "Anna Andersson", Contactinfo: want[1:2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, info, err = FromVCard(strings.NewReader(string(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(info) != 1 || info[0].Value != "+46-8-123-45" || info[0].Name != `Växel "hem"` {
+		t.Errorf("got %+v after a round trip", info)
+	}
+}
+
+func TestFromVCardInvalid(t *testing.T) {
+	cases := map[string]string{
+		"no vcard":       "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n",
+		"unterminated":   "BEGIN:VCARD\r\nFN:Anna\r\n",
+		"nested":         "BEGIN:VCARD\r\nBEGIN:VCARD\r\n",
+		"unexpected end": "END:VCARD\r\n",
+		"invalid line":   "BEGIN:VCARD\r\nFN\r\nEND:VCARD\r\n",
+	}
+
+	for name, card := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := FromVCard(strings.NewReader(card))
+
+			var vErr *VCardError
+			if !errors.As(err, &vErr) {
+				t.Errorf("expected a *VCardError, got %v", err)
+			}
+		})
+	}
+}
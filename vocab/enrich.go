@@ -0,0 +1,136 @@
+package vocab
+
+import (
+	"fmt"
+
+	"github.com/ttab/ttninjs"
+)
+
+// UnknownCode describes a code that wasn't found in the vocabulary for its
+// scheme.
+type UnknownCode struct {
+	// URI of the document that the code was found in.
+	DocumentURI string
+	// Field is the path of the element, f.ex. "subject[2]".
+	Field  string
+	Scheme string
+	Code   string
+}
+
+func (u UnknownCode) String() string {
+	return fmt.Sprintf("%s %s: unknown code %q in scheme %q",
+		u.DocumentURI, u.Field, u.Code, u.Scheme)
+}
+
+// Enrich sets missing names on the coded metadata of the document, and of its
+// associations and assignments. Codes in schemes that the registry has a
+// vocabulary for, but that aren't present in the vocabulary, are returned as
+// unknown. Codes in schemes that the registry doesn't know of are ignored.
+func (r *Registry) Enrich(doc *ttninjs.Document) []UnknownCode {
+	e := enricher{r: r}
+
+	e.document(doc)
+
+	return e.unknown
+}
+
+type enricher struct {
+	r       *Registry
+	unknown []UnknownCode
+}
+
+func (e *enricher) resolve(doc *ttninjs.Document, field string, idx int, scheme, code string, name *string) {
+	if scheme == "" || code == "" || *name != "" {
+		return
+	}
+
+	if !e.r.HasScheme(scheme) {
+		return
+	}
+
+	n, ok := e.r.LookupLang(scheme, code, documentLang(e.r, doc))
+	if !ok {
+		e.unknown = append(e.unknown, UnknownCode{
+			DocumentURI: doc.Uri,
+			Field:       fmt.Sprintf("%s[%d]", field, idx),
+			Scheme:      scheme,
+			Code:        code,
+		})
+
+		return
+	}
+
+	*name = n
+}
+
+// documentLang uses the language of the document if set, otherwise the
+// registry language.
+func documentLang(r *Registry, doc *ttninjs.Document) string {
+	if doc.Language != "" {
+		return doc.Language
+	}
+
+	return r.lang
+}
+
+func (e *enricher) document(doc *ttninjs.Document) {
+	for i := range doc.Subject {
+		s := &doc.Subject[i]
+		e.resolve(doc, "subject", i, s.Scheme, s.Code, &s.Name)
+	}
+
+	for i := range doc.Place {
+		p := &doc.Place[i]
+		e.resolve(doc, "place", i, p.Scheme, p.Code, &p.Name)
+	}
+
+	for i := range doc.Person {
+		p := &doc.Person[i]
+		e.resolve(doc, "person", i, p.Scheme, p.Code, &p.Name)
+	}
+
+	for i := range doc.Organisation {
+		o := &doc.Organisation[i]
+		e.resolve(doc, "organisation", i, o.Scheme, o.Code, &o.Name)
+	}
+
+	for i := range doc.Object {
+		o := &doc.Object[i]
+		e.resolve(doc, "object", i, o.Scheme, o.Code, &o.Name)
+	}
+
+	for i := range doc.Event {
+		ev := &doc.Event[i]
+		e.resolve(doc, "event", i, ev.Scheme, ev.Code, &ev.Name)
+	}
+
+	for i := range doc.Fixture {
+		f := &doc.Fixture[i]
+		e.resolve(doc, "fixture", i, f.Scheme, f.Code, &f.Name)
+	}
+
+	for i := range doc.Genre {
+		g := &doc.Genre[i]
+		e.resolve(doc, "genre", i, g.Scheme, g.Code, &g.Name)
+	}
+
+	for i := range doc.Product {
+		p := &doc.Product[i]
+		e.resolve(doc, "product", i, p.Scheme, p.Code, &p.Name)
+	}
+
+	for i := range doc.Infosource {
+		s := &doc.Infosource[i]
+		e.resolve(doc, "infosource", i, s.Scheme, s.Code, &s.Name)
+	}
+
+	for key, assoc := range doc.Associations {
+		e.document(&assoc)
+		doc.Associations[key] = assoc
+	}
+
+	for key, assignment := range doc.Assignments {
+		e.document(&assignment)
+		doc.Assignments[key] = assignment
+	}
+}
@@ -0,0 +1,85 @@
+package vocab
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ttab/ttninjs"
+)
+
+func TestEnrich(t *testing.T) {
+	r := loadSKOSFixture(t)
+
+	r.Add(SchemeTTProduct, Concept{
+		Code:  "FT",
+		Names: map[string]string{"sv": "Fotoproduktion", "en": "Photo production"},
+	})
+
+	doc := ttninjs.Document{
+		Uri: "http://tt.se/text/1",
+		Subject: []ttninjs.SubjectElem{
+			{Scheme: SchemeIPTCMediaTopic, Code: "01000000"},
+			{Scheme: SchemeIPTCMediaTopic, Code: "20000003", Name: "Animerat"},
+			{Scheme: SchemeIPTCMediaTopic, Code: "12345678"},
+			{Scheme: SchemeTTKeyword, Code: "okänd"},
+			{Name: "Utan kod"},
+		},
+		Product: []ttninjs.ProductElem{
+			{Scheme: SchemeTTProduct, Code: "FT"},
+		},
+		Genre: []ttninjs.GenreElem{
+			{Scheme: "urn:example:genre", Code: "NOT"},
+		},
+		Associations: ttninjs.Associations{
+			"image1": {
+				Uri:      "http://tt.se/media/image/1",
+				Language: "en",
+				Product: []ttninjs.ProductElem{
+					{Scheme: SchemeTTProduct, Code: "FT"},
+					{Scheme: SchemeTTProduct, Code: "XX"},
+				},
+			},
+		},
+	}
+
+	unknown := r.Enrich(&doc)
+
+	wantUnknown := []UnknownCode{
+		{
+			DocumentURI: "http://tt.se/text/1", Field: "subject[2]",
+			Scheme: SchemeIPTCMediaTopic, Code: "12345678",
+		},
+		{
+			DocumentURI: "http://tt.se/media/image/1", Field: "product[1]",
+			Scheme: SchemeTTProduct, Code: "XX",
+		},
+	}
+
+	if !reflect.DeepEqual(unknown, wantUnknown) {
+		t.Errorf("got unknown %v, want %v", unknown, wantUnknown)
+	}
+
+	names := []struct {
+		got, want string
+	}{
+		{doc.Subject[0].Name, "kultur, nöje och media"},
+		{doc.Subject[1].Name, "Animerat"},
+		{doc.Subject[2].Name, ""},
+		{doc.Subject[3].Name, ""},
+		{doc.Subject[4].Name, "Utan kod"},
+		{doc.Product[0].Name, "Fotoproduktion"},
+		{doc.Genre[0].Name, "Notis"},
+		{doc.Associations["image1"].Product[0].Name, "Photo production"},
+	}
+
+	for i, n := range names {
+		if n.got != n.want {
+			t.Errorf("name %d: got %q, want %q", i, n.got, n.want)
+		}
+	}
+
+	want := "http://tt.se/media/image/1 product[1]: unknown code \"XX\" in scheme \"http://tt.se/spec/product/1.0/\""
+	if got := unknown[1].String(); got != want {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,149 @@
+package vocab
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type skosDocument struct {
+	Concepts []skosConcept `xml:"http://www.w3.org/2004/02/skos/core# Concept"`
+}
+
+type skosConcept struct {
+	About     string         `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Notation  string         `xml:"http://www.w3.org/2004/02/skos/core# notation"`
+	InScheme  []skosResource `xml:"http://www.w3.org/2004/02/skos/core# inScheme"`
+	PrefLabel []skosLabel    `xml:"http://www.w3.org/2004/02/skos/core# prefLabel"`
+	Broader   []skosResource `xml:"http://www.w3.org/2004/02/skos/core# broader"`
+	Narrower  []skosResource `xml:"http://www.w3.org/2004/02/skos/core# narrower"`
+}
+
+type skosResource struct {
+	Resource string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# resource,attr"`
+}
+
+type skosLabel struct {
+	Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Value string `xml:",chardata"`
+}
+
+// skosEntry is a concept read from a SKOS document, with its broader concepts
+// as URIs until all concepts have been read.
+type skosEntry struct {
+	uri     string
+	scheme  string
+	concept Concept
+}
+
+// LoadSKOS reads skos:Concept entries from a SKOS RDF/XML document, like the
+// ones published by IPTC for their newscodes. The scheme of a concept is taken
+// from skos:inScheme, and the code from skos:notation or the concept URI with
+// the scheme prefix removed. Broader concepts are read from skos:broader and
+// skos:narrower, links to concepts in other schemes are left out.
+func (r *Registry) LoadSKOS(rd io.Reader) error {
+	var doc skosDocument
+
+	err := xml.NewDecoder(rd).Decode(&doc)
+	if err != nil {
+		return fmt.Errorf("unable to decode SKOS document: %w", err)
+	}
+
+	var entries []*skosEntry
+
+	byURI := make(map[string]*skosEntry)
+
+	for _, sc := range doc.Concepts {
+		scheme, code := skosIdentity(sc)
+		if scheme == "" || code == "" {
+			continue
+		}
+
+		e := skosEntry{
+			uri:    sc.About,
+			scheme: scheme,
+			concept: Concept{
+				Code:  code,
+				Names: make(map[string]string, len(sc.PrefLabel)),
+			},
+		}
+
+		for _, label := range sc.PrefLabel {
+			e.concept.Names[label.Lang] = strings.TrimSpace(label.Value)
+		}
+
+		for _, b := range sc.Broader {
+			e.concept.Broader = append(e.concept.Broader, b.Resource)
+		}
+
+		entries = append(entries, &e)
+		byURI[e.uri] = &e
+	}
+
+	for _, sc := range doc.Concepts {
+		parent, ok := byURI[sc.About]
+		if !ok {
+			continue
+		}
+
+		for _, n := range sc.Narrower {
+			child, ok := byURI[n.Resource]
+			if ok && !slices.Contains(child.concept.Broader, parent.uri) {
+				child.concept.Broader = append(child.concept.Broader, parent.uri)
+			}
+		}
+	}
+
+	for _, e := range entries {
+		var broader []string
+
+		for _, uri := range e.concept.Broader {
+			code, ok := skosBroaderCode(byURI, e, uri)
+			if ok && !slices.Contains(broader, code) {
+				broader = append(broader, code)
+			}
+		}
+
+		e.concept.Broader = broader
+
+		r.Add(e.scheme, e.concept)
+	}
+
+	return nil
+}
+
+// skosBroaderCode returns the code of a broader concept, or false if it's in
+// another scheme.
+func skosBroaderCode(byURI map[string]*skosEntry, e *skosEntry, uri string) (string, bool) {
+	if b, ok := byURI[uri]; ok {
+		return b.concept.Code, normaliseScheme(b.scheme) == normaliseScheme(e.scheme)
+	}
+
+	if !strings.HasPrefix(uri, e.scheme) {
+		return "", false
+	}
+
+	code := strings.TrimPrefix(strings.TrimPrefix(uri, e.scheme), "/")
+
+	return code, code != ""
+}
+
+func skosIdentity(sc skosConcept) (string, string) {
+	var scheme string
+
+	if len(sc.InScheme) > 0 {
+		scheme = sc.InScheme[0].Resource
+	} else if idx := strings.LastIndexAny(sc.About, "/#"); idx != -1 {
+		scheme = sc.About[:idx+1]
+	}
+
+	code := strings.TrimSpace(sc.Notation)
+	if code == "" {
+		code = strings.TrimPrefix(sc.About, scheme)
+		code = strings.TrimPrefix(code, "/")
+	}
+
+	return scheme, code
+}
@@ -0,0 +1,153 @@
+package vocab
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func loadSKOSFixture(t *testing.T) *Registry {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", "mediatopic.rdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	r := NewRegistry("sv")
+
+	err = r.LoadSKOS(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func TestLoadSKOS(t *testing.T) {
+	r := loadSKOSFixture(t)
+
+	const subjectCode = "http://cv.iptc.org/newscodes/subjectcode/"
+
+	cases := []struct {
+		scheme, code, lang string
+		want               string
+	}{
+		{SchemeIPTCMediaTopic, "01000000", "sv", "kultur, nöje och media"},
+		{SchemeIPTCMediaTopic, "01000000", "en-GB", "arts, culture, entertainment and media"},
+		{SchemeIPTCMediaTopic, "20000003", "sv", "animation"},
+		{SchemeIPTCMediaTopic, "20000004", "sv", "tecknad serie"},
+		{subjectCode, "01005000", "en", "cinema"},
+		{"urn:example:genre", "NYH", "sv", "Nyhet"},
+	}
+
+	for _, c := range cases {
+		got, ok := r.LookupLang(c.scheme, c.code, c.lang)
+		if !ok || got != c.want {
+			t.Errorf("lookup %s %s: got %q %v, want %q", c.scheme, c.code, got, ok, c.want)
+		}
+	}
+
+	c, ok := r.Concept(SchemeIPTCMediaTopic, "20000005")
+	if !ok {
+		t.Fatal("concept 20000005 is missing")
+	}
+
+	// Links to other schemes are left out, but links to unknown concepts
+	// in the same scheme are kept.
+	want := Concept{
+		Code:    "20000005",
+		Names:   map[string]string{"en-GB": "cinema"},
+		Broader: []string{"20000002", "99999999"},
+	}
+
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+
+	c, _ = r.Concept(subjectCode, "01005000")
+	if c.Broader != nil {
+		t.Errorf("got broader %v across schemes", c.Broader)
+	}
+}
+
+func TestSKOSHierarchy(t *testing.T) {
+	r := loadSKOSFixture(t)
+
+	cases := []struct {
+		name         string
+		fn           func(scheme, code string) []Concept
+		scheme, code string
+		want         []string
+	}{
+		{
+			name: "broader", fn: r.Broader,
+			scheme: SchemeIPTCMediaTopic, code: "20000003",
+			want: []string{"20000002"},
+		},
+		{
+			name: "broader from narrower", fn: r.Broader,
+			scheme: SchemeIPTCMediaTopic, code: "20000004",
+			want: []string{"20000002"},
+		},
+		{
+			name: "broader and narrower", fn: r.Broader,
+			scheme: SchemeIPTCMediaTopic, code: "20000002",
+			want: []string{"01000000"},
+		},
+		{
+			name: "unknown broader left out", fn: r.Broader,
+			scheme: SchemeIPTCMediaTopic, code: "20000005",
+			want: []string{"20000002"},
+		},
+		{
+			name: "narrower", fn: r.Narrower,
+			scheme: SchemeIPTCMediaTopic, code: "20000002",
+			want: []string{"20000003", "20000004", "20000005"},
+		},
+		{
+			name: "narrower of top", fn: r.Narrower,
+			scheme: SchemeIPTCMediaTopic, code: "01000000",
+			want: []string{"20000002"},
+		},
+		{
+			name: "ancestors", fn: r.Ancestors,
+			scheme: SchemeIPTCMediaTopic, code: "20000004",
+			want: []string{"20000002", "01000000"},
+		},
+		{
+			name: "notation codes", fn: r.Narrower,
+			scheme: "urn:example:genre", code: "NYH",
+			want: []string{"NOT"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := codes(c.fn(c.scheme, c.code))
+			if !slices.Equal(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	// Concepts declared as both broader and narrower aren't duplicated.
+	c, _ := r.Concept("urn:example:genre", "NOT")
+	if !slices.Equal(c.Broader, []string{"NYH"}) {
+		t.Errorf("got broader %v", c.Broader)
+	}
+}
+
+func TestLoadSKOSInvalid(t *testing.T) {
+	r := NewRegistry("sv")
+
+	err := r.LoadSKOS(strings.NewReader("<rdf:RDF"))
+	if err == nil {
+		t.Error("expected an error for invalid XML")
+	}
+}
@@ -0,0 +1,262 @@
+// Package vocab provides a registry of controlled vocabularies that can be used
+// to resolve the codes used in TTNinjs documents to human readable names.
+package vocab
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// Known scheme URIs used by TT and IPTC. The TT schemes are the ones named in
+// the ttninjs schema, the event type scheme as a subject scheme.
+const (
+	SchemeTTSubref    = "http://tt.se/spec/subref/1.0/"
+	SchemeTTKeyword   = "http://tt.se/spec/keyword/1.0/"
+	SchemeTTEventtype = "http://tt.se/spec/eventtype/1.0/"
+	SchemeTTPlace     = "http://tt.se/spec/place/1.0/"
+	SchemeTTPerson    = "http://tt.se/spec/person/1.0/"
+	SchemeTTStory     = "http://tt.se/spec/story/1.0/"
+	SchemeTTProduct   = "http://tt.se/spec/product/1.0/"
+
+	SchemeIPTCGenre            = "http://cv.iptc.org/newscodes/genre/"
+	SchemeIPTCMediaTopic       = "http://cv.iptc.org/newscodes/mediatopic/"
+	SchemeIPTCSubjectCode      = "http://cv.iptc.org/newscodes/subjectcode/"
+	SchemeIPTCAdviceImportance = "http://cv.iptc.org/newscodes/advice-importance/"
+	SchemeIPTCAdviceLifetime   = "http://cv.iptc.org/newscodes/advice-lifetime/"
+)
+
+var ErrUnknownFormat = errors.New("unknown vocabulary file format")
+
+// Concept is a single entry in a controlled vocabulary.
+type Concept struct {
+	Code string `json:"code"`
+	// Names of the concept keyed by IETF BCP47 language tag.
+	Names map[string]string `json:"name"`
+	// Broader are the codes of the broader concepts in the same scheme.
+	Broader []string `json:"broader,omitempty"`
+}
+
+// Vocabulary is the JSON representation of a vocabulary as read by LoadJSON.
+type Vocabulary struct {
+	Scheme   string    `json:"scheme"`
+	Concepts []Concept `json:"concepts"`
+}
+
+// Registry holds a set of vocabularies keyed by scheme URI. A Registry is safe
+// for concurrent use.
+type Registry struct {
+	lang string
+
+	m       sync.RWMutex
+	schemes map[string]map[string]Concept
+}
+
+// NewRegistry creates an empty registry that resolves names in the given
+// language, falling back to any available name if the language is missing.
+func NewRegistry(lang string) *Registry {
+	return &Registry{
+		lang:    lang,
+		schemes: make(map[string]map[string]Concept),
+	}
+}
+
+// normaliseScheme makes scheme URIs with and without trailing slash equivalent.
+func normaliseScheme(scheme string) string {
+	return strings.TrimSuffix(scheme, "/")
+}
+
+// Add adds concepts to the vocabulary for a scheme, replacing any existing
+// concepts with the same codes.
+func (r *Registry) Add(scheme string, concepts ...Concept) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	key := normaliseScheme(scheme)
+
+	vocab, ok := r.schemes[key]
+	if !ok {
+		vocab = make(map[string]Concept)
+		r.schemes[key] = vocab
+	}
+
+	for _, c := range concepts {
+		vocab[c.Code] = c
+	}
+}
+
+// HasScheme returns true if the registry has a vocabulary for the scheme.
+func (r *Registry) HasScheme(scheme string) bool {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	_, ok := r.schemes[normaliseScheme(scheme)]
+
+	return ok
+}
+
+// Concept returns the concept for a code in a scheme.
+func (r *Registry) Concept(scheme, code string) (Concept, bool) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	c, ok := r.schemes[normaliseScheme(scheme)][code]
+
+	return c, ok
+}
+
+// Broader returns the concepts that are directly broader than the code.
+// Broader codes that aren't in the vocabulary are left out.
+func (r *Registry) Broader(scheme, code string) []Concept {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	vocab := r.schemes[normaliseScheme(scheme)]
+
+	var res []Concept
+
+	for _, b := range vocab[code].Broader {
+		if c, ok := vocab[b]; ok {
+			res = append(res, c)
+		}
+	}
+
+	return res
+}
+
+// Narrower returns the concepts that have the code as a broader concept,
+// sorted by code.
+func (r *Registry) Narrower(scheme, code string) []Concept {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	var res []Concept
+
+	for _, c := range r.schemes[normaliseScheme(scheme)] {
+		if slices.Contains(c.Broader, code) {
+			res = append(res, c)
+		}
+	}
+
+	slices.SortFunc(res, func(a, b Concept) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+
+	return res
+}
+
+// Ancestors returns all concepts that are broader than the code, directly or
+// through other concepts, the closest first. Cycles in the vocabulary are
+// ignored.
+func (r *Registry) Ancestors(scheme, code string) []Concept {
+	seen := map[string]bool{code: true}
+	queue := []string{code}
+
+	var res []Concept
+
+	for len(queue) > 0 {
+		broader := r.Broader(scheme, queue[0])
+		queue = queue[1:]
+
+		for _, c := range broader {
+			if seen[c.Code] {
+				continue
+			}
+
+			seen[c.Code] = true
+			res = append(res, c)
+			queue = append(queue, c.Code)
+		}
+	}
+
+	return res
+}
+
+// Lookup returns the name of a code in the registry language.
+func (r *Registry) Lookup(scheme, code string) (string, bool) {
+	return r.LookupLang(scheme, code, r.lang)
+}
+
+// LookupLang returns the name of a code in the given language. If the concept
+// lacks a name in that language the name for the primary language subtag is
+// used, and failing that any available name.
+func (r *Registry) LookupLang(scheme, code, lang string) (string, bool) {
+	c, ok := r.Concept(scheme, code)
+	if !ok || len(c.Names) == 0 {
+		return "", false
+	}
+
+	if name, ok := c.Names[lang]; ok {
+		return name, true
+	}
+
+	if primary, _, found := strings.Cut(lang, "-"); found {
+		if name, ok := c.Names[primary]; ok {
+			return name, true
+		}
+	}
+
+	// Pick the lexically first language to make the fallback stable.
+	var first string
+
+	for l := range c.Names {
+		if first == "" || l < first {
+			first = l
+		}
+	}
+
+	return c.Names[first], true
+}
+
+// LoadJSON reads a vocabulary in the JSON format described by Vocabulary.
+func (r *Registry) LoadJSON(rd io.Reader) error {
+	var v Vocabulary
+
+	err := json.NewDecoder(rd).Decode(&v)
+	if err != nil {
+		return fmt.Errorf("unable to decode vocabulary JSON: %w", err)
+	}
+
+	if v.Scheme == "" {
+		return errors.New("vocabulary has no scheme")
+	}
+
+	r.Add(v.Scheme, v.Concepts...)
+
+	return nil
+}
+
+// LoadFile loads a vocabulary file, the format is determined by the file
+// extension: ".json" for JSON and ".rdf", ".xml" or ".skos" for SKOS RDF/XML.
+func (r *Registry) LoadFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open vocabulary file: %w", err)
+	}
+
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		err = r.LoadJSON(f)
+	case ".rdf", ".xml", ".skos":
+		err = r.LoadSKOS(f)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, name)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to load %q: %w", name, err)
+	}
+
+	return nil
+}
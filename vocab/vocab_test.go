@@ -0,0 +1,215 @@
+package vocab
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func codes(concepts []Concept) []string {
+	var res []string
+
+	for _, c := range concepts {
+		res = append(res, c.Code)
+	}
+
+	return res
+}
+
+func TestRegistryLookup(t *testing.T) {
+	r := NewRegistry("sv")
+
+	r.Add(SchemeIPTCGenre,
+		Concept{Code: "Analysis", Names: map[string]string{
+			"en-GB": "analysis", "sv": "analys",
+		}},
+		Concept{Code: "Feature", Names: map[string]string{
+			"en": "feature", "de": "Reportage",
+		}},
+		Concept{Code: "Empty"},
+	)
+
+	cases := []struct {
+		scheme, code, lang string
+		want               string
+		ok                 bool
+	}{
+		{SchemeIPTCGenre, "Analysis", "", "analys", true},
+		{SchemeIPTCGenre, "Analysis", "en-GB", "analysis", true},
+		{SchemeIPTCGenre, "Analysis", "fi", "analysis", true},
+		{SchemeIPTCGenre, "Feature", "en-US", "feature", true},
+		{SchemeIPTCGenre, "Feature", "sv", "Reportage", true},
+		{strings.TrimSuffix(SchemeIPTCGenre, "/"), "Feature", "en", "feature", true},
+		{SchemeIPTCGenre, "Empty", "", "", false},
+		{SchemeIPTCGenre, "Missing", "", "", false},
+		{SchemeIPTCMediaTopic, "Analysis", "", "", false},
+	}
+
+	for _, c := range cases {
+		var (
+			got string
+			ok  bool
+		)
+
+		if c.lang == "" {
+			got, ok = r.Lookup(c.scheme, c.code)
+		} else {
+			got, ok = r.LookupLang(c.scheme, c.code, c.lang)
+		}
+
+		if got != c.want || ok != c.ok {
+			t.Errorf("lookup %s %s %q: got %q %v, want %q %v",
+				c.scheme, c.code, c.lang, got, ok, c.want, c.ok)
+		}
+	}
+
+	if !r.HasScheme(SchemeIPTCGenre) || !r.HasScheme("http://cv.iptc.org/newscodes/genre") {
+		t.Error("expected the genre scheme with and without trailing slash")
+	}
+
+	if r.HasScheme(SchemeIPTCMediaTopic) {
+		t.Error("didn't expect the media topic scheme")
+	}
+
+	// Adding a concept again replaces it.
+	r.Add(SchemeIPTCGenre, Concept{Code: "Analysis", Names: map[string]string{"sv": "Analys"}})
+
+	if got, _ := r.LookupLang(SchemeIPTCGenre, "Analysis", "en-GB"); got != "Analys" {
+		t.Errorf("got %q after replacing the concept", got)
+	}
+}
+
+func TestRegistryHierarchy(t *testing.T) {
+	r := NewRegistry("sv")
+
+	r.Add("urn:example:topics",
+		Concept{Code: "a"},
+		Concept{Code: "b", Broader: []string{"a"}},
+		Concept{Code: "c", Broader: []string{"a"}},
+		Concept{Code: "d", Broader: []string{"b", "c", "missing"}},
+		Concept{Code: "e", Broader: []string{"d"}},
+		// x and y are a cycle.
+		Concept{Code: "x", Broader: []string{"y"}},
+		Concept{Code: "y", Broader: []string{"x"}},
+	)
+
+	cases := []struct {
+		name string
+		fn   func(scheme, code string) []Concept
+		code string
+		want []string
+	}{
+		{"broader of top", r.Broader, "a", nil},
+		{"broader", r.Broader, "b", []string{"a"}},
+		{"several broader", r.Broader, "d", []string{"b", "c"}},
+		{"broader of unknown", r.Broader, "z", nil},
+		{"narrower", r.Narrower, "a", []string{"b", "c"}},
+		{"narrower of leaf", r.Narrower, "e", nil},
+		{"narrower of unknown code", r.Narrower, "missing", []string{"d"}},
+		{"ancestors", r.Ancestors, "e", []string{"d", "b", "c", "a"}},
+		{"ancestors of top", r.Ancestors, "a", nil},
+		{"ancestors in a cycle", r.Ancestors, "x", []string{"y"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := codes(c.fn("urn:example:topics", c.code))
+			if !slices.Equal(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	if got := r.Narrower("urn:example:other", "a"); got != nil {
+		t.Errorf("got %v for an unknown scheme", got)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	r := NewRegistry("sv")
+
+	err := r.LoadFile(filepath.Join("testdata", "product.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name, _ := r.Lookup(SchemeTTProduct, "FT"); name != "Fotoproduktion" {
+		t.Errorf("got name %q", name)
+	}
+
+	if got := codes(r.Ancestors(SchemeTTProduct, "FTINR")); !slices.Equal(got, []string{"FT"}) {
+		t.Errorf("got ancestors %v", got)
+	}
+
+	err = r.LoadFile(filepath.Join("testdata", "mediatopic.rdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !r.HasScheme(SchemeIPTCMediaTopic) {
+		t.Error("expected the SKOS vocabulary to be loaded")
+	}
+
+	err = r.LoadFile(filepath.Join("testdata", "product.csv"))
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("got %v, want ErrUnknownFormat", err)
+	}
+
+	err = r.LoadFile(filepath.Join("testdata", "missing.json"))
+	if err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	cases := map[string]string{
+		"invalid":   `{"scheme": `,
+		"no scheme": `{"concepts": [{"code": "a"}]}`,
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := NewRegistry("sv")
+
+			if err := r.LoadJSON(strings.NewReader(data)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	r := NewRegistry("sv")
+
+	var wg sync.WaitGroup
+
+	for i := range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range 100 {
+				code := strings.Repeat("a", i+1)
+
+				r.Add(SchemeTTKeyword, Concept{
+					Code:    code,
+					Names:   map[string]string{"sv": code},
+					Broader: []string{"a"},
+				})
+				r.Lookup(SchemeTTKeyword, code)
+				r.Narrower(SchemeTTKeyword, "a")
+				r.Ancestors(SchemeTTKeyword, code)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if got := codes(r.Narrower(SchemeTTKeyword, "a")); len(got) != 4 {
+		t.Errorf("got narrower %v", got)
+	}
+}